@@ -88,6 +88,9 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Stop background jobs after in-flight requests are done
+	router.Shutdown()
+
 	log.Println("Server stopped")
 }
 
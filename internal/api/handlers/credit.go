@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -50,3 +51,28 @@ func (h *CreditHandler) GetSubscription(w http.ResponseWriter, r *http.Request)
 
 	jsonResponse(w, sub, http.StatusOK)
 }
+
+// ActivateBoost starts a profile boost using the weekly allowance or credits
+func (h *CreditHandler) ActivateBoost(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.creditService.Boost(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, credit.ErrBoostActive):
+			jsonError(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, credit.ErrInsufficientCredits):
+			jsonError(w, "not enough credits for a boost", http.StatusPaymentRequired)
+		default:
+			log.Printf("[ERROR] ActivateBoost failed for user %s: %v", userID, err)
+			jsonError(w, "failed to activate boost", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
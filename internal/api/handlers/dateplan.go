@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/feels/feels/internal/api/middleware"
+	"github.com/feels/feels/internal/domain/dateplan"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type DatePlanHandler struct {
+	dateplanService *dateplan.Service
+}
+
+func NewDatePlanHandler(dateplanService *dateplan.Service) *DatePlanHandler {
+	return &DatePlanHandler{dateplanService: dateplanService}
+}
+
+// dateplanError writes the response for date plan errors
+func dateplanError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, dateplan.ErrNotInMatch):
+		jsonError(w, "not in match", http.StatusForbidden)
+	case errors.Is(err, dateplan.ErrNotInvitee):
+		jsonError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, dateplan.ErrPlanNotFound), errors.Is(err, dateplan.ErrCheckInNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, dateplan.ErrPlanClosed):
+		jsonError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, dateplan.ErrInvalidPlan), errors.Is(err, dateplan.ErrInvalidCheckIn), errors.Is(err, dateplan.ErrInvalidContact):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, dateplan.ErrTooManyShares):
+		jsonError(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		jsonError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// CreatePlan proposes a date to the other person in a match
+func (h *DatePlanHandler) CreatePlan(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	var req dateplan.CreatePlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.dateplanService.CreatePlan(r.Context(), userID, matchID, &req)
+	if err != nil {
+		dateplanError(w, err, "failed to create date plan")
+		return
+	}
+
+	jsonResponse(w, plan, http.StatusCreated)
+}
+
+// GetPlans lists a match's date plans with the caller's own check-ins
+func (h *DatePlanHandler) GetPlans(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	plans, err := h.dateplanService.GetPlans(r.Context(), userID, matchID)
+	if err != nil {
+		dateplanError(w, err, "failed to get date plans")
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"plans": plans}, http.StatusOK)
+}
+
+// RespondToPlan accepts or declines a proposed plan
+func (h *DatePlanHandler) RespondToPlan(w http.ResponseWriter, r *http.Request) {
+	userID, planID, ok := planRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req dateplan.RespondRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.dateplanService.RespondToPlan(r.Context(), userID, planID, req.Accept)
+	if err != nil {
+		dateplanError(w, err, "failed to respond to date plan")
+		return
+	}
+
+	jsonResponse(w, plan, http.StatusOK)
+}
+
+// CancelPlan calls off a plan
+func (h *DatePlanHandler) CancelPlan(w http.ResponseWriter, r *http.Request) {
+	userID, planID, ok := planRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.dateplanService.CancelPlan(r.Context(), userID, planID); err != nil {
+		dateplanError(w, err, "failed to cancel date plan")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetupSafety sets the caller's check-in time and trusted contact for a plan
+func (h *DatePlanHandler) SetupSafety(w http.ResponseWriter, r *http.Request) {
+	userID, planID, ok := planRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req dateplan.SafetyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ci, err := h.dateplanService.SetupSafety(r.Context(), userID, planID, &req)
+	if err != nil {
+		dateplanError(w, err, "failed to set up safety check-in")
+		return
+	}
+
+	jsonResponse(w, ci, http.StatusOK)
+}
+
+// CheckIn confirms the caller is safe after the date
+func (h *DatePlanHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	userID, planID, ok := planRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ci, err := h.dateplanService.CheckIn(r.Context(), userID, planID)
+	if err != nil {
+		dateplanError(w, err, "failed to check in")
+		return
+	}
+
+	jsonResponse(w, ci, http.StatusOK)
+}
+
+// GetPlanAudit returns a plan's check-ins and audit trail (admin)
+func (h *DatePlanHandler) GetPlanAudit(w http.ResponseWriter, r *http.Request) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid plan id", http.StatusBadRequest)
+		return
+	}
+
+	audit, err := h.dateplanService.GetPlanAudit(r.Context(), planID)
+	if err != nil {
+		dateplanError(w, err, "failed to get date plan audit")
+		return
+	}
+
+	jsonResponse(w, audit, http.StatusOK)
+}
+
+// ListEscalations returns recent missed check-in escalations (admin)
+func (h *DatePlanHandler) ListEscalations(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	entries, err := h.dateplanService.ListEscalations(r.Context(), limit)
+	if err != nil {
+		jsonError(w, "failed to list escalations", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"escalations": entries}, http.StatusOK)
+}
+
+// planRequest reads the caller and the {planId} URL parameter, writing an error if either is missing
+func planRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	planID, err := uuid.Parse(chi.URLParam(r, "planId"))
+	if err != nil {
+		jsonError(w, "invalid plan id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, planID, true
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/feels/feels/internal/domain/mailer"
+	"github.com/feels/feels/internal/domain/settings"
+)
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Feels email preferences</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background-color: #0a0a0a; color: #ffffff; padding: 40px 20px;">
+  <div style="max-width: 400px; margin: 0 auto; text-align: center;">
+    <h1 style="color: #e85d75; margin-bottom: 30px;">feels</h1>
+    <p style="font-size: 18px; margin-bottom: 30px;">{{.Message}}</p>
+    {{if .Confirm}}<form method="POST">
+      <button type="submit" style="background-color: #e85d75; color: white; padding: 16px 32px; border: none; border-radius: 8px; font-weight: bold; font-size: 16px;">Unsubscribe</button>
+    </form>{{end}}
+  </div>
+</body>
+</html>`))
+
+type EmailHandler struct {
+	mailerService *mailer.Service
+}
+
+func NewEmailHandler(mailerService *mailer.Service) *EmailHandler {
+	return &EmailHandler{mailerService: mailerService}
+}
+
+// UnsubscribePage asks the user to confirm; unsubscribing on GET would let mail
+// clients that prefetch links unsubscribe people
+func (h *EmailHandler) UnsubscribePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("token") == "" {
+		renderUnsubscribePage(w, "This unsubscribe link is invalid.", false, http.StatusBadRequest)
+		return
+	}
+	renderUnsubscribePage(w, "Stop receiving these emails from Feels?", true, http.StatusOK)
+}
+
+// Unsubscribe turns off the email type the link was issued for
+// Mail clients also post here for one-click unsubscribe (RFC 8058)
+func (h *EmailHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	_, err := h.mailerService.Unsubscribe(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		if errors.Is(err, mailer.ErrInvalidUnsubscribeToken) || errors.Is(err, settings.ErrUnknownEmailType) {
+			renderUnsubscribePage(w, "This unsubscribe link is invalid.", false, http.StatusBadRequest)
+			return
+		}
+		log.Printf("[ERROR] Email unsubscribe failed: %v", err)
+		renderUnsubscribePage(w, "Something went wrong. Please try again later.", false, http.StatusInternalServerError)
+		return
+	}
+
+	renderUnsubscribePage(w, "You're unsubscribed. You can turn these emails back on in the app's notification settings.", false, http.StatusOK)
+}
+
+func renderUnsubscribePage(w http.ResponseWriter, message string, confirm bool, status int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	unsubscribePage.Execute(w, map[string]interface{}{"Message": message, "Confirm": confirm})
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/feels/feels/internal/api/middleware"
 	"github.com/feels/feels/internal/domain/credit"
@@ -190,7 +191,15 @@ func (h *FeedHandler) GetDailyPicks(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	resp, err := h.feedService.GetDailyPicks(r.Context(), userID, isPremium)
+	// Picks refresh at the user's local midnight when the client sends its IANA time zone
+	loc := time.UTC
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+
+	resp, err := h.feedService.GetDailyPicks(r.Context(), userID, isPremium, loc)
 	if err != nil {
 		if errors.Is(err, feed.ErrProfileRequired) {
 			jsonError(w, "profile required to use feed", http.StatusPreconditionRequired)
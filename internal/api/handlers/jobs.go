@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/feels/feels/internal/jobs"
+	"github.com/go-chi/chi/v5"
+)
+
+// JobScheduler interface for inspecting and triggering background jobs
+type JobScheduler interface {
+	Jobs(ctx context.Context) ([]jobs.JobInfo, error)
+	History(ctx context.Context, name string, limit int) ([]jobs.Run, error)
+	Trigger(ctx context.Context, name string) (*jobs.Run, error)
+}
+
+type JobsHandler struct {
+	scheduler JobScheduler
+}
+
+func NewJobsHandler(scheduler JobScheduler) *JobsHandler {
+	return &JobsHandler{scheduler: scheduler}
+}
+
+// ListJobs returns registered jobs with their last run
+func (h *JobsHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	list, err := h.scheduler.Jobs(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"failed to list jobs"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs": list,
+	})
+}
+
+// GetJobRuns returns the run history for a job
+func (h *JobsHandler) GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	limit := 20
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	runs, err := h.scheduler.History(r.Context(), name, limit)
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			http.Error(w, `{"error":"job not found"}`, http.StatusNotFound)
+			return
+		}
+		http.Error(w, `{"error":"failed to get job runs"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if runs == nil {
+		runs = []jobs.Run{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"runs": runs,
+	})
+}
+
+// TriggerJob starts a job in the background and returns 202 with the run record;
+// poll GET /jobs/{name}/runs for the outcome
+func (h *JobsHandler) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	run, err := h.scheduler.Trigger(r.Context(), name)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrJobNotFound):
+			http.Error(w, `{"error":"job not found"}`, http.StatusNotFound)
+		case errors.Is(err, jobs.ErrJobRunning):
+			http.Error(w, `{"error":"job is already running"}`, http.StatusConflict)
+		default:
+			http.Error(w, `{"error":"failed to run job"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
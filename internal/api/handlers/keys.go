@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/feels/feels/internal/api/middleware"
+	"github.com/feels/feels/internal/domain/keys"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type KeysHandler struct {
+	keysService *keys.Service
+}
+
+func NewKeysHandler(keysService *keys.Service) *KeysHandler {
+	return &KeysHandler{keysService: keysService}
+}
+
+// keysError writes the response for key directory errors
+func keysError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, keys.ErrNotInMatch):
+		jsonError(w, "not in match", http.StatusForbidden)
+	case errors.Is(err, keys.ErrDeviceNotFound), errors.Is(err, keys.ErrNoDeviceKeys):
+		jsonError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, keys.ErrInvalidKey), errors.Is(err, keys.ErrDuplicateKeyID), errors.Is(err, keys.ErrTooManyPreKeys):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, keys.ErrRateLimited):
+		jsonError(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		jsonError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// RegisterDevice publishes the calling device's identity and prekeys
+func (h *KeysHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req keys.RegisterDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DeviceID == "" {
+		jsonError(w, "device_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.keysService.RegisterDevice(r.Context(), userID, &req); err != nil {
+		keysError(w, err, "failed to register device keys")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UploadPreKeys tops up the calling device's one-time prekeys
+func (h *KeysHandler) UploadPreKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req keys.UploadPreKeysRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DeviceID == "" {
+		jsonError(w, "device_id is required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.keysService.UploadPreKeys(r.Context(), userID, &req)
+	if err != nil {
+		keysError(w, err, "failed to upload prekeys")
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
+
+// GetPreKeyCount reports how many one-time prekeys a device has left
+func (h *KeysHandler) GetPreKeyCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deviceID := r.URL.Query().Get("device_id")
+	if deviceID == "" {
+		jsonError(w, "device_id query parameter required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.keysService.GetPreKeyCount(r.Context(), userID, deviceID)
+	if err != nil {
+		keysError(w, err, "failed to count prekeys")
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
+
+// RevokeDevice removes one of the user's devices from the key directory
+func (h *KeysHandler) RevokeDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.keysService.RevokeDevice(r.Context(), userID, chi.URLParam(r, "deviceId")); err != nil {
+		keysError(w, err, "failed to revoke device keys")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ClaimMatchBundles returns prekey bundles for every device that should receive the user's messages in a match
+func (h *KeysHandler) ClaimMatchBundles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	var req keys.ClaimBundlesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DeviceID == "" {
+		jsonError(w, "device_id required", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.keysService.ClaimMatchBundles(r.Context(), userID, matchID, req.DeviceID)
+	if err != nil {
+		keysError(w, err, "failed to claim key bundles")
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
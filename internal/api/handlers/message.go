@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/feels/feels/internal/api/middleware"
 	"github.com/feels/feels/internal/domain/message"
@@ -44,21 +45,28 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse pagination
-	limit := 50
-	offset := 0
+	// Parse pagination: before/after cursors, with offset kept for older clients
+	page := message.PageQuery{Limit: 50}
 	if l := r.URL.Query().Get("limit"); l != "" {
 		if parsed, err := strconv.Atoi(l); err == nil {
-			limit = parsed
+			page.Limit = parsed
 		}
 	}
 	if o := r.URL.Query().Get("offset"); o != "" {
 		if parsed, err := strconv.Atoi(o); err == nil {
-			offset = parsed
+			page.Offset = parsed
 		}
 	}
+	if page.Before, err = parseCursor(r.URL.Query().Get("before")); err != nil {
+		jsonError(w, "before must be a message id or RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	if page.After, err = parseCursor(r.URL.Query().Get("after")); err != nil {
+		jsonError(w, "after must be a message id or RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
 
-	resp, err := h.messageService.GetMessages(r.Context(), userID, matchID, limit, offset)
+	resp, err := h.messageService.GetMessages(r.Context(), userID, matchID, page)
 	if err != nil {
 		if errors.Is(err, message.ErrNotInMatch) {
 			jsonError(w, "not in match", http.StatusForbidden)
@@ -71,6 +79,125 @@ func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, resp, http.StatusOK)
 }
 
+// SearchMessages searches a chat's plaintext messages
+func (h *MessageHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	limit := 20
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	before, err := parseCursor(r.URL.Query().Get("before"))
+	if err != nil {
+		jsonError(w, "before must be a message id or RFC3339 timestamp", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.messageService.SearchMessages(r.Context(), userID, matchID, r.URL.Query().Get("q"), before, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, message.ErrNotInMatch):
+			jsonError(w, "not in match", http.StatusForbidden)
+		case errors.Is(err, message.ErrEmptySearch):
+			jsonError(w, err.Error(), http.StatusBadRequest)
+		default:
+			jsonError(w, "failed to search messages", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
+
+// GetConversations lists the user's conversations (?folder=archived for the archive)
+func (h *MessageHandler) GetConversations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	folder := r.URL.Query().Get("folder")
+	if folder == "" {
+		folder = message.FolderInbox
+	}
+	if folder != message.FolderInbox && folder != message.FolderArchived {
+		jsonError(w, "folder must be inbox or archived", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.messageService.GetConversations(r.Context(), userID, folder)
+	if err != nil {
+		jsonError(w, "failed to get conversations", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
+
+// UpdateConversation mutes, archives or pins a conversation for the user
+func (h *MessageHandler) UpdateConversation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	var req message.UpdateConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.messageService.UpdateConversation(r.Context(), userID, matchID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, message.ErrNotInMatch):
+			jsonError(w, "not in match", http.StatusForbidden)
+		case errors.Is(err, message.ErrInvalidMuteUntil):
+			jsonError(w, err.Error(), http.StatusBadRequest)
+		default:
+			jsonError(w, "failed to update conversation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
+
+// parseCursor reads a pagination cursor: a message ID or an RFC3339 timestamp
+func parseCursor(v string) (*message.Cursor, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if id, err := uuid.Parse(v); err == nil {
+		return &message.Cursor{MessageID: &id}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, err
+	}
+	return &message.Cursor{Time: &t}, nil
+}
+
 func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -96,10 +223,15 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.Is(err, message.ErrNotInMatch):
 			jsonError(w, "not in match", http.StatusForbidden)
-		case errors.Is(err, message.ErrEmptyMessage):
+		case errors.Is(err, message.ErrEmptyMessage), errors.Is(err, message.ErrInvalidReply), errors.Is(err, message.ErrInvalidVoiceNote), errors.Is(err, message.ErrInvalidVoiceNoteURL),
+			errors.Is(err, message.ErrInvalidViewOnceImage), errors.Is(err, message.ErrInvalidDeviceCiphertexts):
 			jsonError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, message.ErrStaleDevices):
+			jsonError(w, err.Error(), http.StatusConflict)
 		case errors.Is(err, message.ErrImageNotEnabled):
 			jsonError(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, message.ErrSendingDisabled):
+			jsonError(w, err.Error(), http.StatusForbidden)
 		default:
 			jsonError(w, "failed to send message", http.StatusInternalServerError)
 		}
@@ -109,6 +241,168 @@ func (h *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, msg, http.StatusCreated)
 }
 
+// parseMessageIDs reads the match and message IDs from the URL, writing an error response if invalid
+func parseMessageIDs(w http.ResponseWriter, r *http.Request) (matchID, messageID uuid.UUID, ok bool) {
+	matchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid match id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	messageID, err = uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		jsonError(w, "invalid message id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return matchID, messageID, true
+}
+
+// messageChangeError writes the response for errors from edit, unsend and reaction calls
+func messageChangeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, message.ErrNotInMatch):
+		jsonError(w, "not in match", http.StatusForbidden)
+	case errors.Is(err, message.ErrMessageNotFound):
+		jsonError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, message.ErrNotMessageSender):
+		jsonError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, message.ErrEditWindowExpired), errors.Is(err, message.ErrMessageUnsent),
+		errors.Is(err, message.ErrDeviceEncryptedEdit):
+		jsonError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, message.ErrEmptyMessage), errors.Is(err, message.ErrInvalidReaction):
+		jsonError(w, err.Error(), http.StatusBadRequest)
+	default:
+		jsonError(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// EditMessage edits the text of the user's own message
+func (h *MessageHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, messageID, ok := parseMessageIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req message.EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	msg, err := h.messageService.EditMessage(r.Context(), userID, matchID, messageID, &req)
+	if err != nil {
+		messageChangeError(w, err, "failed to edit message")
+		return
+	}
+
+	jsonResponse(w, msg, http.StatusOK)
+}
+
+// UnsendMessage takes back the user's own message
+func (h *MessageHandler) UnsendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, messageID, ok := parseMessageIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.messageService.UnsendMessage(r.Context(), userID, matchID, messageID); err != nil {
+		messageChangeError(w, err, "failed to unsend message")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// React sets the user's reaction to a message
+func (h *MessageHandler) React(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, messageID, ok := parseMessageIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req message.ReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.messageService.ReactToMessage(r.Context(), userID, matchID, messageID, req.Emoji); err != nil {
+		messageChangeError(w, err, "failed to react to message")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveReaction clears the user's reaction to a message
+func (h *MessageHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, messageID, ok := parseMessageIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.messageService.RemoveReaction(r.Context(), userID, matchID, messageID); err != nil {
+		messageChangeError(w, err, "failed to remove reaction")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ViewImage opens a view-once image, returning a short-lived link to it
+func (h *MessageHandler) ViewImage(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchID, messageID, ok := parseMessageIDs(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := h.messageService.ViewImage(r.Context(), userID, matchID, messageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, message.ErrAlreadyViewed):
+			jsonError(w, err.Error(), http.StatusGone)
+		case errors.Is(err, message.ErrNotRecipient):
+			jsonError(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, message.ErrMediaStoreUnavailable):
+			jsonError(w, "image viewing not available", http.StatusServiceUnavailable)
+		default:
+			messageChangeError(w, err, "failed to view image")
+		}
+		return
+	}
+
+	jsonResponse(w, link, http.StatusOK)
+}
+
 func (h *MessageHandler) EnableImages(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserID(r.Context())
 	if !ok {
@@ -264,6 +558,17 @@ func (h *MessageHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// View-once images go to private storage; the key is only usable as view_once_image
+	if r.FormValue("view_once") == "true" {
+		key, err := h.storage.UploadPrivate(r.Context(), message.ViewOnceKeyPrefix(userID), io.Reader(file), header.Size, contentType)
+		if err != nil {
+			jsonError(w, "failed to upload image", http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, map[string]string{"view_once_image": key}, http.StatusOK)
+		return
+	}
+
 	url, err := h.storage.UploadPhoto(r.Context(), userID, io.Reader(file), header.Size, contentType)
 	if err != nil {
 		jsonError(w, "failed to upload image", http.StatusInternalServerError)
@@ -272,3 +577,72 @@ func (h *MessageHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 
 	jsonResponse(w, map[string]string{"url": url}, http.StatusOK)
 }
+
+// UploadVoiceNote uploads a voice note for use in chat messages
+// Voice notes are gated by the same per-match image permissions as photos
+func (h *MessageHandler) UploadVoiceNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	matchIDStr := chi.URLParam(r, "id")
+	matchID, err := uuid.Parse(matchIDStr)
+	if err != nil {
+		jsonError(w, "invalid match id", http.StatusBadRequest)
+		return
+	}
+
+	canSendMedia, err := h.messageService.CanSendImages(r.Context(), userID, matchID)
+	if err != nil {
+		if errors.Is(err, message.ErrNotInMatch) {
+			jsonError(w, "not in match", http.StatusForbidden)
+			return
+		}
+		jsonError(w, "failed to check image permissions", http.StatusInternalServerError)
+		return
+	}
+	if !canSendMedia {
+		jsonError(w, "media not enabled for this conversation", http.StatusForbidden)
+		return
+	}
+
+	// Reject oversized bodies before buffering them
+	r.Body = http.MaxBytesReader(w, r.Body, storage.MaxAudioSize+1024*1024)
+	if err := r.ParseMultipartForm(storage.MaxAudioSize); err != nil {
+		jsonError(w, "file too large (max 5MB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	file, header, err := r.FormFile("audio")
+	if err != nil {
+		jsonError(w, "audio file required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > storage.MaxAudioSize {
+		jsonError(w, "file too large (max 5MB)", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	contentType := header.Header.Get("Content-Type")
+	if !storage.IsAllowedAudioContentType(contentType) {
+		jsonError(w, "invalid file type, must be m4a, aac, mp3, ogg, or webm audio", http.StatusBadRequest)
+		return
+	}
+
+	if h.storage == nil {
+		jsonError(w, "audio upload not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	url, err := h.storage.UploadAudio(r.Context(), message.VoiceKeyPrefix(userID), io.Reader(file), header.Size, contentType)
+	if err != nil {
+		jsonError(w, "failed to upload audio", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]string{"url": url}, http.StatusOK)
+}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/feels/feels/internal/api/middleware"
 	"github.com/feels/feels/internal/domain/notification"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type NotificationHandler struct {
@@ -76,3 +80,127 @@ func (h *NotificationHandler) UnregisterToken(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetNotifications returns a page of the caller's notification inbox, newest first
+// Pass the last notification's id as before to get the next page
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 30
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	var before *uuid.UUID
+	if b := r.URL.Query().Get("before"); b != "" {
+		id, err := uuid.Parse(b)
+		if err != nil {
+			jsonError(w, "before must be a notification id", http.StatusBadRequest)
+			return
+		}
+		before = &id
+	}
+
+	resp, err := h.notificationService.GetInbox(r.Context(), userID, before, limit)
+	if err != nil {
+		if errors.Is(err, notification.ErrUnknownCursor) {
+			jsonError(w, "before is not one of your notifications", http.StatusBadRequest)
+			return
+		}
+		jsonError(w, "failed to get notifications", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, resp, http.StatusOK)
+}
+
+// GetUnreadCount returns the caller's unread notification count, for the badge
+func (h *NotificationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	unread, err := h.notificationService.GetUnreadCount(r.Context(), userID)
+	if err != nil {
+		jsonError(w, "failed to get unread count", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]int{"unread_count": unread}, http.StatusOK)
+}
+
+// MarkRead marks some of the caller's notifications read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req notification.MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	unread, err := h.notificationService.MarkRead(r.Context(), userID, req.IDs)
+	if err != nil {
+		if errors.Is(err, notification.ErrTooManyIDs) {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		jsonError(w, "failed to mark notifications read", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]int{"unread_count": unread}, http.StatusOK)
+}
+
+// MarkAllRead marks the caller's whole inbox read
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		jsonError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	unread, err := h.notificationService.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		jsonError(w, "failed to mark notifications read", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]int{"unread_count": unread}, http.StatusOK)
+}
+
+// GetDeliveries returns a user's recent notification deliveries on every channel (admin)
+func (h *NotificationHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		jsonError(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	deliveries, err := h.notificationService.Dispatcher().GetDeliveries(r.Context(), userID, limit)
+	if err != nil {
+		jsonError(w, "failed to get deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{"deliveries": deliveries}, http.StatusOK)
+}
@@ -12,7 +12,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/feels/feels/internal/domain/mailer"
 	"github.com/feels/feels/internal/repository"
+	"github.com/google/uuid"
 )
 
 // RevenueCat webhook event types
@@ -49,14 +51,21 @@ type RevenueCatWebhookEvent struct {
 		GracePeriodExpirationAtMs int64    `json:"grace_period_expiration_at_ms,omitempty"`
 		AutoResumeAtMs            int64    `json:"auto_resume_at_ms,omitempty"`
 		Price                     float64  `json:"price"`
+		PriceInPurchasedCurrency  float64  `json:"price_in_purchased_currency"`
 		Currency                  string   `json:"currency"`
 		TakehomePercentage        float64  `json:"takehome_percentage"`
 	} `json:"event"`
 }
 
+// ReceiptMailer emails subscription receipts
+type ReceiptMailer interface {
+	SendSubscriptionReceipt(ctx context.Context, userID uuid.UUID, r *mailer.Receipt) (bool, error)
+}
+
 type RevenueCatHandler struct {
 	subscriptionRepo *repository.SubscriptionRepository
 	webhookSecret    string
+	mailer           ReceiptMailer
 }
 
 func NewRevenueCatHandler(subscriptionRepo *repository.SubscriptionRepository) *RevenueCatHandler {
@@ -66,6 +75,11 @@ func NewRevenueCatHandler(subscriptionRepo *repository.SubscriptionRepository) *
 	}
 }
 
+// SetReceiptMailer sets the mailer for purchase and renewal receipts
+func (h *RevenueCatHandler) SetReceiptMailer(m ReceiptMailer) {
+	h.mailer = m
+}
+
 // HandleWebhook processes RevenueCat webhook events
 func (h *RevenueCatHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -104,6 +118,9 @@ func (h *RevenueCatHandler) HandleWebhook(w http.ResponseWriter, r *http.Request
 	switch event.Event.Type {
 	case EventInitialPurchase, EventRenewal, EventUncancellation, EventSubscriptionResumed:
 		err = h.handleSubscriptionActive(ctx, event)
+		if err == nil && (event.Event.Type == EventInitialPurchase || event.Event.Type == EventRenewal) {
+			h.sendReceipt(ctx, event)
+		}
 	case EventCancellation:
 		err = h.handleCancellation(ctx, event)
 	case EventExpiration:
@@ -157,6 +174,29 @@ func (h *RevenueCatHandler) handleSubscriptionActive(ctx context.Context, event
 	)
 }
 
+// sendReceipt emails a receipt for a purchase or renewal; the event ID keeps webhook retries
+// from sending it twice
+func (h *RevenueCatHandler) sendReceipt(ctx context.Context, event RevenueCatWebhookEvent) {
+	if h.mailer == nil {
+		return
+	}
+	userID, err := uuid.Parse(event.Event.AppUserID)
+	if err != nil {
+		return
+	}
+
+	_, err = h.mailer.SendSubscriptionReceipt(ctx, userID, &mailer.Receipt{
+		ID:          event.Event.ID,
+		Plan:        h.productToPlanType(event.Event.ProductID),
+		Amount:      mailer.FormatAmount(event.Event.PriceInPurchasedCurrency, event.Event.Currency),
+		PurchasedAt: time.UnixMilli(event.Event.PurchasedAtMs),
+		RenewsAt:    time.UnixMilli(event.Event.ExpirationAtMs),
+	})
+	if err != nil {
+		log.Printf("[ERROR] RevenueCat: failed to send receipt for user=%s: %v", userID, err)
+	}
+}
+
 func (h *RevenueCatHandler) handleCancellation(ctx context.Context, event RevenueCatWebhookEvent) error {
 	userID := event.Event.AppUserID
 
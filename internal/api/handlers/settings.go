@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/feels/feels/internal/api/middleware"
@@ -46,6 +47,10 @@ func (h *SettingsHandler) UpdateNotificationSettings(w http.ResponseWriter, r *h
 	}
 
 	if err := h.settingsService.UpdateNotificationSettings(r.Context(), userID, &req); err != nil {
+		if errors.Is(err, settings.ErrInvalidQuietHours) || errors.Is(err, settings.ErrInvalidTimezone) || errors.Is(err, settings.ErrInvalidLocale) {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		jsonError(w, "failed to update settings", http.StatusInternalServerError)
 		return
 	}
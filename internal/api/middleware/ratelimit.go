@@ -69,6 +69,12 @@ func (m *RateLimitMiddleware) Limit(next http.Handler) http.Handler {
 	})
 }
 
+// Allow counts a request against key, for limits that aren't per IP
+func (m *RateLimitMiddleware) Allow(ctx context.Context, key string) (bool, error) {
+	allowed, _, err := m.checkLimit(ctx, fmt.Sprintf("%s:%s", m.config.KeyPrefix, key))
+	return allowed, err
+}
+
 // checkLimit checks if request is allowed using sliding window counter
 func (m *RateLimitMiddleware) checkLimit(ctx context.Context, key string) (bool, int, error) {
 	pipe := m.redis.Pipeline()
@@ -116,6 +122,17 @@ func MagicLinkRateLimiter(redis *redis.Client) *RateLimitMiddleware {
 	})
 }
 
+// SafetySetupRateLimiter returns a rate limiter for setting up date safety check-ins (5 req/min)
+// Setting one up can message a trusted contact, so it fails closed like magic links
+func SafetySetupRateLimiter(redis *redis.Client) *RateLimitMiddleware {
+	return NewRateLimitMiddleware(redis, RateLimitConfig{
+		Requests:   5,
+		Window:     time.Minute,
+		KeyPrefix:  "rl:safety",
+		FailClosed: true,
+	})
+}
+
 // APIRateLimiter returns a general API rate limiter (100 req/min)
 func APIRateLimiter(redis *redis.Client) *RateLimitMiddleware {
 	return NewRateLimitMiddleware(redis, RateLimitConfig{
@@ -125,6 +142,15 @@ func APIRateLimiter(redis *redis.Client) *RateLimitMiddleware {
 	})
 }
 
+// PreKeyClaimRateLimiter returns a limiter for claiming E2E key bundles (10 req/min per device)
+func PreKeyClaimRateLimiter(redis *redis.Client) *RateLimitMiddleware {
+	return NewRateLimitMiddleware(redis, RateLimitConfig{
+		Requests:  10,
+		Window:    time.Minute,
+		KeyPrefix: "rl:prekeys",
+	})
+}
+
 // StrictRateLimiter returns a strict rate limiter for sensitive ops (3 req/min)
 func StrictRateLimiter(redis *redis.Client) *RateLimitMiddleware {
 	return NewRateLimitMiddleware(redis, RateLimitConfig{
@@ -10,20 +10,26 @@ import (
 	"github.com/feels/feels/internal/api/middleware"
 	"github.com/feels/feels/internal/config"
 	"github.com/feels/feels/internal/domain/credit"
+	"github.com/feels/feels/internal/domain/dateplan"
 	"github.com/feels/feels/internal/domain/feed"
+	"github.com/feels/feels/internal/domain/keys"
+	"github.com/feels/feels/internal/domain/mailer"
 	"github.com/feels/feels/internal/domain/match"
 	"github.com/feels/feels/internal/domain/message"
 	"github.com/feels/feels/internal/domain/moderation"
 	"github.com/feels/feels/internal/domain/notification"
 	"github.com/feels/feels/internal/domain/payment"
+	"github.com/feels/feels/internal/domain/presence"
 	"github.com/feels/feels/internal/domain/profile"
 	"github.com/feels/feels/internal/domain/referral"
 	"github.com/google/uuid"
 	"github.com/feels/feels/internal/domain/settings"
 	"github.com/feels/feels/internal/domain/user"
 	"github.com/feels/feels/internal/email"
+	"github.com/feels/feels/internal/jobs"
 	"github.com/feels/feels/internal/otp"
 	"github.com/feels/feels/internal/repository"
+	"github.com/feels/feels/internal/sms"
 	"github.com/feels/feels/internal/storage"
 	"github.com/feels/feels/internal/websocket"
 	"github.com/go-chi/chi/v5"
@@ -40,6 +46,7 @@ type Router struct {
 	redis  *redis.Client
 	authMw *middleware.AuthMiddleware
 	hub    *websocket.Hub
+	jobs   *jobs.Scheduler
 }
 
 // moderationAdapter adapts the moderation.Service to the message.ModerationService interface
@@ -54,7 +61,24 @@ func (a *moderationAdapter) CheckContent(ctx context.Context, userID uuid.UUID,
 
 func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client) *Router {
 	// Initialize WebSocket hub
-	hub := websocket.NewHub()
+	// Events fan out through Redis so they reach sockets on every replica
+	hub := websocket.NewRedisHub(redisClient)
+	hub.SetEventLog(websocket.NewEventLog(redisClient),
+		message.EventNewMessage,
+		message.EventMessageRead,
+		message.EventMatchCreated,
+		message.EventMatchDeleted,
+		message.EventMessageEdited,
+		message.EventMessageUnsent,
+		message.EventMessageReaction,
+		message.EventImageUnlockPrompt,
+		message.EventMessageViewed,
+		keys.EventDeviceKeysChanged,
+		jobs.EventConversationNudge,
+		dateplan.EventDatePlanUpdated,
+		notification.EventNotificationCreated,
+		notification.EventNotificationsRead,
+	)
 	go hub.Run()
 
 	// Initialize repositories
@@ -73,6 +97,8 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 	moderationRepo := repository.NewModerationRepository(db)
 	adminRepo := repository.NewAdminRepository(db)
 	referralRepo := repository.NewReferralRepository(db)
+	jobRepo := repository.NewJobRepository(db)
+	mailerRepo := repository.NewMailerRepository(db)
 
 	// Ensure passes table exists
 	if err := feedRepo.EnsurePassesTable(context.Background()); err != nil {
@@ -97,12 +123,13 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 	// Initialize S3 storage
 	log.Printf("[S3] Initializing with endpoint=%s bucket=%s ssl=%v public_url=%s", cfg.S3.Endpoint, cfg.S3.Bucket, cfg.S3.UseSSL, cfg.S3.PublicURL)
 	s3Client, err := storage.NewS3Client(storage.S3Config{
-		Endpoint:  cfg.S3.Endpoint,
-		AccessKey: cfg.S3.AccessKey,
-		SecretKey: cfg.S3.SecretKey,
-		Bucket:    cfg.S3.Bucket,
-		UseSSL:    cfg.S3.UseSSL,
-		PublicURL: cfg.S3.PublicURL,
+		Endpoint:      cfg.S3.Endpoint,
+		AccessKey:     cfg.S3.AccessKey,
+		SecretKey:     cfg.S3.SecretKey,
+		Bucket:        cfg.S3.Bucket,
+		UseSSL:        cfg.S3.UseSSL,
+		PublicURL:     cfg.S3.PublicURL,
+		PrivateBucket: cfg.S3.PrivateBucket,
 	})
 	if err != nil {
 		log.Printf("[S3] ERROR: Failed to initialize S3 client: %v", err)
@@ -120,6 +147,13 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 		TelnyxFromNumber: cfg.Telnyx.FromNumber,
 	})
 
+	// Outbound SMS (Twilio) for notifications and trusted contacts
+	smsService := sms.NewService(sms.Config{
+		AccountSID: cfg.SMS.AccountSID,
+		AuthToken:  cfg.SMS.AuthToken,
+		FromNumber: cfg.SMS.FromNumber,
+	})
+
 	// Initialize services
 	userService := user.NewService(
 		userRepo,
@@ -132,7 +166,10 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 	profileService := profile.NewService(profileRepo, s3Client)
 	// Payment service is initialized later and will be set on profile service
 	creditService := credit.NewService(creditRepo)
-	notificationService := notification.NewService(notificationRepo, notificationSettingsRepo)
+	notificationService := notification.NewService(notificationRepo, notificationSettingsRepo, notification.Config{
+		ExpoPushURL:     cfg.Push.ExpoPushURL,
+		ExpoReceiptsURL: cfg.Push.ExpoReceiptsURL,
+	})
 
 	feedService := feed.NewService(feedRepo, profileRepo, matchRepo, 100)
 	feedService.SetCreditService(creditService)
@@ -140,6 +177,7 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 	feedService.SetNotificationService(notificationService)
 	feedService.SetAnalyticsRepository(analyticsRepo)
 	feedService.SetUserRepository(userRepo)
+	feedService.SetDailyPicksRepository(feedRepo)
 	matchService := match.NewService(matchRepo, blockRepo)
 	matchService.SetMessageRepository(messageRepo)
 	matchService.SetHub(hub)
@@ -155,7 +193,29 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 		ReviewThreshold: cfg.Moderation.ReviewThreshold,
 	})
 	messageService.SetModerationService(&moderationAdapter{svc: moderationService})
+	hub.SetFrameHandler(messageService)
+	if s3Client != nil {
+		messageService.SetMediaStore(s3Client)
+		messageService.SetAudioStore(s3Client)
+	}
 	settingsService := settings.NewService(settingsRepo)
+	feedService.SetPrivacyService(settingsService)
+	matchService.SetPrivacyService(settingsService)
+	messageService.SetPrivacyService(settingsService)
+
+	// Per-device E2E key directory; logging a device out revokes its keys
+	keysService := keys.NewService(repository.NewKeyRepository(db), matchRepo)
+	keysService.SetHub(hub)
+	keysService.SetClaimRateLimiter(middleware.PreKeyClaimRateLimiter(redisClient))
+	userService.SetDeviceKeyRevoker(keysService)
+	messageService.SetDeviceDirectory(keysService)
+
+	// Presence is tracked in Redis so every replica sees every connection
+	presenceService := presence.NewService(redisClient, matchRepo, profileRepo)
+	presenceService.SetHub(hub)
+	presenceService.SetPrivacyService(settingsService)
+	hub.SetPresenceTracker(presenceService)
+	matchService.SetPresenceService(presenceService)
 	paymentService := payment.NewService(paymentRepo, userRepo, payment.Config{
 		SecretKey:        cfg.Stripe.SecretKey,
 		WebhookSecret:    cfg.Stripe.WebhookSecret,
@@ -178,11 +238,55 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 		FromName:  cfg.Email.FromName,
 	})
 
+	// Notification channels beyond push; the delivery log enables quiet hours and batching,
+	// and the inbox keeps every in-app notification
+	notificationDispatcher := notificationService.Dispatcher()
+	notificationDispatcher.SetDeliveryRepository(notificationRepo)
+	notificationService.SetInbox(notificationRepo, hub)
+	notificationService.SetTicketRepository(notificationRepo)
+	notificationDispatcher.AddChannel(notification.NewEmailChannel(emailService, notificationRepo))
+	notificationDispatcher.AddChannel(notification.NewSMSChannel(smsService, notificationRepo))
+
+	// Templated emails (digests, receipts, reminders) honor the email section of notification settings
+	unsubscribeSecret := cfg.Email.UnsubscribeSecret
+	if unsubscribeSecret == "" {
+		unsubscribeSecret = cfg.JWT.Secret
+	}
+	mailerService := mailer.NewService(mailerRepo, settingsService, emailService, mailer.Config{
+		LinkBaseURL: cfg.Email.LinkBaseURL,
+		Secret:      unsubscribeSecret,
+	})
+	userService.SetDeletionMailer(mailerService)
+	paymentService.SetReceiptMailer(mailerService)
+
+	// Date plans with safety check-ins; trusted contacts are reached by email or SMS
+	dateplanService := dateplan.NewService(repository.NewDatePlanRepository(db), matchRepo, profileRepo)
+	dateplanService.SetHub(hub)
+	dateplanService.SetNotifier(notificationService)
+	dateplanService.SetContactChannels(emailService, smsService)
+
+	// Initialize background jobs (Redis lock ensures one replica runs each job)
+	scheduler := jobs.NewScheduler(redisClient, jobRepo)
+	jobs.RegisterDefaultJobs(scheduler, jobRepo, notificationService)
+	jobs.RegisterConversationNudges(scheduler, jobRepo, notificationService, hub)
+	jobs.RegisterSafetyCheckIns(scheduler, dateplanService)
+	jobs.RegisterNotificationDispatch(scheduler, notificationDispatcher)
+	jobs.RegisterInboxCleanup(scheduler, notificationService)
+	jobs.RegisterPushReceipts(scheduler, notificationService)
+	jobs.RegisterEmailJobs(scheduler, jobRepo, mailerService)
+	if s3Client != nil {
+		jobs.RegisterViewOnceSweep(scheduler, jobRepo, s3Client)
+	}
+	if cfg.Jobs.Enabled {
+		scheduler.Start(context.Background())
+	}
+
 	// Initialize middleware
 	authMw := middleware.NewAuthMiddleware(userService)
 	adminMw := middleware.NewAdminMiddleware(userRepo)
 	authRateLimiter := middleware.AuthRateLimiter(redisClient)
 	magicLinkRateLimiter := middleware.MagicLinkRateLimiter(redisClient)
+	safetyRateLimiter := middleware.SafetySetupRateLimiter(redisClient)
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(db, redisClient)
@@ -193,14 +297,19 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 	feedHandler.SetSubscriptionChecker(paymentService)
 	matchHandler := handlers.NewMatchHandler(matchService)
 	messageHandler := handlers.NewMessageHandler(messageService, hub, s3Client)
+	keysHandler := handlers.NewKeysHandler(keysService)
+	dateplanHandler := handlers.NewDatePlanHandler(dateplanService)
 	creditHandler := handlers.NewCreditHandler(creditService)
 	settingsHandler := handlers.NewSettingsHandler(settingsService)
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	emailHandler := handlers.NewEmailHandler(mailerService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService, cfg.Stripe.WebhookSecret)
 	referralHandler := handlers.NewReferralHandler(referralService)
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsRepo, paymentService)
 	adminHandler := handlers.NewAdminHandler(adminRepo, userRepo)
 	revenueCatHandler := handlers.NewRevenueCatHandler(paymentRepo)
+	revenueCatHandler.SetReceiptMailer(mailerService)
+	jobsHandler := handlers.NewJobsHandler(scheduler)
 
 	r := &Router{
 		mux:    chi.NewRouter(),
@@ -209,10 +318,11 @@ func NewRouter(cfg *config.Config, db *pgxpool.Pool, redisClient *redis.Client)
 		redis:  redisClient,
 		authMw: authMw,
 		hub:    hub,
+		jobs:   scheduler,
 	}
 
 	r.setupMiddleware()
-	r.setupRoutes(healthHandler, authHandler, profileHandler, feedHandler, matchHandler, messageHandler, creditHandler, settingsHandler, notificationHandler, paymentHandler, analyticsHandler, adminHandler, adminMw, referralHandler, revenueCatHandler, authRateLimiter, magicLinkRateLimiter)
+	r.setupRoutes(healthHandler, authHandler, profileHandler, feedHandler, matchHandler, messageHandler, keysHandler, dateplanHandler, creditHandler, settingsHandler, notificationHandler, emailHandler, paymentHandler, analyticsHandler, adminHandler, adminMw, referralHandler, revenueCatHandler, jobsHandler, authRateLimiter, magicLinkRateLimiter, safetyRateLimiter)
 
 	return r
 }
@@ -241,17 +351,22 @@ func (r *Router) setupRoutes(
 	feedHandler *handlers.FeedHandler,
 	matchHandler *handlers.MatchHandler,
 	messageHandler *handlers.MessageHandler,
+	keysHandler *handlers.KeysHandler,
+	dateplanHandler *handlers.DatePlanHandler,
 	creditHandler *handlers.CreditHandler,
 	settingsHandler *handlers.SettingsHandler,
 	notificationHandler *handlers.NotificationHandler,
+	emailHandler *handlers.EmailHandler,
 	paymentHandler *handlers.PaymentHandler,
 	analyticsHandler *handlers.AnalyticsHandler,
 	adminHandler *handlers.AdminHandler,
 	adminMw *middleware.AdminMiddleware,
 	referralHandler *handlers.ReferralHandler,
 	revenueCatHandler *handlers.RevenueCatHandler,
+	jobsHandler *handlers.JobsHandler,
 	authRateLimiter *middleware.RateLimitMiddleware,
 	magicLinkRateLimiter *middleware.RateLimitMiddleware,
+	safetyRateLimiter *middleware.RateLimitMiddleware,
 ) {
 	// Health check routes (no auth required)
 	r.mux.Get("/health", healthHandler.Health)
@@ -261,6 +376,10 @@ func (r *Router) setupRoutes(
 	// Magic link redirect (root level - handles email link clicks)
 	r.mux.Get("/auth/magic", authHandler.MagicLinkRedirect)
 
+	// Email unsubscribe links (root level - signed token in the query string)
+	r.mux.Get("/email/unsubscribe", emailHandler.UnsubscribePage)
+	r.mux.Post("/email/unsubscribe", emailHandler.Unsubscribe)
+
 	// API v1 routes
 	r.mux.Route("/api/v1", func(router chi.Router) {
 		// Auth routes (public) with rate limiting
@@ -348,12 +467,34 @@ func (r *Router) setupRoutes(
 				m.Delete("/{id}", matchHandler.Unmatch)
 				m.Get("/{id}/messages", messageHandler.GetMessages)
 				m.Post("/{id}/messages", messageHandler.SendMessage)
+				m.Get("/{id}/messages/search", messageHandler.SearchMessages)
+				m.Patch("/{id}/messages/{messageId}", messageHandler.EditMessage)
+				m.Delete("/{id}/messages/{messageId}", messageHandler.UnsendMessage)
+				m.Put("/{id}/messages/{messageId}/reaction", messageHandler.React)
+				m.Delete("/{id}/messages/{messageId}/reaction", messageHandler.RemoveReaction)
+				m.Post("/{id}/messages/{messageId}/view", messageHandler.ViewImage)
 				m.Post("/{id}/images/enable", messageHandler.EnableImages)
 				m.Post("/{id}/images/disable", messageHandler.DisableImages)
 				m.Post("/{id}/images/upload", messageHandler.UploadImage)
+				m.Post("/{id}/audio/upload", messageHandler.UploadVoiceNote)
 				m.Post("/{id}/typing", messageHandler.Typing)
+				m.Post("/{id}/keys/claim", keysHandler.ClaimMatchBundles)
+				m.Get("/{id}/date-plans", dateplanHandler.GetPlans)
+				m.Post("/{id}/date-plans", dateplanHandler.CreatePlan)
 			})
 
+			// Date plans and safety check-ins
+			protected.Route("/date-plans", func(dp chi.Router) {
+				dp.Post("/{planId}/respond", dateplanHandler.RespondToPlan)
+				dp.Post("/{planId}/cancel", dateplanHandler.CancelPlan)
+				dp.With(safetyRateLimiter.Limit).Put("/{planId}/safety", dateplanHandler.SetupSafety)
+				dp.Post("/{planId}/check-in", dateplanHandler.CheckIn)
+			})
+
+			// Conversation list with per-user mute, archive and pin
+			protected.Get("/conversations", messageHandler.GetConversations)
+			protected.Patch("/conversations/{id}", messageHandler.UpdateConversation)
+
 			// Safety routes
 			protected.Post("/block/{id}", matchHandler.Block)
 			protected.Delete("/block/{id}", matchHandler.Unblock)
@@ -365,11 +506,18 @@ func (r *Router) setupRoutes(
 			// Credits routes
 			protected.Get("/credits", creditHandler.GetCredits)
 			protected.Get("/subscription", creditHandler.GetSubscription)
+			protected.Post("/boost", creditHandler.ActivateBoost)
 
 			// Public key management for E2E encryption
 			protected.Post("/keys/public", authHandler.SetPublicKey)
 			protected.Get("/keys/public", authHandler.GetPublicKey)
 
+			// Per-device key directory for multi-device E2E encryption
+			protected.Put("/keys/devices", keysHandler.RegisterDevice)
+			protected.Post("/keys/devices/prekeys", keysHandler.UploadPreKeys)
+			protected.Get("/keys/devices/prekeys/count", keysHandler.GetPreKeyCount)
+			protected.Delete("/keys/devices/{deviceId}", keysHandler.RevokeDevice)
+
 			// Settings routes
 			protected.Route("/settings", func(s chi.Router) {
 				s.Get("/notifications", settingsHandler.GetNotificationSettings)
@@ -382,6 +530,14 @@ func (r *Router) setupRoutes(
 			protected.Post("/push/register", notificationHandler.RegisterToken)
 			protected.Delete("/push/register", notificationHandler.UnregisterToken)
 
+			// Notification inbox
+			protected.Route("/notifications", func(n chi.Router) {
+				n.Get("/", notificationHandler.GetNotifications)
+				n.Get("/unread-count", notificationHandler.GetUnreadCount)
+				n.Post("/read", notificationHandler.MarkRead)
+				n.Post("/read-all", notificationHandler.MarkAllRead)
+			})
+
 			// Payment routes (protected)
 			protected.Route("/payments", func(pay chi.Router) {
 				pay.Post("/checkout", paymentHandler.CreateCheckout)
@@ -408,6 +564,7 @@ func (r *Router) setupRoutes(
 				// User management
 				admin.Get("/users/{id}", adminHandler.GetUserDetails)
 				admin.Post("/users/{id}/moderate", adminHandler.ModerateUser)
+				admin.Get("/users/{id}/notification-deliveries", notificationHandler.GetDeliveries)
 
 				// Verification queue
 				admin.Get("/verification-queue", adminHandler.GetVerificationQueue)
@@ -416,11 +573,26 @@ func (r *Router) setupRoutes(
 				// Content moderation queue
 				admin.Get("/moderation-queue", adminHandler.GetModerationQueue)
 				admin.Post("/moderation/{id}", adminHandler.ActionOnModeration)
+
+				// Background jobs
+				admin.Get("/jobs", jobsHandler.ListJobs)
+				admin.Get("/jobs/{name}/runs", jobsHandler.GetJobRuns)
+				admin.Post("/jobs/{name}/run", jobsHandler.TriggerJob)
+
+				// Date safety
+				admin.Get("/date-plans/{id}/audit", dateplanHandler.GetPlanAudit)
+				admin.Get("/safety/escalations", dateplanHandler.ListEscalations)
 			})
 		})
 	})
 }
 
+// Shutdown stops background work started by the router
+func (r *Router) Shutdown() {
+	r.jobs.Stop()
+	r.hub.Close()
+}
+
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.mux.ServeHTTP(w, req)
 }
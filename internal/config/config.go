@@ -19,6 +19,17 @@ type Config struct {
 	Sentry     SentryConfig
 	OpenAI     OpenAIConfig
 	Moderation ModerationConfig
+	Jobs       JobsConfig
+	Push       PushConfig
+}
+
+type PushConfig struct {
+	ExpoPushURL     string // Expo push endpoint; overridable so tests can use a local stand-in
+	ExpoReceiptsURL string // Expo receipts endpoint; empty means getReceipts beside ExpoPushURL
+}
+
+type JobsConfig struct {
+	Enabled bool // run the background job scheduler in this process
 }
 
 type SMSConfig struct {
@@ -48,9 +59,11 @@ type ModerationConfig struct {
 }
 
 type EmailConfig struct {
-	APIKey    string
-	FromEmail string
-	FromName  string
+	APIKey            string
+	FromEmail         string
+	FromName          string
+	LinkBaseURL       string // public API URL used for unsubscribe links
+	UnsubscribeSecret string // signs unsubscribe links; defaults to the JWT secret
 }
 
 type StripeConfig struct {
@@ -81,12 +94,13 @@ type JWTConfig struct {
 }
 
 type S3Config struct {
-	Endpoint  string
-	AccessKey string
-	SecretKey string
-	Bucket    string
-	UseSSL    bool
-	PublicURL string
+	Endpoint      string
+	AccessKey     string
+	SecretKey     string
+	Bucket        string
+	UseSSL        bool
+	PublicURL     string
+	PrivateBucket string // not publicly readable; objects are served through presigned links
 }
 
 func Load() *Config {
@@ -107,12 +121,13 @@ func Load() *Config {
 			RefreshExpiry: parseDuration(getEnv("JWT_REFRESH_EXPIRY", "168h")), // 7 days
 		},
 		S3: S3Config{
-			Endpoint:  getEnv("S3_ENDPOINT", "localhost:9000"),
-			AccessKey: getEnv("S3_ACCESS_KEY", "minioadmin"),
-			SecretKey: getEnv("S3_SECRET_KEY", "minioadmin"),
-			Bucket:    getEnv("S3_BUCKET", "feels-photos"),
-			UseSSL:    getEnvBool("S3_USE_SSL", false),
-			PublicURL: getEnv("S3_PUBLIC_URL", ""),
+			Endpoint:      getEnv("S3_ENDPOINT", "localhost:9000"),
+			AccessKey:     getEnv("S3_ACCESS_KEY", "minioadmin"),
+			SecretKey:     getEnv("S3_SECRET_KEY", "minioadmin"),
+			Bucket:        getEnv("S3_BUCKET", "feels-photos"),
+			UseSSL:        getEnvBool("S3_USE_SSL", false),
+			PublicURL:     getEnv("S3_PUBLIC_URL", ""),
+			PrivateBucket: getEnv("S3_PRIVATE_BUCKET", "feels-private"),
 		},
 		Stripe: StripeConfig{
 			SecretKey:        getEnv("STRIPE_SECRET_KEY", ""),
@@ -122,15 +137,21 @@ func Load() *Config {
 			AnnualPriceID:    getEnv("STRIPE_ANNUAL_PRICE_ID", ""),
 		},
 		Email: EmailConfig{
-			APIKey:    getEnv("RESEND_API_KEY", ""),
-			FromEmail: getEnv("EMAIL_FROM", ""),
-			FromName:  getEnv("EMAIL_FROM_NAME", "Feels"),
+			APIKey:            getEnv("RESEND_API_KEY", ""),
+			FromEmail:         getEnv("EMAIL_FROM", ""),
+			FromName:          getEnv("EMAIL_FROM_NAME", "Feels"),
+			LinkBaseURL:       getEnv("EMAIL_LINK_BASE_URL", "https://api.feelsfun.app"),
+			UnsubscribeSecret: getEnv("EMAIL_UNSUBSCRIBE_SECRET", ""),
 		},
 		SMS: SMSConfig{
 			AccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
 			AuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
 			FromNumber: getEnv("TWILIO_FROM_NUMBER", ""),
 		},
+		Push: PushConfig{
+			ExpoPushURL:     getEnv("EXPO_PUSH_URL", "https://exp.host/--/api/v2/push/send"),
+			ExpoReceiptsURL: getEnv("EXPO_RECEIPTS_URL", ""),
+		},
 		Telnyx: TelnyxConfig{
 			APIKey:     getEnv("TELNYX_API_KEY", ""),
 			FromNumber: getEnv("TELNYX_FROM_NUMBER", ""),
@@ -147,6 +168,9 @@ func Load() *Config {
 			BlockThreshold:  getEnvFloat("MODERATION_BLOCK_THRESHOLD", 0.9),
 			ReviewThreshold: getEnvFloat("MODERATION_REVIEW_THRESHOLD", 0.7),
 		},
+		Jobs: JobsConfig{
+			Enabled: getEnvBool("JOBS_ENABLED", true),
+		},
 	}
 
 	// Security: refuse to start in production with weak JWT secret
@@ -43,6 +43,51 @@ const (
 	BoostsPerWeek         = 1
 )
 
+// Boost settings
+const (
+	BoostDuration   = 30 * time.Minute // how long a boost keeps a profile ranked up
+	BoostCreditCost = 50               // credits for a boost beyond the weekly allowance
+)
+
+// Boost sources
+const (
+	BoostSourceWeekly  = "weekly"  // included weekly boost
+	BoostSourceCredits = "credits" // paid with credits
+)
+
+// Boost represents a period during which a user's profile is ranked up in the feed
+type Boost struct {
+	ID           uuid.UUID `json:"id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Source       string    `json:"source"`
+	CreditsSpent int       `json:"credits_spent"`
+	StartedAt    time.Time `json:"started_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// RemainingSeconds returns the seconds left on the boost (0 if expired)
+func (b *Boost) RemainingSeconds() int {
+	remaining := int(time.Until(b.ExpiresAt).Seconds())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ActiveBoostResponse describes the user's currently running boost
+type ActiveBoostResponse struct {
+	ExpiresAt        time.Time `json:"expires_at"`
+	RemainingSeconds int       `json:"remaining_seconds"`
+}
+
+// BoostResponse is the API response for activating a boost
+type BoostResponse struct {
+	Boost       *Boost `json:"boost"`
+	BoostsUsed  int    `json:"boosts_used"`
+	BoostsLimit int    `json:"boosts_limit"`
+	Balance     int    `json:"balance"`
+}
+
 // Credit represents a user's credit balance and daily usage
 type Credit struct {
 	UserID            uuid.UUID  `json:"user_id"`
@@ -84,6 +129,7 @@ type BalanceResponse struct {
 	BoostsUsed         int  `json:"boosts_used"`
 	BoostsLimit        int  `json:"boosts_limit"`
 	HasSubscription    bool `json:"has_subscription"`
+	ActiveBoost        *ActiveBoostResponse `json:"active_boost,omitempty"`
 }
 
 // SubscriptionResponse is the API response for subscription status
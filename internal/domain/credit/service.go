@@ -12,6 +12,7 @@ var (
 	ErrInsufficientCredits = errors.New("insufficient credits")
 	ErrDailyLimitReached   = errors.New("daily like limit reached")
 	ErrNoActiveSubscription = errors.New("no active subscription")
+	ErrBoostActive         = errors.New("boost already active")
 )
 
 type Repository interface {
@@ -36,6 +37,9 @@ type Repository interface {
 	UseBonusLikeAtomic(ctx context.Context, userID uuid.UUID) error
 	UseDailyLikeAtomic(ctx context.Context, userID uuid.UUID, limit int) error
 	DeductCreditsAtomic(ctx context.Context, userID uuid.UUID, amount int) error
+	// Boosts
+	GetActiveBoost(ctx context.Context, userID uuid.UUID) (*Boost, error)
+	ActivateBoost(ctx context.Context, userID uuid.UUID, weeklyLimit, creditCost int, duration time.Duration) (*Boost, error)
 }
 
 type Service struct {
@@ -84,9 +88,51 @@ func (s *Service) GetBalance(ctx context.Context, userID uuid.UUID) (*BalanceRes
 		resp.BoostsLimit = BoostsPerWeek
 	}
 
+	boost, err := s.repo.GetActiveBoost(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if boost != nil {
+		resp.ActiveBoost = &ActiveBoostResponse{
+			ExpiresAt:        boost.ExpiresAt,
+			RemainingSeconds: boost.RemainingSeconds(),
+		}
+	}
+
 	return resp, nil
 }
 
+// Boost activates a profile boost for BoostDuration
+// Subscribers use their weekly boost first; otherwise the boost costs BoostCreditCost credits
+func (s *Service) Boost(ctx context.Context, userID uuid.UUID) (*BoostResponse, error) {
+	hasSub, err := s.repo.HasSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	weeklyLimit := 0
+	if hasSub {
+		weeklyLimit = BoostsPerWeek
+	}
+
+	boost, err := s.repo.ActivateBoost(ctx, userID, weeklyLimit, BoostCreditCost, BoostDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.repo.GetCredit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoostResponse{
+		Boost:       boost,
+		BoostsUsed:  c.BoostsUsed,
+		BoostsLimit: weeklyLimit,
+		Balance:     c.Balance,
+	}, nil
+}
+
 // GetSubscription returns the user's subscription status
 func (s *Service) GetSubscription(ctx context.Context, userID uuid.UUID) (*SubscriptionResponse, error) {
 	sub, err := s.repo.GetSubscription(ctx, userID)
@@ -0,0 +1,251 @@
+package dateplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// e164 matches a phone number in international format, which the SMS provider requires
+var e164 = regexp.MustCompile(`^\+[1-9]\d{7,14}$`)
+
+const maxContactNameLength = 100
+
+// SetupSafety sets the user's check-in time and optional trusted contact for a plan
+// Setting it up again replaces the previous check-in
+func (s *Service) SetupSafety(ctx context.Context, userID, planID uuid.UUID, req *SafetyRequest) (*CheckIn, error) {
+	plan, err := s.getParticipantPlan(ctx, userID, planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Status == StatusCancelled || plan.Status == StatusDeclined {
+		return nil, ErrPlanClosed
+	}
+
+	now := time.Now()
+	if !req.CheckInAt.After(now) || req.CheckInAt.After(plan.ScheduledAt.Add(MaxCheckInAfterDate)) {
+		return nil, ErrInvalidCheckIn
+	}
+	contact, err := normalizeContact(req.Contact)
+	if err != nil {
+		return nil, err
+	}
+	if req.ShareWithContact && contact == nil {
+		return nil, ErrInvalidContact
+	}
+
+	// Only a new or changed contact is told about the plan, so setting the
+	// check-in up again doesn't message the same person over and over
+	share := false
+	if req.ShareWithContact {
+		prev, err := s.repo.GetCheckIn(ctx, planID, userID)
+		if err != nil && !errors.Is(err, ErrCheckInNotFound) {
+			return nil, err
+		}
+		share = prev == nil || !sameRecipient(prev.Contact, contact)
+	}
+	if share {
+		shared, err := s.repo.CountAuditByActor(ctx, userID, []string{ActionContactShared, ActionContactFailed}, now.Add(-24*time.Hour))
+		if err != nil {
+			return nil, err
+		}
+		if shared >= MaxContactSharesPerDay {
+			return nil, ErrTooManyShares
+		}
+	}
+
+	ci := &CheckIn{
+		ID:        uuid.New(),
+		PlanID:    planID,
+		UserID:    userID,
+		Contact:   contact,
+		CheckInAt: req.CheckInAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.UpsertCheckIn(ctx, ci); err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{"check_in_at": ci.CheckInAt, "has_contact": contact != nil, "shared": share}
+	s.audit(ctx, planID, &userID, ActionSafetySet, details)
+
+	if share {
+		channel, err := s.notifyContact(ctx, userID, plan, contact, false)
+		details := map[string]interface{}{"email": contact.Email, "phone": contact.Phone}
+		if err != nil {
+			log.Printf("[DATEPLAN] Failed to share plan %s with contact: %v", planID, err)
+			details["error"] = err.Error()
+			s.audit(ctx, planID, &userID, ActionContactFailed, details)
+		} else {
+			details["channel"] = channel
+			s.audit(ctx, planID, &userID, ActionContactShared, details)
+		}
+	}
+	return ci, nil
+}
+
+// sameRecipient reports whether two contacts reach the same email and phone
+func sameRecipient(a, b *TrustedContact) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return equalPtr(a.Email, b.Email) && equalPtr(a.Phone, b.Phone)
+}
+
+func equalPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// CheckIn confirms the user is safe, stopping any reminder or escalation
+func (s *Service) CheckIn(ctx context.Context, userID, planID uuid.UUID) (*CheckIn, error) {
+	if _, err := s.getParticipantPlan(ctx, userID, planID); err != nil {
+		return nil, err
+	}
+
+	ci, err := s.repo.GetCheckIn(ctx, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ci.CheckedInAt == nil {
+		if _, err := s.repo.MarkCheckedIn(ctx, ci.ID); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		ci.CheckedInAt = &now
+		s.audit(ctx, planID, &userID, ActionCheckedIn, map[string]interface{}{"after_escalation": ci.EscalatedAt != nil})
+	}
+	return ci, nil
+}
+
+// ProcessDueCheckIns reminds users whose check-in time has come and escalates those
+// who haven't confirmed within CheckInGrace. It is run by the safety check-in job.
+func (s *Service) ProcessDueCheckIns(ctx context.Context, now time.Time) (reminded, escalated int, err error) {
+	toRemind, err := s.repo.GetCheckInsToRemind(ctx, now, dueBatch)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, ci := range toRemind {
+		// The conditional update stops two runs reminding the same check-in
+		if ok, err := s.repo.MarkReminded(ctx, ci.ID); err != nil || !ok {
+			continue
+		}
+		if s.notifier != nil {
+			if err := s.notifier.SendCheckInNotification(ctx, ci.UserID, ci.PlanID, false); err != nil {
+				log.Printf("[DATEPLAN] Check-in reminder for %s failed: %v", ci.UserID, err)
+			}
+		}
+		s.audit(ctx, ci.PlanID, nil, ActionReminderSent, nil)
+		reminded++
+	}
+
+	toEscalate, err := s.repo.GetCheckInsToEscalate(ctx, now.Add(-CheckInGrace), dueBatch)
+	if err != nil {
+		return reminded, 0, err
+	}
+	for _, ci := range toEscalate {
+		if ok, err := s.repo.MarkEscalated(ctx, ci.ID); err != nil || !ok {
+			continue
+		}
+		s.escalate(ctx, ci)
+		escalated++
+	}
+	return reminded, escalated, nil
+}
+
+// escalate tells the user and their trusted contact that a check-in was missed
+func (s *Service) escalate(ctx context.Context, ci CheckIn) {
+	if s.notifier != nil {
+		if err := s.notifier.SendCheckInNotification(ctx, ci.UserID, ci.PlanID, true); err != nil {
+			log.Printf("[DATEPLAN] Escalation push for %s failed: %v", ci.UserID, err)
+		}
+	}
+
+	details := map[string]interface{}{"user_id": ci.UserID, "check_in_at": ci.CheckInAt}
+	if ci.Contact != nil {
+		plan, err := s.repo.GetPlan(ctx, ci.PlanID)
+		if err == nil {
+			var channel string
+			channel, err = s.notifyContact(ctx, ci.UserID, plan, ci.Contact, true)
+			details["contact_channel"] = channel
+		}
+		if err != nil {
+			log.Printf("[DATEPLAN] Escalation to contact for plan %s failed: %v", ci.PlanID, err)
+			s.audit(ctx, ci.PlanID, nil, ActionContactFailed, map[string]interface{}{"error": err.Error()})
+		}
+	}
+	s.audit(ctx, ci.PlanID, nil, ActionEscalated, details)
+}
+
+// notifyContact emails or texts a trusted contact about the plan, preferring email
+// It returns the channel used
+func (s *Service) notifyContact(ctx context.Context, userID uuid.UUID, plan *DatePlan, contact *TrustedContact, missed bool) (string, error) {
+	userName := s.name(ctx, userID)
+	when := plan.ScheduledAt.UTC().Format("Mon, Jan 2 at 3:04 PM UTC")
+	place := plan.PlaceName
+	if plan.PlaceAddress != nil && *plan.PlaceAddress != "" {
+		place += ", " + *plan.PlaceAddress
+	}
+
+	if contact.Email != nil && s.emailer != nil {
+		var err error
+		if missed {
+			err = s.emailer.SendCheckInEscalation(ctx, *contact.Email, contact.Name, userName, when, place)
+		} else {
+			err = s.emailer.SendDatePlanShared(ctx, *contact.Email, contact.Name, userName, when, place)
+		}
+		if err == nil || contact.Phone == nil || s.texter == nil {
+			return "email", err
+		}
+	}
+
+	if contact.Phone != nil && s.texter != nil {
+		var text string
+		if missed {
+			text = fmt.Sprintf("Feels safety alert: %s missed a check-in after a date (%s at %s). Please try to reach them.", userName, when, place)
+		} else {
+			text = fmt.Sprintf("Feels: %s shared their date plans with you: %s at %s. We'll let you know if they miss a check-in.", userName, when, place)
+		}
+		return "sms", s.texter.Send(ctx, *contact.Phone, text)
+	}
+
+	return "", fmt.Errorf("no channel available for trusted contact")
+}
+
+func normalizeContact(c *TrustedContact) (*TrustedContact, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	out := &TrustedContact{Name: strings.TrimSpace(c.Name)}
+	if c.Email != nil && strings.TrimSpace(*c.Email) != "" {
+		addr, err := mail.ParseAddress(strings.TrimSpace(*c.Email))
+		if err != nil {
+			return nil, ErrInvalidContact
+		}
+		out.Email = &addr.Address
+	}
+	if c.Phone != nil && strings.TrimSpace(*c.Phone) != "" {
+		phone := strings.TrimSpace(*c.Phone)
+		if !e164.MatchString(phone) {
+			return nil, ErrInvalidContact
+		}
+		out.Phone = &phone
+	}
+	if out.Name == "" || utf8.RuneCountInString(out.Name) > maxContactNameLength || (out.Email == nil && out.Phone == nil) {
+		return nil, ErrInvalidContact
+	}
+	return out, nil
+}
@@ -0,0 +1,181 @@
+package dateplan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryPlans is an in-memory Repository for plans, check-ins and the audit trail
+type memoryPlans struct {
+	Repository
+	plans    map[uuid.UUID]*DatePlan
+	checkIns map[[2]uuid.UUID]*CheckIn
+	audit    []AuditEntry
+}
+
+func newMemoryPlans(plans ...*DatePlan) *memoryPlans {
+	r := &memoryPlans{plans: make(map[uuid.UUID]*DatePlan), checkIns: make(map[[2]uuid.UUID]*CheckIn)}
+	for _, p := range plans {
+		r.plans[p.ID] = p
+	}
+	return r
+}
+
+func (r *memoryPlans) GetPlan(ctx context.Context, planID uuid.UUID) (*DatePlan, error) {
+	if p, ok := r.plans[planID]; ok {
+		return p, nil
+	}
+	return nil, ErrPlanNotFound
+}
+
+func (r *memoryPlans) UpsertCheckIn(ctx context.Context, ci *CheckIn) error {
+	stored := *ci
+	r.checkIns[[2]uuid.UUID{ci.PlanID, ci.UserID}] = &stored
+	return nil
+}
+
+func (r *memoryPlans) GetCheckIn(ctx context.Context, planID, userID uuid.UUID) (*CheckIn, error) {
+	if ci, ok := r.checkIns[[2]uuid.UUID{planID, userID}]; ok {
+		return ci, nil
+	}
+	return nil, ErrCheckInNotFound
+}
+
+func (r *memoryPlans) AddAudit(ctx context.Context, e *AuditEntry) error {
+	r.audit = append(r.audit, *e)
+	return nil
+}
+
+func (r *memoryPlans) CountAuditByActor(ctx context.Context, actorID uuid.UUID, actions []string, since time.Time) (int, error) {
+	count := 0
+	for _, e := range r.audit {
+		if e.ActorID == nil || *e.ActorID != actorID || !e.CreatedAt.After(since) {
+			continue
+		}
+		for _, action := range actions {
+			if e.Action == action {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryPlans) actions(action string) []AuditEntry {
+	var entries []AuditEntry
+	for _, e := range r.audit {
+		if e.Action == action {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// sentTexts records texts instead of sending them
+type sentTexts struct {
+	to []string
+}
+
+func (t *sentTexts) Send(ctx context.Context, to, message string) error {
+	t.to = append(t.to, to)
+	return nil
+}
+
+func newSafetyService(plans ...*DatePlan) (*Service, *memoryPlans, *sentTexts) {
+	repo := newMemoryPlans(plans...)
+	texts := &sentTexts{}
+	s := NewService(repo, nil, nil)
+	s.SetContactChannels(nil, texts)
+	return s, repo, texts
+}
+
+func newPlan(userID uuid.UUID) *DatePlan {
+	return &DatePlan{
+		ID:          uuid.New(),
+		ProposerID:  userID,
+		InviteeID:   uuid.New(),
+		ScheduledAt: time.Now().Add(48 * time.Hour),
+		PlaceName:   "Cafe",
+		Status:      StatusAccepted,
+	}
+}
+
+func shareWith(plan *DatePlan, phone string) *SafetyRequest {
+	return &SafetyRequest{
+		CheckInAt:        plan.ScheduledAt.Add(2 * time.Hour),
+		Contact:          &TrustedContact{Name: "Alex", Phone: &phone},
+		ShareWithContact: true,
+	}
+}
+
+func TestSetupSafety_SharesOnlyWithANewContact(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	plan := newPlan(userID)
+	s, repo, texts := newSafetyService(plan)
+
+	_, err := s.SetupSafety(ctx, userID, plan.ID, shareWith(plan, "+15550000001"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"+15550000001"}, texts.to)
+
+	// Setting the same contact up again, even under another name, doesn't text them again
+	req := shareWith(plan, "+15550000001")
+	req.Contact.Name = "Alex B"
+	req.CheckInAt = req.CheckInAt.Add(time.Hour)
+	_, err = s.SetupSafety(ctx, userID, plan.ID, req)
+	require.NoError(t, err)
+	assert.Len(t, texts.to, 1)
+
+	_, err = s.SetupSafety(ctx, userID, plan.ID, shareWith(plan, "+15550000002"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"+15550000001", "+15550000002"}, texts.to)
+
+	// Every share is in the audit trail with who it went to
+	shares := repo.actions(ActionContactShared)
+	require.Len(t, shares, 2)
+	var details map[string]interface{}
+	require.NoError(t, json.Unmarshal(shares[1].Details, &details))
+	assert.Equal(t, "+15550000002", details["phone"])
+	assert.Equal(t, "sms", details["channel"])
+}
+
+func TestSetupSafety_CapsContactSharesPerDay(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	plan := newPlan(userID)
+	s, repo, texts := newSafetyService(plan)
+
+	for i := 0; i < MaxContactSharesPerDay; i++ {
+		_, err := s.SetupSafety(ctx, userID, plan.ID, shareWith(plan, fmt.Sprintf("+1555000000%d", i)))
+		require.NoError(t, err)
+	}
+	require.Len(t, texts.to, MaxContactSharesPerDay)
+
+	_, err := s.SetupSafety(ctx, userID, plan.ID, shareWith(plan, "+15550000099"))
+	assert.ErrorIs(t, err, ErrTooManyShares)
+	assert.Len(t, texts.to, MaxContactSharesPerDay)
+	ci, err := repo.GetCheckIn(ctx, plan.ID, userID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "+15550000099", *ci.Contact.Phone, "a refused share changes nothing")
+
+	// Without sharing, the check-in can still be set up
+	req := shareWith(plan, "+15550000099")
+	req.ShareWithContact = false
+	_, err = s.SetupSafety(ctx, userID, plan.ID, req)
+	require.NoError(t, err)
+
+	// Shares older than a day no longer count
+	for i := range repo.audit {
+		repo.audit[i].CreatedAt = repo.audit[i].CreatedAt.Add(-25 * time.Hour)
+	}
+	_, err = s.SetupSafety(ctx, userID, plan.ID, shareWith(plan, "+15550000100"))
+	require.NoError(t, err)
+	assert.Len(t, texts.to, MaxContactSharesPerDay+1)
+}
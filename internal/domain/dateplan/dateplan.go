@@ -0,0 +1,125 @@
+package dateplan
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Plan statuses
+const (
+	StatusProposed  = "proposed"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+)
+
+// Audit actions, recorded for every change to a plan or check-in
+const (
+	ActionCreated          = "created"
+	ActionAccepted         = "accepted"
+	ActionDeclined         = "declined"
+	ActionCancelled        = "cancelled"
+	ActionSafetySet        = "safety_set"
+	ActionContactShared    = "contact_shared"
+	ActionCheckedIn        = "checked_in"
+	ActionReminderSent     = "reminder_sent"
+	ActionEscalated        = "escalated"
+	ActionContactFailed    = "contact_notify_failed"
+	ActionCheckInCancelled = "check_in_cancelled"
+)
+
+// WebSocket event type
+const EventDatePlanUpdated = "date_plan_updated"
+
+// WSMessage is a WebSocket message envelope
+type WSMessage struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// DatePlan is a proposed in-person date between two matched users
+// MatchID is nil once the match is gone; the plan and its audit trail are kept
+type DatePlan struct {
+	ID           uuid.UUID  `json:"id"`
+	MatchID      *uuid.UUID `json:"match_id,omitempty"`
+	ProposerID   uuid.UUID  `json:"proposer_id"`
+	InviteeID    uuid.UUID  `json:"invitee_id"`
+	ScheduledAt  time.Time  `json:"scheduled_at"`
+	PlaceName    string     `json:"place_name"`
+	PlaceAddress *string    `json:"place_address,omitempty"`
+	Lat          *float64   `json:"lat,omitempty"`
+	Lng          *float64   `json:"lng,omitempty"`
+	Notes        *string    `json:"notes,omitempty"`
+	Status       string     `json:"status"`
+	RespondedAt  *time.Time `json:"responded_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// CheckIn is the viewing user's own safety check-in; never the other person's
+	CheckIn *CheckIn `json:"check_in,omitempty"`
+}
+
+// TrustedContact is someone outside the app told about the date if a check-in is missed
+type TrustedContact struct {
+	Name  string  `json:"name"`
+	Email *string `json:"email,omitempty"`
+	Phone *string `json:"phone,omitempty"`
+}
+
+// CheckIn is one user's safety check-in for a plan
+// The user is reminded at CheckInAt and escalated after CheckInGrace without confirming
+type CheckIn struct {
+	ID          uuid.UUID       `json:"id"`
+	PlanID      uuid.UUID       `json:"plan_id"`
+	UserID      uuid.UUID       `json:"user_id"`
+	Contact     *TrustedContact `json:"trusted_contact,omitempty"`
+	CheckInAt   time.Time       `json:"check_in_at"`
+	RemindedAt  *time.Time      `json:"reminded_at,omitempty"`
+	CheckedInAt *time.Time      `json:"checked_in_at,omitempty"`
+	EscalatedAt *time.Time      `json:"escalated_at,omitempty"`
+	CancelledAt *time.Time      `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// AuditEntry records one action on a plan; ActorID is nil for actions taken by the server
+type AuditEntry struct {
+	ID        uuid.UUID       `json:"id"`
+	PlanID    uuid.UUID       `json:"plan_id"`
+	ActorID   *uuid.UUID      `json:"actor_id,omitempty"`
+	Action    string          `json:"action"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// CreatePlanRequest proposes a date to the other person in a match
+type CreatePlanRequest struct {
+	ScheduledAt  time.Time `json:"scheduled_at"`
+	PlaceName    string    `json:"place_name"`
+	PlaceAddress *string   `json:"place_address,omitempty"`
+	Lat          *float64  `json:"lat,omitempty"`
+	Lng          *float64  `json:"lng,omitempty"`
+	Notes        *string   `json:"notes,omitempty"`
+}
+
+// RespondRequest accepts or declines a proposed plan
+type RespondRequest struct {
+	Accept bool `json:"accept"`
+}
+
+// SafetyRequest sets up (or replaces) the user's check-in for a plan
+// With ShareWithContact the trusted contact is sent the plan details right away
+type SafetyRequest struct {
+	CheckInAt        time.Time       `json:"check_in_at"`
+	Contact          *TrustedContact `json:"trusted_contact,omitempty"`
+	ShareWithContact bool            `json:"share_with_contact"`
+}
+
+// PlanAudit is a plan with its check-ins and full audit trail, for admins
+type PlanAudit struct {
+	Plan     DatePlan     `json:"plan"`
+	CheckIns []CheckIn    `json:"check_ins"`
+	Audit    []AuditEntry `json:"audit"`
+}
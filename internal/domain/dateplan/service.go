@@ -0,0 +1,368 @@
+package dateplan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotInMatch      = errors.New("not in match")
+	ErrPlanNotFound    = errors.New("date plan not found")
+	ErrNotInvitee      = errors.New("only the invited person can respond to this plan")
+	ErrPlanClosed      = errors.New("date plan is no longer open")
+	ErrInvalidPlan     = errors.New("a date plan needs a future time (within 90 days) and a place")
+	ErrInvalidCheckIn  = errors.New("check-in time must be in the future and within a day of the date")
+	ErrInvalidContact  = errors.New("trusted contact needs a name and an email or phone number")
+	ErrCheckInNotFound = errors.New("no check-in set up for this plan")
+	ErrTooManyShares   = errors.New("you've shared too many date plans today, try again tomorrow")
+)
+
+const (
+	// CheckInGrace is how long after the check-in time the user has to confirm before escalation
+	CheckInGrace = 15 * time.Minute
+
+	// MaxPlanAhead is how far ahead a date can be planned
+	MaxPlanAhead = 90 * 24 * time.Hour
+
+	// MaxCheckInAfterDate is the latest a check-in can be set after the date starts
+	MaxCheckInAfterDate = 24 * time.Hour
+
+	// MaxContactSharesPerDay caps how many times a user can message trusted contacts
+	// about their plans, so the share can't be used to send texts and emails in bulk
+	MaxContactSharesPerDay = 5
+
+	maxPlaceLength = 200
+	maxNotesLength = 1000
+	dueBatch       = 200
+)
+
+type Repository interface {
+	CreatePlan(ctx context.Context, plan *DatePlan) error
+	GetPlan(ctx context.Context, planID uuid.UUID) (*DatePlan, error) // ErrPlanNotFound when missing
+	GetPlansByMatch(ctx context.Context, matchID uuid.UUID) ([]DatePlan, error)
+	UpdatePlanStatus(ctx context.Context, planID uuid.UUID, status string) error
+	UpsertCheckIn(ctx context.Context, ci *CheckIn) error
+	GetCheckIn(ctx context.Context, planID, userID uuid.UUID) (*CheckIn, error) // ErrCheckInNotFound when missing
+	GetCheckInsByPlan(ctx context.Context, planID uuid.UUID) ([]CheckIn, error)
+	MarkCheckedIn(ctx context.Context, checkInID uuid.UUID) (bool, error)
+	CancelCheckIn(ctx context.Context, planID, userID uuid.UUID) (bool, error)
+	GetCheckInsToRemind(ctx context.Context, now time.Time, limit int) ([]CheckIn, error)
+	GetCheckInsToEscalate(ctx context.Context, dueBefore time.Time, limit int) ([]CheckIn, error)
+	MarkReminded(ctx context.Context, checkInID uuid.UUID) (bool, error)
+	MarkEscalated(ctx context.Context, checkInID uuid.UUID) (bool, error)
+	AddAudit(ctx context.Context, entry *AuditEntry) error
+	GetAudit(ctx context.Context, planID uuid.UUID) ([]AuditEntry, error)
+	ListAuditByAction(ctx context.Context, action string, limit int) ([]AuditEntry, error)
+	CountAuditByActor(ctx context.Context, actorID uuid.UUID, actions []string, since time.Time) (int, error)
+}
+
+type MatchRepository interface {
+	IsUserInMatch(ctx context.Context, matchID, userID uuid.UUID) (bool, error)
+	GetOtherUserID(ctx context.Context, matchID, userID uuid.UUID) (uuid.UUID, error)
+}
+
+// ProfileRepository interface for names shown to the other person and to contacts
+type ProfileRepository interface {
+	GetNameByUserID(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// Hub interface for real-time updates
+type Hub interface {
+	SendToUser(userID uuid.UUID, msg interface{})
+}
+
+// Notifier sends push notifications about plans and check-ins
+type Notifier interface {
+	SendDatePlanNotification(ctx context.Context, userID uuid.UUID, fromName, body string, matchID uuid.UUID) error
+	SendCheckInNotification(ctx context.Context, userID, planID uuid.UUID, escalated bool) error
+}
+
+// ContactEmailer emails trusted contacts
+type ContactEmailer interface {
+	SendDatePlanShared(ctx context.Context, toEmail, contactName, userName, when, place string) error
+	SendCheckInEscalation(ctx context.Context, toEmail, contactName, userName, when, place string) error
+}
+
+// ContactTexter texts trusted contacts
+type ContactTexter interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+type Service struct {
+	repo        Repository
+	matchRepo   MatchRepository
+	profileRepo ProfileRepository
+	hub         Hub
+	notifier    Notifier
+	emailer     ContactEmailer
+	texter      ContactTexter
+}
+
+func NewService(repo Repository, matchRepo MatchRepository, profileRepo ProfileRepository) *Service {
+	return &Service{
+		repo:        repo,
+		matchRepo:   matchRepo,
+		profileRepo: profileRepo,
+	}
+}
+
+// SetHub sets the WebSocket hub used to push plan updates
+func (s *Service) SetHub(hub Hub) {
+	s.hub = hub
+}
+
+// SetNotifier sets the push notification sender
+func (s *Service) SetNotifier(n Notifier) {
+	s.notifier = n
+}
+
+// SetContactChannels sets how trusted contacts are reached (either may be nil)
+func (s *Service) SetContactChannels(emailer ContactEmailer, texter ContactTexter) {
+	s.emailer = emailer
+	s.texter = texter
+}
+
+// CreatePlan proposes a date to the other person in the match
+func (s *Service) CreatePlan(ctx context.Context, userID, matchID uuid.UUID, req *CreatePlanRequest) (*DatePlan, error) {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inMatch {
+		return nil, ErrNotInMatch
+	}
+
+	req.PlaceName = strings.TrimSpace(req.PlaceName)
+	now := time.Now()
+	if req.PlaceName == "" || utf8.RuneCountInString(req.PlaceName) > maxPlaceLength ||
+		!req.ScheduledAt.After(now) || req.ScheduledAt.After(now.Add(MaxPlanAhead)) {
+		return nil, ErrInvalidPlan
+	}
+	if req.Notes != nil && utf8.RuneCountInString(*req.Notes) > maxNotesLength {
+		return nil, ErrInvalidPlan
+	}
+	if (req.Lat == nil) != (req.Lng == nil) {
+		return nil, ErrInvalidPlan
+	}
+
+	otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &DatePlan{
+		ID:           uuid.New(),
+		MatchID:      &matchID,
+		ProposerID:   userID,
+		InviteeID:    otherUserID,
+		ScheduledAt:  req.ScheduledAt,
+		PlaceName:    req.PlaceName,
+		PlaceAddress: req.PlaceAddress,
+		Lat:          req.Lat,
+		Lng:          req.Lng,
+		Notes:        req.Notes,
+		Status:       StatusProposed,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.repo.CreatePlan(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, plan.ID, &userID, ActionCreated, map[string]interface{}{
+		"scheduled_at": plan.ScheduledAt,
+		"place_name":   plan.PlaceName,
+	})
+	s.publish(ctx, plan, otherUserID, "suggested a date")
+	return plan, nil
+}
+
+// GetPlans lists a match's date plans, each with the user's own check-in
+func (s *Service) GetPlans(ctx context.Context, userID, matchID uuid.UUID) ([]DatePlan, error) {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inMatch {
+		return nil, ErrNotInMatch
+	}
+
+	plans, err := s.repo.GetPlansByMatch(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+	if plans == nil {
+		plans = []DatePlan{}
+	}
+	for i := range plans {
+		ci, err := s.repo.GetCheckIn(ctx, plans[i].ID, userID)
+		if err == nil {
+			plans[i].CheckIn = ci
+		} else if !errors.Is(err, ErrCheckInNotFound) {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+// RespondToPlan accepts or declines a plan; only the invited person can respond
+func (s *Service) RespondToPlan(ctx context.Context, userID, planID uuid.UUID, accept bool) (*DatePlan, error) {
+	plan, err := s.getParticipantPlan(ctx, userID, planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.InviteeID != userID {
+		return nil, ErrNotInvitee
+	}
+	if plan.Status != StatusProposed {
+		return nil, ErrPlanClosed
+	}
+
+	status, action, verb := StatusDeclined, ActionDeclined, "can't make the date"
+	if accept {
+		status, action, verb = StatusAccepted, ActionAccepted, "accepted your date"
+	}
+	if err := s.repo.UpdatePlanStatus(ctx, planID, status); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	plan.Status, plan.RespondedAt, plan.UpdatedAt = status, &now, now
+
+	s.audit(ctx, planID, &userID, action, nil)
+	s.publish(ctx, plan, plan.ProposerID, verb)
+	return plan, nil
+}
+
+// CancelPlan calls off a plan and the caller's pending check-in
+// The other person's check-in keeps running, so cancelling can't be used to stop
+// their trusted contact being alerted; they end it by checking in
+func (s *Service) CancelPlan(ctx context.Context, userID, planID uuid.UUID) error {
+	plan, err := s.getParticipantPlan(ctx, userID, planID)
+	if err != nil {
+		return err
+	}
+	if plan.Status == StatusCancelled || plan.Status == StatusDeclined {
+		return ErrPlanClosed
+	}
+
+	if err := s.repo.UpdatePlanStatus(ctx, planID, StatusCancelled); err != nil {
+		return err
+	}
+	if _, err := s.repo.CancelCheckIn(ctx, planID, userID); err != nil {
+		return err
+	}
+	plan.Status, plan.UpdatedAt = StatusCancelled, time.Now()
+
+	s.audit(ctx, planID, &userID, ActionCancelled, nil)
+	otherUserID := plan.InviteeID
+	if userID == plan.InviteeID {
+		otherUserID = plan.ProposerID
+	}
+	s.publish(ctx, plan, otherUserID, "cancelled your date")
+	return nil
+}
+
+// getParticipantPlan loads a plan the user is part of
+func (s *Service) getParticipantPlan(ctx context.Context, userID, planID uuid.UUID) (*DatePlan, error) {
+	plan, err := s.repo.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.ProposerID != userID && plan.InviteeID != userID {
+		return nil, ErrPlanNotFound
+	}
+	return plan, nil
+}
+
+// publish sends the plan to the other person over WebSocket and push
+func (s *Service) publish(ctx context.Context, plan *DatePlan, toUserID uuid.UUID, verb string) {
+	if s.hub != nil {
+		s.hub.SendToUser(toUserID, WSMessage{Type: EventDatePlanUpdated, Payload: plan})
+	}
+	if s.notifier == nil || plan.MatchID == nil {
+		return
+	}
+
+	actorID := plan.ProposerID
+	if toUserID == plan.ProposerID {
+		actorID = plan.InviteeID
+	}
+	name := s.name(ctx, actorID)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.notifier.SendDatePlanNotification(ctx, toUserID, name, name+" "+verb, *plan.MatchID); err != nil {
+			log.Printf("[DATEPLAN] Failed to push plan %s to %s: %v", plan.ID, toUserID, err)
+		}
+	}()
+}
+
+// audit records an action on a plan; failures are logged rather than failing the request
+func (s *Service) audit(ctx context.Context, planID uuid.UUID, actorID *uuid.UUID, action string, details map[string]interface{}) {
+	entry := &AuditEntry{
+		ID:        uuid.New(),
+		PlanID:    planID,
+		ActorID:   actorID,
+		Action:    action,
+		CreatedAt: time.Now(),
+	}
+	if details != nil {
+		if b, err := json.Marshal(details); err == nil {
+			entry.Details = b
+		}
+	}
+	if err := s.repo.AddAudit(ctx, entry); err != nil {
+		log.Printf("[DATEPLAN] Failed to record %s for plan %s: %v", action, planID, err)
+	}
+}
+
+func (s *Service) name(ctx context.Context, userID uuid.UUID) string {
+	if s.profileRepo != nil {
+		if name, err := s.profileRepo.GetNameByUserID(ctx, userID); err == nil && name != "" {
+			return name
+		}
+	}
+	return "Your match"
+}
+
+// GetPlanAudit returns a plan with every check-in and its audit trail (admin)
+func (s *Service) GetPlanAudit(ctx context.Context, planID uuid.UUID) (*PlanAudit, error) {
+	plan, err := s.repo.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	checkIns, err := s.repo.GetCheckInsByPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.GetAudit(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if checkIns == nil {
+		checkIns = []CheckIn{}
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+	return &PlanAudit{Plan: *plan, CheckIns: checkIns, Audit: entries}, nil
+}
+
+// ListEscalations returns the most recent missed check-in escalations (admin)
+func (s *Service) ListEscalations(ctx context.Context, limit int) ([]AuditEntry, error) {
+	entries, err := s.repo.ListAuditByAction(ctx, ActionEscalated, limit)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+	return entries, nil
+}
@@ -12,46 +12,151 @@ const (
 	PremiumDailyPicks = 10
 )
 
+// Daily pick actions (what the user did with a pick since it was chosen)
+const (
+	PickActionLiked   = "liked"
+	PickActionPassed  = "passed"
+	PickActionMatched = "matched"
+)
+
 // DailyPick represents a daily pick entry
 type DailyPick struct {
 	ID         uuid.UUID `json:"id"`
 	UserID     uuid.UUID `json:"user_id"`
 	PickUserID uuid.UUID `json:"pick_user_id"`
 	PickDate   time.Time `json:"pick_date"`
+	Position   int       `json:"position"`
+	Priority   string    `json:"priority,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// DailyPickProfile is a stored pick with the profile and the viewer's action on it
+type DailyPickProfile struct {
+	FeedProfile
+	Acted  bool   `json:"acted"`
+	Action string `json:"action,omitempty"` // liked, passed, matched
+}
+
 // DailyPicksResponse contains daily picks for a user
 type DailyPicksResponse struct {
-	Picks       []FeedProfile `json:"picks"`
-	PicksToday  int           `json:"picks_today"`
-	MaxPicks    int           `json:"max_picks"`
-	RefreshesAt time.Time     `json:"refreshes_at"`
+	Picks       []DailyPickProfile `json:"picks"`
+	PicksToday  int                `json:"picks_today"`
+	MaxPicks    int                `json:"max_picks"`
+	RefreshesAt time.Time          `json:"refreshes_at"`
 }
 
 // DailyPicksRepository interface for daily picks operations
 type DailyPicksRepository interface {
-	GetDailyPicks(ctx context.Context, userID uuid.UUID, date time.Time) ([]uuid.UUID, error)
-	SaveDailyPicks(ctx context.Context, userID uuid.UUID, pickUserIDs []uuid.UUID, date time.Time) error
+	GetDailyPicks(ctx context.Context, userID uuid.UUID, date time.Time) ([]DailyPick, error)
+	// SaveDailyPicks stores the picks for a date unless picks already exist (first writer wins)
+	SaveDailyPicks(ctx context.Context, userID uuid.UUID, picks []DailyPick, date time.Time) error
 	CountPicksToday(ctx context.Context, userID uuid.UUID) (int, error)
+	GetDailyPickProfiles(ctx context.Context, userID uuid.UUID, date time.Time) ([]DailyPickProfile, error)
+}
+
+// SetDailyPicksRepository sets the repository used to persist daily picks
+func (s *Service) SetDailyPicksRepository(repo DailyPicksRepository) {
+	s.dailyPicksRepo = repo
 }
 
 // GetDailyPicks returns curated daily picks for a user
 // Order: pending likes first, then curated daily picks
-func (s *Service) GetDailyPicks(ctx context.Context, userID uuid.UUID, isPremium bool) (*DailyPicksResponse, error) {
+// Picks are chosen once per user per local day (loc, UTC if nil) and stored, so repeat
+// calls return the same set; picks the user has acted on are marked rather than replaced
+func (s *Service) GetDailyPicks(ctx context.Context, userID uuid.UUID, isPremium bool, loc *time.Location) (*DailyPicksResponse, error) {
 	// All users get 10 daily picks
 	maxPicks := FreeDailyPicks
 
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// Get today's date in the user's time zone
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	tomorrow := today.AddDate(0, 0, 1)
+	pickDate := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	if s.dailyPicksRepo == nil {
+		picks, err := s.selectDailyPicks(ctx, userID, maxPicks)
+		if err != nil {
+			return nil, err
+		}
+		resp := &DailyPicksResponse{
+			Picks:       make([]DailyPickProfile, len(picks)),
+			PicksToday:  len(picks),
+			MaxPicks:    maxPicks,
+			RefreshesAt: tomorrow,
+		}
+		for i, p := range picks {
+			resp.Picks[i] = DailyPickProfile{FeedProfile: p}
+		}
+		s.applyPrivacy(ctx, pickProfiles(resp.Picks))
+		return resp, nil
+	}
+
+	existing, err := s.dailyPicksRepo.GetDailyPicks(ctx, userID, pickDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// First request of the day: choose and store picks
+	if len(existing) == 0 {
+		picks, err := s.selectDailyPicks(ctx, userID, maxPicks)
+		if err != nil {
+			return nil, err
+		}
+
+		toSave := make([]DailyPick, len(picks))
+		for i, p := range picks {
+			toSave[i] = DailyPick{
+				ID:         uuid.New(),
+				UserID:     userID,
+				PickUserID: p.UserID,
+				PickDate:   pickDate,
+				Position:   i,
+				Priority:   p.Priority,
+			}
+		}
+		if len(toSave) > 0 {
+			if err := s.dailyPicksRepo.SaveDailyPicks(ctx, userID, toSave, pickDate); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Always read back the stored set so concurrent first requests agree
+	picks, err := s.dailyPicksRepo.GetDailyPickProfiles(ctx, userID, pickDate)
+	if err != nil {
+		return nil, err
+	}
+	s.applyPrivacy(ctx, pickProfiles(picks))
+
+	return &DailyPicksResponse{
+		Picks:       picks,
+		PicksToday:  len(picks),
+		MaxPicks:    maxPicks,
+		RefreshesAt: tomorrow,
+	}, nil
+}
+
+// pickProfiles returns pointers to the feed profiles inside picks
+func pickProfiles(picks []DailyPickProfile) []*FeedProfile {
+	profiles := make([]*FeedProfile, len(picks))
+	for i := range picks {
+		profiles[i] = &picks[i].FeedProfile
+	}
+	return profiles
+}
+
+// selectDailyPicks chooses up to maxPicks profiles: pending likes first, then browse profiles
+func (s *Service) selectDailyPicks(ctx context.Context, userID uuid.UUID, maxPicks int) ([]FeedProfile, error) {
 	// Get user's preferences
 	prefs, err := s.profileRepo.GetPreferences(ctx, userID)
 	if err != nil {
 		return nil, ErrProfileRequired
 	}
 
-	// Get today's date
-	today := time.Now().UTC().Truncate(24 * time.Hour)
-	tomorrow := today.Add(24 * time.Hour)
-
 	// Get feed profiles using compatibility algorithm
 	// We request more profiles than needed to ensure we have good picks
 	profiles, err := s.feedRepo.GetFeedProfiles(ctx, userID, prefs, maxPicks*3)
@@ -87,10 +192,5 @@ func (s *Service) GetDailyPicks(ctx context.Context, userID uuid.UUID, isPremium
 		picks = append(picks, dailyPicks...)
 	}
 
-	return &DailyPicksResponse{
-		Picks:       picks,
-		PicksToday:  len(picks),
-		MaxPicks:    maxPicks,
-		RefreshesAt: tomorrow,
-	}, nil
+	return picks, nil
 }
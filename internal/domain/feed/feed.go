@@ -4,19 +4,39 @@ import (
 	"time"
 
 	"github.com/feels/feels/internal/domain/profile"
+	"github.com/feels/feels/internal/domain/settings"
 	"github.com/google/uuid"
 )
 
 // FeedProfile is a profile as shown in the feed
 type FeedProfile struct {
 	profile.Profile
-	Age                 int      `json:"age"`
+	Age                 int      `json:"age,omitempty"`                   // 0 when the owner hides their age
 	Distance            *int     `json:"distance,omitempty"`              // miles, nil if location not available
-	Priority            string   `json:"priority,omitempty"`              // for debugging: qualified_superlike, qualified_like, gap_superlike, browse
+	Priority            string   `json:"priority,omitempty"`              // for debugging: qualified_superlike, qualified_like, decayed_like, gap_superlike, boosted, browse
 	LookingForAlignment *string  `json:"looking_for_alignment,omitempty"` // alignment with viewer's intentions
 	GenderTags          []string `json:"gender_tags,omitempty"`           // gender-specific tags (e.g., "curious", "experienced")
 }
 
+// ApplyPrivacy strips the fields the profile's owner has chosen to hide
+func (fp *FeedProfile) ApplyPrivacy(ps *settings.PrivacySettings) {
+	if ps == nil {
+		return
+	}
+	if !ps.ShowDistance {
+		fp.Distance = nil
+		fp.Lat = nil
+		fp.Lng = nil
+	}
+	if ps.HideAge {
+		fp.Age = 0
+		fp.DOB = time.Time{}
+	}
+	if !ps.ShowOnlineStatus {
+		fp.LastActive = time.Time{}
+	}
+}
+
 // LookingFor alignment values
 const (
 	AlignmentPerfect = "perfect"  // exact match
@@ -106,7 +126,9 @@ const (
 const (
 	PriorityQualifiedSuperlike = "qualified_superlike" // in your search range + superliked you
 	PriorityQualifiedLike      = "qualified_like"      // in your search range + liked you
+	PriorityDecayedLike        = "decayed_like"        // qualified like left unprocessed past LikeDecayDays
 	PriorityGapSuperlike       = "gap_superlike"       // outside range but not blocked + superliked
+	PriorityBoosted            = "boosted"             // boosted profile in search range, nearby
 	PriorityBrowse             = "browse"              // regular profiles in search range
 )
 
@@ -116,11 +138,14 @@ const (
 	DefaultFeedLimit       = 10  // default number of profiles per request
 	MaxFeedLimit           = 50  // max profiles per request
 	FreeLikeSlots          = 10  // number of free like slots per user before premium required
+	LikeDecayDays          = 30  // unprocessed likes older than this drop out of the qualified Top 10
+	QueueClearBonusLikes   = 10  // bonus likes granted for clearing a full queue
 )
 
 // WebSocket event types
 const (
 	EventMatchCreated = "match_created"
+	EventQueueCleared = "queue_cleared"
 )
 
 // WSMessage is a WebSocket message envelope
@@ -134,3 +159,8 @@ type MatchCreatedPayload struct {
 	MatchID     uuid.UUID `json:"match_id"`
 	OtherUserID uuid.UUID `json:"other_user_id"`
 }
+
+// QueueClearedPayload is sent when a user clears a full queue and earns bonus likes
+type QueueClearedPayload struct {
+	BonusLikes int `json:"bonus_likes"`
+}
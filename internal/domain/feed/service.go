@@ -8,6 +8,7 @@ import (
 
 	"github.com/feels/feels/internal/domain/match"
 	"github.com/feels/feels/internal/domain/profile"
+	"github.com/feels/feels/internal/domain/settings"
 	"github.com/google/uuid"
 )
 
@@ -72,6 +73,11 @@ type FeedRepository interface {
 	// Atomic like+credit operations (credit deduction + like creation in single transaction)
 	CreateLikeWithCreditAtomic(ctx context.Context, like *Like, isSuperlike bool, dailyLikeLimit int, superlikeCost int, matchUser1ID, matchUser2ID uuid.UUID) (*LikeResult, *CreditCheckResult, error)
 	CreateLikeWithMessageAndCreditAtomic(ctx context.Context, like *Like, message string, superlikeCost int, matchUser1ID, matchUser2ID uuid.UUID) (*LikeResult, error)
+	// Queue-clear reward ledger
+	ArmQueueClearReward(ctx context.Context, userID uuid.UUID) error
+	// ClaimQueueClearReward pays out an armed reward, unless likes decayed out of the
+	// queue since it was armed; then it withdraws the reward and returns false
+	ClaimQueueClearReward(ctx context.Context, userID uuid.UUID, prefs *profile.Preferences, bonusLikes int) (bool, error)
 }
 
 // UserRepository interface for checking user status
@@ -83,6 +89,12 @@ type AnalyticsRepository interface {
 	RecordView(ctx context.Context, viewerID, viewedID uuid.UUID) error
 }
 
+// PrivacyService provides users' privacy settings (defaults when unset)
+type PrivacyService interface {
+	GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*settings.PrivacySettings, error)
+	GetPrivacySettingsForUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*settings.PrivacySettings, error)
+}
+
 type ProfileRepository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID) (*profile.Profile, error)
 	GetPreferences(ctx context.Context, userID uuid.UUID) (*profile.Preferences, error)
@@ -121,6 +133,8 @@ type Service struct {
 	matchRepo           MatchRepository
 	userRepo            UserRepository
 	analyticsRepo       AnalyticsRepository
+	dailyPicksRepo      DailyPicksRepository
+	privacyService      PrivacyService
 	creditService       CreditService
 	notificationService NotificationService
 	hub                 Hub
@@ -161,6 +175,51 @@ func (s *Service) SetUserRepository(ur UserRepository) {
 	s.userRepo = ur
 }
 
+// SetPrivacyService sets the privacy settings provider used to hide profile fields
+func (s *Service) SetPrivacyService(ps PrivacyService) {
+	s.privacyService = ps
+}
+
+// applyPrivacy hides distance, age and last active on profiles whose owners opted out
+func (s *Service) applyPrivacy(ctx context.Context, profiles []*FeedProfile) {
+	if s.privacyService == nil || len(profiles) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(profiles))
+	for i, p := range profiles {
+		ids[i] = p.UserID
+	}
+
+	privacy, err := s.privacyService.GetPrivacySettingsForUsers(ctx, ids)
+	if err != nil {
+		// Fail closed: hide everything optional rather than leak a hidden field
+		log.Printf("[FEED] Failed to load privacy settings: %v", err)
+		hidden := &settings.PrivacySettings{HideAge: true}
+		for _, p := range profiles {
+			p.ApplyPrivacy(hidden)
+		}
+		return
+	}
+
+	for _, p := range profiles {
+		p.ApplyPrivacy(privacy[p.UserID])
+	}
+}
+
+// isIncognito reports whether the user browses without leaving profile views
+func (s *Service) isIncognito(ctx context.Context, userID uuid.UUID) bool {
+	if s.privacyService == nil {
+		return false
+	}
+	ps, err := s.privacyService.GetPrivacySettings(ctx, userID)
+	if err != nil {
+		// Fail closed: don't record a view we can't confirm is allowed
+		return true
+	}
+	return ps.IncognitoMode
+}
+
 // GetFeed returns the next batch of profiles for the user
 func (s *Service) GetFeed(ctx context.Context, userID uuid.UUID, limit int) (*FeedResponse, error) {
 	// Validate limit
@@ -191,6 +250,14 @@ func (s *Service) GetFeed(ctx context.Context, userID uuid.UUID, limit int) (*Fe
 		return nil, err
 	}
 
+	// A full queue arms the queue-clear bonus, which is paid out once the user works through it
+	mustProcessAll := queuedLikes >= MaxQualifiedLikesShown
+	if mustProcessAll {
+		if err := s.feedRepo.ArmQueueClearReward(ctx, userID); err != nil {
+			log.Printf("[FEED] Failed to arm queue-clear reward for user %s: %v", userID, err)
+		}
+	}
+
 	// Get feed profiles
 	profiles, err := s.feedRepo.GetFeedProfiles(ctx, userID, prefs, limit)
 	if err != nil {
@@ -206,8 +273,14 @@ func (s *Service) GetFeed(ctx context.Context, userID uuid.UUID, limit int) (*Fe
 		profiles[i].LookingForAlignment = ComputeLookingForAlignment(viewerLookingFor, profileLookingFor)
 	}
 
-	// Record profile views asynchronously
-	if s.analyticsRepo != nil {
+	visible := make([]*FeedProfile, len(profiles))
+	for i := range profiles {
+		visible[i] = &profiles[i]
+	}
+	s.applyPrivacy(ctx, visible)
+
+	// Record profile views asynchronously (incognito viewers leave no trace)
+	if s.analyticsRepo != nil && !s.isIncognito(ctx, userID) {
 		go func() {
 			for _, p := range profiles {
 				s.analyticsRepo.RecordView(ctx, userID, p.UserID)
@@ -219,10 +292,45 @@ func (s *Service) GetFeed(ctx context.Context, userID uuid.UUID, limit int) (*Fe
 		Profiles:       profiles,
 		HasMore:        len(profiles) == limit,
 		QueuedLikes:    queuedLikes,
-		MustProcessAll: queuedLikes >= MaxQualifiedLikesShown,
+		MustProcessAll: mustProcessAll,
 	}, nil
 }
 
+// checkQueueCleared grants the queue-clear bonus if the user just emptied a full queue
+// Safe to call after every like/pass: the ledger row ensures the bonus is granted once per clear,
+// and only when the user's own likes and passes emptied it rather than decay
+func (s *Service) checkQueueCleared(ctx context.Context, userID uuid.UUID) {
+	prefs, err := s.profileRepo.GetPreferences(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	queuedLikes, err := s.feedRepo.CountQueuedLikes(ctx, userID, prefs)
+	if err != nil || queuedLikes > 0 {
+		return
+	}
+
+	granted, err := s.feedRepo.ClaimQueueClearReward(ctx, userID, prefs, QueueClearBonusLikes)
+	if err != nil {
+		log.Printf("[FEED] Failed to claim queue-clear reward for user %s: %v", userID, err)
+		return
+	}
+	if !granted {
+		return
+	}
+
+	log.Printf("[FEED] User %s cleared their queue, granted %d bonus likes", userID, QueueClearBonusLikes)
+
+	if s.hub != nil {
+		s.hub.SendToUser(userID, WSMessage{
+			Type: EventQueueCleared,
+			Payload: QueueClearedPayload{
+				BonusLikes: QueueClearBonusLikes,
+			},
+		})
+	}
+}
+
 // Like likes a profile using atomic transaction to prevent race conditions
 // Credit deduction and like creation are wrapped in a single transaction to prevent credit loss
 // For non-premium likes: checks if target's queue is full or if liker is outside age range
@@ -293,6 +401,9 @@ func (s *Service) Like(ctx context.Context, userID, targetID uuid.UUID, isSuperl
 		return nil, err
 	}
 
+	// Liking someone from the queue processes it
+	s.checkQueueCleared(ctx, userID)
+
 	// Send push notification for like received (only if like was created)
 	if result.LikeCreated && s.notificationService != nil {
 		if isSuperlike {
@@ -378,7 +489,12 @@ func (s *Service) Pass(ctx context.Context, userID, targetID uuid.UUID) error {
 		CreatedAt: time.Now(),
 	}
 
-	return s.feedRepo.CreatePass(ctx, pass)
+	if err := s.feedRepo.CreatePass(ctx, pass); err != nil {
+		return err
+	}
+
+	s.checkQueueCleared(ctx, userID)
+	return nil
 }
 
 // EnsurePassesTable ensures the passes table exists
@@ -419,9 +535,12 @@ func (s *Service) Rewind(ctx context.Context, userID uuid.UUID) (*FeedProfile, e
 		return nil, err
 	}
 
-	return &FeedProfile{
+	fp := &FeedProfile{
 		Profile: *profile,
-	}, nil
+	}
+	s.applyPrivacy(ctx, []*FeedProfile{fp})
+
+	return fp, nil
 }
 
 // LikeWithMessage creates a superlike with an attached message (premium feature)
@@ -466,6 +585,8 @@ func (s *Service) LikeWithMessage(ctx context.Context, userID, targetID uuid.UUI
 		return nil, err
 	}
 
+	s.checkQueueCleared(ctx, userID)
+
 	// Send push notification (only if like was created)
 	if result.LikeCreated && s.notificationService != nil {
 		likerProfile, _ := s.profileRepo.GetByUserID(ctx, userID)
@@ -0,0 +1,102 @@
+package keys
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxOneTimePreKeys caps how many unused one-time prekeys a device can hold
+	MaxOneTimePreKeys = 100
+
+	// LowPreKeyThreshold is when clients should upload more one-time prekeys
+	LowPreKeyThreshold = 10
+
+	// maxKeyLength bounds base64-encoded keys and signatures
+	maxKeyLength = 1024
+)
+
+// WebSocket event type
+const EventDeviceKeysChanged = "device_keys_changed"
+
+// WSMessage is a WebSocket message envelope
+type WSMessage struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// DeviceKeysChangedPayload tells matches (and the user's other devices) to refetch key bundles
+type DeviceKeysChangedPayload struct {
+	UserID   uuid.UUID `json:"user_id"`
+	DeviceID string    `json:"device_id"`
+	Revoked  bool      `json:"revoked"`
+}
+
+// SignedPreKey is a medium-term key signed by the device's identity key
+// The server stores the signature; clients verify it
+type SignedPreKey struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// OneTimePreKey is handed out to at most one sender
+type OneTimePreKey struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+// DeviceKeys is a device's entry in the key directory
+type DeviceKeys struct {
+	DeviceSessionID uuid.UUID    `json:"-"`
+	UserID          uuid.UUID    `json:"user_id"`
+	DeviceID        string       `json:"device_id"`
+	IdentityKey     string       `json:"identity_key"`
+	SignedPreKey    SignedPreKey `json:"signed_prekey"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+}
+
+// PreKeyBundle is what a sender needs to start a session with one device
+// OneTimePreKey is omitted when the device has run out or the requesting device
+// already claimed one from it
+type PreKeyBundle struct {
+	UserID        uuid.UUID      `json:"user_id"`
+	DeviceID      string         `json:"device_id"`
+	IdentityKey   string         `json:"identity_key"`
+	SignedPreKey  SignedPreKey   `json:"signed_prekey"`
+	OneTimePreKey *OneTimePreKey `json:"one_time_prekey,omitempty"`
+}
+
+// RegisterDeviceRequest publishes (or replaces) the calling device's keys
+type RegisterDeviceRequest struct {
+	DeviceID       string          `json:"device_id"`
+	IdentityKey    string          `json:"identity_key"`
+	SignedPreKey   SignedPreKey    `json:"signed_prekey"`
+	OneTimePreKeys []OneTimePreKey `json:"one_time_prekeys"`
+}
+
+// UploadPreKeysRequest tops up a device's one-time prekeys
+type UploadPreKeysRequest struct {
+	DeviceID       string          `json:"device_id"`
+	OneTimePreKeys []OneTimePreKey `json:"one_time_prekeys"`
+}
+
+// PreKeyCountResponse tells a device how many one-time prekeys it has left
+type PreKeyCountResponse struct {
+	DeviceID  string `json:"device_id"`
+	Remaining int    `json:"remaining"`
+	Low       bool   `json:"low"`
+}
+
+// ClaimBundlesRequest identifies the device that will start the sessions
+type ClaimBundlesRequest struct {
+	DeviceID string `json:"device_id"`
+}
+
+// MatchBundlesResponse has a bundle for every device that should receive a message in a match
+type MatchBundlesResponse struct {
+	MatchID uuid.UUID      `json:"match_id"`
+	Bundles []PreKeyBundle `json:"bundles"`
+}
@@ -0,0 +1,257 @@
+package keys
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("no session for this device, log in on it first")
+	ErrNoDeviceKeys   = errors.New("device has not registered keys")
+	ErrInvalidKey     = errors.New("keys must be non-empty base64")
+	ErrDuplicateKeyID = errors.New("one-time prekey ids must be unique")
+	ErrTooManyPreKeys = errors.New("too many one-time prekeys")
+	ErrNotInMatch     = errors.New("not in match")
+	ErrRateLimited    = errors.New("too many key bundle requests, try again later")
+)
+
+type Repository interface {
+	GetDeviceSessionID(ctx context.Context, userID uuid.UUID, deviceID string) (uuid.UUID, error)
+	UpsertDeviceKeys(ctx context.Context, keys *DeviceKeys, preKeys []OneTimePreKey) error
+	AddOneTimePreKeys(ctx context.Context, deviceSessionID uuid.UUID, preKeys []OneTimePreKey) (int, error)
+	CountOneTimePreKeys(ctx context.Context, deviceSessionID uuid.UUID) (int, error)
+	// ClaimPreKeyBundles returns bundles for the users' devices other than the requester,
+	// with a one-time prekey only from devices the requester hasn't claimed from before
+	ClaimPreKeyBundles(ctx context.Context, userIDs []uuid.UUID, requesterSessionID uuid.UUID) ([]PreKeyBundle, error)
+	DeleteDeviceKeys(ctx context.Context, userID uuid.UUID, deviceID string) (bool, error)
+	GetKeyedDeviceIDs(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]string, error)
+}
+
+type MatchRepository interface {
+	IsUserInMatch(ctx context.Context, matchID, userID uuid.UUID) (bool, error)
+	GetOtherUserID(ctx context.Context, matchID, userID uuid.UUID) (uuid.UUID, error)
+	GetMatchedUserIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// Hub interface for real-time notifications
+type Hub interface {
+	SendToUser(userID uuid.UUID, msg interface{})
+}
+
+// RateLimiter counts requests per key, reporting whether one is within the limit
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
+// Service is the per-device E2E key directory
+// Keys belong to a device session, so they disappear when the session is logged out
+type Service struct {
+	repo         Repository
+	matchRepo    MatchRepository
+	hub          Hub
+	claimLimiter RateLimiter
+}
+
+func NewService(repo Repository, matchRepo MatchRepository) *Service {
+	return &Service{
+		repo:      repo,
+		matchRepo: matchRepo,
+	}
+}
+
+// SetHub sets the WebSocket hub used to announce key changes
+func (s *Service) SetHub(hub Hub) {
+	s.hub = hub
+}
+
+// SetClaimRateLimiter sets the per-device limit on claiming key bundles (optional)
+func (s *Service) SetClaimRateLimiter(l RateLimiter) {
+	s.claimLimiter = l
+}
+
+// RegisterDevice publishes the device's identity key, signed prekey and one-time prekeys
+// Re-registering replaces the previous keys and discards unused one-time prekeys
+func (s *Service) RegisterDevice(ctx context.Context, userID uuid.UUID, req *RegisterDeviceRequest) error {
+	if !validKey(req.IdentityKey) || !validKey(req.SignedPreKey.PublicKey) || !validKey(req.SignedPreKey.Signature) {
+		return ErrInvalidKey
+	}
+	if err := validatePreKeys(req.OneTimePreKeys); err != nil {
+		return err
+	}
+
+	sessionID, err := s.repo.GetDeviceSessionID(ctx, userID, req.DeviceID)
+	if err != nil {
+		return ErrDeviceNotFound
+	}
+
+	keys := &DeviceKeys{
+		DeviceSessionID: sessionID,
+		UserID:          userID,
+		DeviceID:        req.DeviceID,
+		IdentityKey:     req.IdentityKey,
+		SignedPreKey:    req.SignedPreKey,
+	}
+	if err := s.repo.UpsertDeviceKeys(ctx, keys, req.OneTimePreKeys); err != nil {
+		return err
+	}
+
+	s.announce(ctx, userID, req.DeviceID, false)
+	return nil
+}
+
+// UploadPreKeys adds one-time prekeys to a registered device
+func (s *Service) UploadPreKeys(ctx context.Context, userID uuid.UUID, req *UploadPreKeysRequest) (*PreKeyCountResponse, error) {
+	if len(req.OneTimePreKeys) == 0 {
+		return nil, ErrInvalidKey
+	}
+	if err := validatePreKeys(req.OneTimePreKeys); err != nil {
+		return nil, err
+	}
+
+	sessionID, err := s.repo.GetDeviceSessionID(ctx, userID, req.DeviceID)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	current, err := s.repo.CountOneTimePreKeys(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if current+len(req.OneTimePreKeys) > MaxOneTimePreKeys {
+		return nil, ErrTooManyPreKeys
+	}
+
+	remaining, err := s.repo.AddOneTimePreKeys(ctx, sessionID, req.OneTimePreKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &PreKeyCountResponse{DeviceID: req.DeviceID, Remaining: remaining, Low: remaining < LowPreKeyThreshold}, nil
+}
+
+// GetPreKeyCount reports how many one-time prekeys a device has left
+func (s *Service) GetPreKeyCount(ctx context.Context, userID uuid.UUID, deviceID string) (*PreKeyCountResponse, error) {
+	sessionID, err := s.repo.GetDeviceSessionID(ctx, userID, deviceID)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	remaining, err := s.repo.CountOneTimePreKeys(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &PreKeyCountResponse{DeviceID: deviceID, Remaining: remaining, Low: remaining < LowPreKeyThreshold}, nil
+}
+
+// ClaimMatchBundles returns prekey bundles for every device that should be able to read
+// the user's messages in a match: all of the other user's devices and the user's own
+// other devices. The calling device gets one one-time prekey from each device; later
+// claims return bundles without one until either device re-registers its keys.
+func (s *Service) ClaimMatchBundles(ctx context.Context, userID, matchID uuid.UUID, deviceID string) (*MatchBundlesResponse, error) {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inMatch {
+		return nil, ErrNotInMatch
+	}
+
+	sessionID, err := s.repo.GetDeviceSessionID(ctx, userID, deviceID)
+	if err != nil {
+		return nil, ErrDeviceNotFound
+	}
+
+	if s.claimLimiter != nil {
+		allowed, err := s.claimLimiter.Allow(ctx, userID.String()+":"+deviceID)
+		if err != nil {
+			log.Printf("[KEYS] Claim rate limit check failed for %s: %v", userID, err)
+		} else if !allowed {
+			return nil, ErrRateLimited
+		}
+	}
+
+	otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundles, err := s.repo.ClaimPreKeyBundles(ctx, []uuid.UUID{otherUserID, userID}, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if bundles == nil {
+		bundles = []PreKeyBundle{}
+	}
+	return &MatchBundlesResponse{MatchID: matchID, Bundles: bundles}, nil
+}
+
+// RevokeDevice removes a device from the key directory (on logout or explicit removal)
+func (s *Service) RevokeDevice(ctx context.Context, userID uuid.UUID, deviceID string) error {
+	removed, err := s.repo.DeleteDeviceKeys(ctx, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	if removed {
+		s.announce(ctx, userID, deviceID, true)
+	}
+	return nil
+}
+
+// KeyedDevices returns the device IDs with registered keys for each user
+func (s *Service) KeyedDevices(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	return s.repo.GetKeyedDeviceIDs(ctx, userIDs)
+}
+
+// announce tells the user's other devices and their matches to refetch bundles
+func (s *Service) announce(ctx context.Context, userID uuid.UUID, deviceID string, revoked bool) {
+	if s.hub == nil {
+		return
+	}
+
+	event := WSMessage{
+		Type: EventDeviceKeysChanged,
+		Payload: DeviceKeysChangedPayload{
+			UserID:   userID,
+			DeviceID: deviceID,
+			Revoked:  revoked,
+		},
+	}
+	s.hub.SendToUser(userID, event)
+
+	matchedIDs, err := s.matchRepo.GetMatchedUserIDs(ctx, userID)
+	if err != nil {
+		log.Printf("[KEYS] Failed to list matches for %s: %v", userID, err)
+		return
+	}
+	for _, id := range matchedIDs {
+		s.hub.SendToUser(id, event)
+	}
+}
+
+func validKey(k string) bool {
+	if k == "" || len(k) > maxKeyLength {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(k)
+	return err == nil
+}
+
+func validatePreKeys(preKeys []OneTimePreKey) error {
+	if len(preKeys) > MaxOneTimePreKeys {
+		return ErrTooManyPreKeys
+	}
+	seen := make(map[int]bool, len(preKeys))
+	for _, pk := range preKeys {
+		if !validKey(pk.PublicKey) {
+			return ErrInvalidKey
+		}
+		if seen[pk.KeyID] {
+			return ErrDuplicateKeyID
+		}
+		seen[pk.KeyID] = true
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Recipient is where a user's emails go
+type Recipient struct {
+	UserID uuid.UUID
+	Email  string // empty for accounts without a real address, e.g. phone-only sign-ups
+	Name   string
+}
+
+// Receipt describes a subscription payment
+type Receipt struct {
+	ID          string // store transaction or webhook event ID; one receipt is sent per ID
+	Plan        string // monthly, quarterly or annual
+	Amount      string // e.g. "9.99 USD"; empty if the store didn't report one
+	PurchasedAt time.Time
+	RenewsAt    time.Time
+}
+
+// Template data; each field is available to the template as .Data.<Field>
+
+type LikesDigestData struct {
+	Name  string
+	Likes int
+}
+
+type ReceiptData struct {
+	Name        string
+	Plan        string
+	Amount      string
+	PurchasedAt time.Time
+	RenewsAt    time.Time
+}
+
+type RenewalReminderData struct {
+	Name     string
+	Plan     string
+	RenewsAt time.Time
+}
+
+type AccountDeletedData struct {
+	Name string
+}
+
+// FormatAmount formats a store price for a receipt, e.g. 9.99 and "usd" as "9.99 USD"
+func FormatAmount(price float64, currency string) string {
+	if price <= 0 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", price, strings.ToUpper(currency)))
+}
@@ -0,0 +1,247 @@
+package mailer
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/feels/feels/internal/domain/settings"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+	ErrRecipientNotFound       = errors.New("recipient not found")
+)
+
+type Repository interface {
+	GetRecipient(ctx context.Context, userID uuid.UUID) (*Recipient, error) // ErrRecipientNotFound when the account is gone
+	// ClaimEmail records an email as sent, returning false if one with the same key already was
+	ClaimEmail(ctx context.Context, userID uuid.UUID, emailType, key string) (bool, error)
+	// ReleaseEmail forgets a claim whose email failed, so a later run can retry it
+	ReleaseEmail(ctx context.Context, userID uuid.UUID, emailType, key string) error
+}
+
+// SettingsService reads and updates the email section of notification settings
+type SettingsService interface {
+	GetNotificationSettings(ctx context.Context, userID uuid.UUID) (*settings.NotificationSettings, error)
+	Unsubscribe(ctx context.Context, userID uuid.UUID, emailType string) error
+}
+
+// Sender renders a template in the recipient's locale and sends it
+type Sender interface {
+	SendTemplate(ctx context.Context, toEmail, name, locale string, data interface{}, unsubscribeURL string) error
+}
+
+type Config struct {
+	LinkBaseURL string // public API URL that unsubscribe links point at
+	Secret      string // signs unsubscribe links
+}
+
+// Service sends templated emails, honoring each user's email settings
+type Service struct {
+	repo        Repository
+	settings    SettingsService
+	sender      Sender
+	linkBaseURL string
+	secret      []byte
+}
+
+func NewService(repo Repository, settingsService SettingsService, sender Sender, cfg Config) *Service {
+	return &Service{
+		repo:        repo,
+		settings:    settingsService,
+		sender:      sender,
+		linkBaseURL: strings.TrimSuffix(cfg.LinkBaseURL, "/"),
+		secret:      []byte(cfg.Secret),
+	}
+}
+
+// message is an email ready to send; it holds everything needed so it can be sent
+// after the account it was addressed from is gone
+type message struct {
+	emailType      string
+	to             string
+	locale         string
+	data           interface{}
+	unsubscribeURL string
+}
+
+// SendLikesDigest emails the weekly "who liked you" digest, at most once per ISO week
+func (s *Service) SendLikesDigest(ctx context.Context, userID uuid.UUID, likes int, weekOf time.Time) (bool, error) {
+	year, week := weekOf.ISOWeek()
+	return s.send(ctx, userID, settings.EmailLikesDigest, fmt.Sprintf("%d-W%02d", year, week), func(name string, _ *time.Location) interface{} {
+		return LikesDigestData{Name: name, Likes: likes}
+	})
+}
+
+// SendSubscriptionReceipt emails a receipt for a subscription payment, once per receipt ID
+func (s *Service) SendSubscriptionReceipt(ctx context.Context, userID uuid.UUID, r *Receipt) (bool, error) {
+	return s.send(ctx, userID, settings.EmailSubscriptionReceipt, r.ID, func(name string, loc *time.Location) interface{} {
+		return ReceiptData{Name: name, Plan: r.Plan, Amount: r.Amount, PurchasedAt: r.PurchasedAt.In(loc), RenewsAt: r.RenewsAt.In(loc)}
+	})
+}
+
+// SendRenewalReminder warns that a subscription is about to renew, once per renewal date
+func (s *Service) SendRenewalReminder(ctx context.Context, userID uuid.UUID, plan string, renewsAt time.Time) (bool, error) {
+	return s.send(ctx, userID, settings.EmailRenewalReminder, renewsAt.UTC().Format("2006-01-02"), func(name string, loc *time.Location) interface{} {
+		return RenewalReminderData{Name: name, Plan: plan, RenewsAt: renewsAt.In(loc)}
+	})
+}
+
+// PrepareAccountDeleted looks up the confirmation email while the account still exists
+// The returned func sends it once the account is deleted, and does nothing if the user opted out
+func (s *Service) PrepareAccountDeleted(ctx context.Context, userID uuid.UUID) (func(ctx context.Context) error, error) {
+	msg, err := s.prepare(ctx, userID, settings.EmailAccountDeleted, func(name string, _ *time.Location) interface{} {
+		return AccountDeletedData{Name: name}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		if msg == nil {
+			return nil
+		}
+		return s.deliver(ctx, msg)
+	}, nil
+}
+
+// Unsubscribe turns off the email type an unsubscribe link was issued for
+// Links for deleted accounts succeed, as there is nothing left to send to
+func (s *Service) Unsubscribe(ctx context.Context, token string) (string, error) {
+	userID, emailType, err := s.parseUnsubscribeToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.repo.GetRecipient(ctx, userID); err != nil {
+		if errors.Is(err, ErrRecipientNotFound) {
+			return emailType, nil
+		}
+		return "", err
+	}
+
+	if err := s.settings.Unsubscribe(ctx, userID, emailType); err != nil {
+		return "", err
+	}
+	log.Printf("[MAILER] User %s unsubscribed from %s emails", userID, emailType)
+	return emailType, nil
+}
+
+// UnsubscribeURL returns the signed link that turns off one email type for a user
+func (s *Service) UnsubscribeURL(userID uuid.UUID, emailType string) string {
+	return s.linkBaseURL + "/email/unsubscribe?token=" + url.QueryEscape(s.unsubscribeToken(userID, emailType))
+}
+
+// send emails a user unless they opted out of the type or an email with the same key was
+// already sent, reporting whether it went out
+func (s *Service) send(ctx context.Context, userID uuid.UUID, emailType, key string, data dataFunc) (bool, error) {
+	msg, err := s.prepare(ctx, userID, emailType, data)
+	if err != nil || msg == nil {
+		return false, err
+	}
+
+	claimed, err := s.repo.ClaimEmail(ctx, userID, emailType, key)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := s.deliver(ctx, msg); err != nil {
+		if rerr := s.repo.ReleaseEmail(ctx, userID, emailType, key); rerr != nil {
+			log.Printf("[MAILER] Failed to release %s email for %s: %v", emailType, userID, rerr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// dataFunc builds a template's data for the recipient's name and time zone
+type dataFunc func(name string, loc *time.Location) interface{}
+
+// prepare builds the email for a user, or returns nil if there is no address to send
+// to or the user unsubscribed from the type
+func (s *Service) prepare(ctx context.Context, userID uuid.UUID, emailType string, data dataFunc) (*message, error) {
+	r, err := s.repo.GetRecipient(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrRecipientNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if r.Email == "" {
+		return nil, nil
+	}
+
+	prefs, err := s.settings.GetNotificationSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	email := prefs.Email
+	if email == nil {
+		email = settings.DefaultEmailSettings()
+	}
+	if !email.Allows(emailType) {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	return &message{
+		emailType:      emailType,
+		to:             r.Email,
+		locale:         email.Locale,
+		data:           data(r.Name, loc),
+		unsubscribeURL: s.UnsubscribeURL(userID, emailType),
+	}, nil
+}
+
+func (s *Service) deliver(ctx context.Context, msg *message) error {
+	if err := s.sender.SendTemplate(ctx, msg.to, msg.emailType, msg.locale, msg.data, msg.unsubscribeURL); err != nil {
+		return fmt.Errorf("failed to send %s email: %w", msg.emailType, err)
+	}
+	return nil
+}
+
+// Unsubscribe tokens are "<user id>.<email type>.<signature>"; they don't expire, so old
+// emails keep working
+
+func (s *Service) unsubscribeToken(userID uuid.UUID, emailType string) string {
+	payload := userID.String() + "." + emailType
+	return payload + "." + s.sign(payload)
+}
+
+func (s *Service) parseUnsubscribeToken(token string) (uuid.UUID, string, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return uuid.Nil, "", ErrInvalidUnsubscribeToken
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.sign(payload))) {
+		return uuid.Nil, "", ErrInvalidUnsubscribeToken
+	}
+
+	idStr, emailType, ok := strings.Cut(payload, ".")
+	if !ok {
+		return uuid.Nil, "", ErrInvalidUnsubscribeToken
+	}
+	userID, err := uuid.Parse(idStr)
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidUnsubscribeToken
+	}
+	return userID, emailType, nil
+}
+
+func (s *Service) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,143 @@
+package mailer
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/feels/feels/internal/domain/settings"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recipients is an in-memory Repository
+type recipients struct {
+	Repository
+	users map[uuid.UUID]*Recipient
+}
+
+func (r *recipients) GetRecipient(ctx context.Context, userID uuid.UUID) (*Recipient, error) {
+	if rec, ok := r.users[userID]; ok {
+		return rec, nil
+	}
+	return nil, ErrRecipientNotFound
+}
+
+// emailSettings is an in-memory SettingsService
+type emailSettings struct {
+	byUser map[uuid.UUID]*settings.EmailSettings
+}
+
+func (s *emailSettings) GetNotificationSettings(ctx context.Context, userID uuid.UUID) (*settings.NotificationSettings, error) {
+	prefs := settings.DefaultNotificationSettings(userID)
+	if e, ok := s.byUser[userID]; ok {
+		prefs.Email = e
+	}
+	return prefs, nil
+}
+
+func (s *emailSettings) Unsubscribe(ctx context.Context, userID uuid.UUID, emailType string) error {
+	e, ok := s.byUser[userID]
+	if !ok {
+		e = settings.DefaultEmailSettings()
+		s.byUser[userID] = e
+	}
+	if !e.Unsubscribe(emailType) {
+		return settings.ErrUnknownEmailType
+	}
+	return nil
+}
+
+func newTestService(secret string) (*Service, *emailSettings, uuid.UUID) {
+	userID := uuid.New()
+	repo := &recipients{users: map[uuid.UUID]*Recipient{userID: {UserID: userID, Email: "sam@example.com", Name: "Sam"}}}
+	prefs := &emailSettings{byUser: make(map[uuid.UUID]*settings.EmailSettings)}
+	return NewService(repo, prefs, nil, Config{LinkBaseURL: "https://api.example.com/", Secret: secret}), prefs, userID
+}
+
+func TestParseUnsubscribeToken(t *testing.T) {
+	svc, _, userID := newTestService("secret")
+	other, _, _ := newTestService("other secret")
+	payload := userID.String() + "." + settings.EmailLikesDigest
+	valid := svc.unsubscribeToken(userID, settings.EmailLikesDigest)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", payload},
+		{"empty signature", payload + "."},
+		{"tampered signature", valid[:len(valid)-2] + "xx"},
+		{"signed with another secret", other.unsubscribeToken(userID, settings.EmailLikesDigest)},
+		{"another user's id", strings.Replace(valid, userID.String(), uuid.New().String(), 1)},
+		{"another email type", strings.Replace(valid, settings.EmailLikesDigest, settings.EmailRenewalReminder, 1)},
+		{"signed payload without a type", userID.String() + "." + svc.sign(userID.String())},
+		{"signed payload with a bad user id", "not-a-uuid." + settings.EmailLikesDigest + "." + svc.sign("not-a-uuid."+settings.EmailLikesDigest)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := svc.parseUnsubscribeToken(tt.token)
+			assert.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		gotUser, gotType, err := svc.parseUnsubscribeToken(valid)
+		require.NoError(t, err)
+		assert.Equal(t, userID, gotUser)
+		assert.Equal(t, settings.EmailLikesDigest, gotType)
+	})
+}
+
+func TestUnsubscribeURL_RoundTrips(t *testing.T) {
+	svc, _, userID := newTestService("secret")
+
+	link, err := url.Parse(svc.UnsubscribeURL(userID, settings.EmailRenewalReminder))
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.example.com/email/unsubscribe", link.Scheme+"://"+link.Host+link.Path)
+
+	gotUser, gotType, err := svc.parseUnsubscribeToken(link.Query().Get("token"))
+	require.NoError(t, err)
+	assert.Equal(t, userID, gotUser)
+	assert.Equal(t, settings.EmailRenewalReminder, gotType)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("turns off only the link's email type", func(t *testing.T) {
+		svc, prefs, userID := newTestService("secret")
+		emailType, err := svc.Unsubscribe(ctx, svc.unsubscribeToken(userID, settings.EmailLikesDigest))
+		require.NoError(t, err)
+		assert.Equal(t, settings.EmailLikesDigest, emailType)
+		assert.False(t, prefs.byUser[userID].Allows(settings.EmailLikesDigest))
+		assert.True(t, prefs.byUser[userID].Allows(settings.EmailRenewalReminder))
+	})
+
+	t.Run("signed token for an unknown email type", func(t *testing.T) {
+		svc, _, userID := newTestService("secret")
+		_, err := svc.Unsubscribe(ctx, svc.unsubscribeToken(userID, "promotions"))
+		assert.ErrorIs(t, err, settings.ErrUnknownEmailType)
+	})
+
+	t.Run("tampered token changes nothing", func(t *testing.T) {
+		svc, prefs, userID := newTestService("secret")
+		token := strings.Replace(svc.unsubscribeToken(userID, settings.EmailLikesDigest), settings.EmailLikesDigest, settings.EmailSubscriptionReceipt, 1)
+		_, err := svc.Unsubscribe(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+		assert.Empty(t, prefs.byUser)
+	})
+
+	t.Run("deleted account succeeds", func(t *testing.T) {
+		svc, prefs, _ := newTestService("secret")
+		gone := uuid.New()
+		emailType, err := svc.Unsubscribe(ctx, svc.unsubscribeToken(gone, settings.EmailLikesDigest))
+		require.NoError(t, err)
+		assert.Equal(t, settings.EmailLikesDigest, emailType)
+		assert.Empty(t, prefs.byUser)
+	})
+}
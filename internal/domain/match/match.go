@@ -23,6 +23,8 @@ type MatchWithProfile struct {
 	LastMessage  *MessagePreview `json:"last_message,omitempty"`
 	UnreadCount  int             `json:"unread_count"`
 	ImageEnabled bool            `json:"image_enabled"` // whether you've enabled images
+	Online       bool            `json:"online"`
+	LastSeen     *time.Time      `json:"last_seen,omitempty"` // nil while online or when hidden
 }
 
 // MessagePreview is a preview of a message
@@ -3,8 +3,10 @@ package match
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
+	"github.com/feels/feels/internal/domain/settings"
 	"github.com/google/uuid"
 )
 
@@ -54,11 +56,23 @@ type Hub interface {
 	SendToUser(userID uuid.UUID, msg interface{})
 }
 
+// PrivacyService provides users' privacy settings (defaults when unset)
+type PrivacyService interface {
+	GetPrivacySettingsForUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*settings.PrivacySettings, error)
+}
+
+// PresenceService reports which users are currently connected
+type PresenceService interface {
+	GetOnline(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+}
+
 type Service struct {
-	matchRepo   MatchRepository
-	blockRepo   BlockRepository
-	messageRepo MessageRepository
-	hub         Hub
+	matchRepo       MatchRepository
+	blockRepo       BlockRepository
+	messageRepo     MessageRepository
+	privacyService  PrivacyService
+	presenceService PresenceService
+	hub             Hub
 }
 
 func NewService(matchRepo MatchRepository, blockRepo BlockRepository) *Service {
@@ -78,14 +92,78 @@ func (s *Service) SetHub(hub Hub) {
 	s.hub = hub
 }
 
+// SetPrivacyService sets the privacy settings provider used to hide online status
+func (s *Service) SetPrivacyService(ps PrivacyService) {
+	s.privacyService = ps
+}
+
+// SetPresenceService sets the presence service used for online status
+func (s *Service) SetPresenceService(ps PresenceService) {
+	s.presenceService = ps
+}
+
 // GetMatches returns all matches for a user
 func (s *Service) GetMatches(ctx context.Context, userID uuid.UUID) ([]MatchWithProfile, error) {
-	return s.matchRepo.GetUserMatches(ctx, userID)
+	matches, err := s.matchRepo.GetUserMatches(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.applyPresence(ctx, matches)
+	return matches, nil
 }
 
 // GetMatch returns a specific match with the other user's profile
 func (s *Service) GetMatch(ctx context.Context, matchID, userID uuid.UUID) (*MatchWithProfile, error) {
-	return s.matchRepo.GetMatchWithProfile(ctx, matchID, userID)
+	m, err := s.matchRepo.GetMatchWithProfile(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	matches := []MatchWithProfile{*m}
+	s.applyPresence(ctx, matches)
+	return &matches[0], nil
+}
+
+// applyPresence fills in online/last seen, hiding both for matches who've turned off online status
+func (s *Service) applyPresence(ctx context.Context, matches []MatchWithProfile) {
+	if len(matches) == 0 {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(matches))
+	for i := range matches {
+		ids[i] = matches[i].OtherUser.UserID
+	}
+
+	var privacy map[uuid.UUID]*settings.PrivacySettings
+	var privacyErr error
+	if s.privacyService != nil {
+		privacy, privacyErr = s.privacyService.GetPrivacySettingsForUsers(ctx, ids)
+	}
+
+	var online map[uuid.UUID]bool
+	if s.presenceService != nil {
+		var err error
+		if online, err = s.presenceService.GetOnline(ctx, ids); err != nil {
+			log.Printf("[MATCH] Failed to get presence: %v", err)
+		}
+	}
+
+	for i := range matches {
+		m := &matches[i]
+		if s.privacyService != nil {
+			ps := privacy[m.OtherUser.UserID]
+			if privacyErr != nil || ps == nil || !ps.ShowOnlineStatus {
+				m.OtherUser.LastActive = time.Time{}
+				continue
+			}
+		}
+
+		m.Online = online[m.OtherUser.UserID]
+		if !m.Online && !m.OtherUser.LastActive.IsZero() {
+			lastSeen := m.OtherUser.LastActive
+			m.LastSeen = &lastSeen
+		}
+	}
 }
 
 // Unmatch removes a match, deletes messages, and notifies the other user
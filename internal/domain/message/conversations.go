@@ -0,0 +1,120 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidMuteUntil is returned when muting until a time that has already passed
+var ErrInvalidMuteUntil = errors.New("muted_until must be in the future")
+
+// Conversation folders
+const (
+	FolderInbox    = "inbox"
+	FolderArchived = "archived"
+)
+
+// GetConversations lists the user's conversations in a folder, pinned first and
+// then by last activity
+func (s *Service) GetConversations(ctx context.Context, userID uuid.UUID, folder string) (*ConversationsResponse, error) {
+	conversations, err := s.repo.GetConversations(ctx, userID, folder == FolderArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ConversationsResponse{Conversations: conversations}
+	if resp.Conversations == nil {
+		resp.Conversations = []Conversation{}
+	}
+
+	// Last messages need the same per-viewer treatment as a page of messages
+	var last []Message
+	for _, c := range resp.Conversations {
+		if c.LastMessage != nil {
+			last = append(last, *c.LastMessage)
+		}
+	}
+	if err := s.attachCiphertexts(ctx, userID, last); err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]Message, len(last))
+	for _, m := range last {
+		byID[m.ID] = m
+	}
+	for i := range resp.Conversations {
+		c := &resp.Conversations[i]
+		if c.LastMessage != nil {
+			m := byID[c.LastMessage.ID]
+			if m.SenderID == userID && !s.showsReadReceipts(ctx, c.OtherUserID) {
+				m.ReadAt = nil
+			}
+			c.LastMessage = &m
+		}
+	}
+
+	if folder != FolderArchived {
+		if resp.ArchivedCount, err = s.repo.CountArchivedConversations(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// UpdateConversation changes the user's mute, archive or pin state for a match
+func (s *Service) UpdateConversation(ctx context.Context, userID, matchID uuid.UUID, req *UpdateConversationRequest) (*ConversationSettings, error) {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inMatch {
+		return nil, ErrNotInMatch
+	}
+
+	current, err := s.repo.GetConversationSettings(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !current.isMuted(time.Now()) {
+		current.Muted, current.MutedUntil = false, nil
+	}
+
+	if req.Muted != nil {
+		current.Muted = *req.Muted
+		current.MutedUntil = nil
+		if *req.Muted && req.MutedUntil != nil {
+			if !req.MutedUntil.After(time.Now()) {
+				return nil, ErrInvalidMuteUntil
+			}
+			current.MutedUntil = req.MutedUntil
+		}
+	}
+	if req.Archived != nil {
+		current.Archived = *req.Archived
+	}
+	if req.Pinned != nil {
+		current.Pinned = *req.Pinned
+	}
+
+	if err := s.repo.SaveConversationSettings(ctx, matchID, userID, current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// isMuted reports whether the user has muted the conversation right now
+func (cs *ConversationSettings) isMuted(now time.Time) bool {
+	return cs.Muted && (cs.MutedUntil == nil || cs.MutedUntil.After(now))
+}
+
+// conversationMuted reports whether the user has muted the match, treating lookup
+// failures as unmuted so pushes still go out
+func (s *Service) conversationMuted(ctx context.Context, matchID, userID uuid.UUID) bool {
+	cs, err := s.repo.GetConversationSettings(ctx, matchID, userID)
+	if err != nil {
+		return false
+	}
+	return cs.isMuted(time.Now())
+}
@@ -0,0 +1,109 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidDeviceCiphertexts = errors.New("device ciphertexts must come from one of your keyed devices and target devices in this match")
+	ErrStaleDevices             = errors.New("recipient devices changed, refetch key bundles and re-encrypt")
+)
+
+// maxCiphertextLength bounds a single per-device ciphertext
+const maxCiphertextLength = 64 * 1024
+
+// DeviceDirectory lists the devices that have published E2E keys
+type DeviceDirectory interface {
+	KeyedDevices(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]string, error)
+}
+
+// SetDeviceDirectory sets the key directory used to check per-device ciphertexts
+func (s *Service) SetDeviceDirectory(d DeviceDirectory) {
+	s.deviceDirectory = d
+}
+
+// validateDeviceCiphertexts checks a multi-device message comes from one of the sender's
+// keyed devices, only targets the two people in the match, and covers every keyed device
+// of the recipient so none of their devices is left unable to read it
+func (s *Service) validateDeviceCiphertexts(ctx context.Context, userID, otherUserID uuid.UUID, req *SendMessageRequest) error {
+	if req.SenderDeviceID == "" || (req.Content != nil && *req.Content != "") {
+		return ErrInvalidDeviceCiphertexts
+	}
+
+	covered := make(map[string]bool, len(req.DeviceCiphertexts))
+	for _, c := range req.DeviceCiphertexts {
+		if c.UserID != userID && c.UserID != otherUserID {
+			return ErrInvalidDeviceCiphertexts
+		}
+		if c.DeviceID == "" || c.Ciphertext == "" || len(c.Ciphertext) > maxCiphertextLength {
+			return ErrInvalidDeviceCiphertexts
+		}
+		if c.UserID == userID && c.DeviceID == req.SenderDeviceID {
+			return ErrInvalidDeviceCiphertexts
+		}
+		key := c.UserID.String() + "/" + c.DeviceID
+		if covered[key] {
+			return ErrInvalidDeviceCiphertexts
+		}
+		covered[key] = true
+	}
+
+	// Without the key directory there's no way to tell whose devices these are
+	if s.deviceDirectory == nil {
+		return ErrInvalidDeviceCiphertexts
+	}
+	devices, err := s.deviceDirectory.KeyedDevices(ctx, []uuid.UUID{userID, otherUserID})
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(devices[userID], req.SenderDeviceID) {
+		return ErrInvalidDeviceCiphertexts
+	}
+	for _, deviceID := range devices[otherUserID] {
+		if !covered[otherUserID.String()+"/"+deviceID] {
+			return ErrStaleDevices
+		}
+	}
+	return nil
+}
+
+// ciphertextsFor picks out the ciphertexts addressed to one user's devices
+func ciphertextsFor(all []DeviceCiphertext, userID uuid.UUID) map[string]string {
+	var out map[string]string
+	for _, c := range all {
+		if c.UserID != userID {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[c.DeviceID] = c.Ciphertext
+	}
+	return out
+}
+
+// attachCiphertexts loads the viewer's per-device ciphertexts for a page of messages
+func (s *Service) attachCiphertexts(ctx context.Context, userID uuid.UUID, messages []Message) error {
+	var ids []uuid.UUID
+	for _, m := range messages {
+		if m.DeviceEncrypted {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ciphertexts, err := s.repo.GetCiphertexts(ctx, ids, userID)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		messages[i].Ciphertexts = ciphertexts[messages[i].ID]
+	}
+	return nil
+}
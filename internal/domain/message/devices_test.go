@@ -0,0 +1,64 @@
+package message
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticDirectory is a fixed key directory
+type staticDirectory map[uuid.UUID][]string
+
+func (d staticDirectory) KeyedDevices(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	return d, nil
+}
+
+func TestValidateDeviceCiphertexts(t *testing.T) {
+	sender, recipient := uuid.New(), uuid.New()
+	dir := staticDirectory{
+		sender:    {"phone", "laptop"},
+		recipient: {"r-phone"},
+	}
+	to := func(userID uuid.UUID, deviceIDs ...string) []DeviceCiphertext {
+		var out []DeviceCiphertext
+		for _, id := range deviceIDs {
+			out = append(out, DeviceCiphertext{UserID: userID, DeviceID: id, Ciphertext: "c2VjcmV0"})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name         string
+		senderDevice string
+		ciphertexts  []DeviceCiphertext
+		directory    DeviceDirectory
+		wantErr      error
+	}{
+		{"recipient and own other device", "phone", append(to(recipient, "r-phone"), to(sender, "laptop")...), dir, nil},
+		{"sender device not registered", "burner", to(recipient, "r-phone"), dir, ErrInvalidDeviceCiphertexts},
+		{"sender device belongs to the recipient", "r-phone", to(recipient, "r-phone"), dir, ErrInvalidDeviceCiphertexts},
+		{"no sender device", "", to(recipient, "r-phone"), dir, ErrInvalidDeviceCiphertexts},
+		{"ciphertext for the sending device", "phone", append(to(recipient, "r-phone"), to(sender, "phone")...), dir, ErrInvalidDeviceCiphertexts},
+		{"ciphertext for someone outside the match", "phone", append(to(recipient, "r-phone"), to(uuid.New(), "x")...), dir, ErrInvalidDeviceCiphertexts},
+		{"recipient device missing", "phone", to(sender, "laptop"), dir, ErrStaleDevices},
+		{"no key directory", "phone", to(recipient, "r-phone"), nil, ErrInvalidDeviceCiphertexts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &Service{}
+			if tt.directory != nil {
+				svc.SetDeviceDirectory(tt.directory)
+			}
+			req := &SendMessageRequest{SenderDeviceID: tt.senderDevice, DeviceCiphertexts: tt.ciphertexts}
+			err := svc.validateDeviceCiphertexts(context.Background(), sender, recipient, req)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotMessageSender    = errors.New("only the sender can change this message")
+	ErrEditWindowExpired   = errors.New("message can no longer be edited")
+	ErrMessageUnsent       = errors.New("message was unsent")
+	ErrInvalidReaction     = errors.New("reaction must be a single emoji")
+	ErrDeviceEncryptedEdit = errors.New("device-encrypted messages can't be edited, unsend and resend instead")
+)
+
+const (
+	// EditWindow is how long after sending a message can be edited
+	EditWindow = 15 * time.Minute
+
+	// maxReactionRunes allows multi-codepoint emoji (skin tones, ZWJ sequences) but not text
+	maxReactionRunes = 8
+)
+
+// getMatchMessage loads a message and checks it belongs to a match the user is in
+func (s *Service) getMatchMessage(ctx context.Context, userID, matchID, messageID uuid.UUID) (*Message, error) {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inMatch {
+		return nil, ErrNotInMatch
+	}
+
+	msg, err := s.repo.GetByID(ctx, messageID)
+	if err != nil || msg.MatchID != matchID {
+		return nil, ErrMessageNotFound
+	}
+	return msg, nil
+}
+
+// EditMessage replaces the text of the user's own message within EditWindow
+// The previous version is kept in the edit history for moderation
+func (s *Service) EditMessage(ctx context.Context, userID, matchID, messageID uuid.UUID, req *EditMessageRequest) (*Message, error) {
+	msg, err := s.getMatchMessage(ctx, userID, matchID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SenderID != userID {
+		return nil, ErrNotMessageSender
+	}
+	if msg.UnsentAt != nil {
+		return nil, ErrMessageUnsent
+	}
+	if msg.DeviceEncrypted {
+		return nil, ErrDeviceEncryptedEdit
+	}
+	if time.Since(msg.CreatedAt) > EditWindow {
+		return nil, ErrEditWindowExpired
+	}
+
+	if (req.Content == nil || *req.Content == "") && (req.EncryptedContent == nil || *req.EncryptedContent == "") {
+		return nil, ErrEmptyMessage
+	}
+
+	if s.moderationService != nil && req.Content != nil && *req.Content != "" {
+		if err := s.moderationService.CheckContent(ctx, userID, &messageID, *req.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	edited, err := s.repo.EditMessage(ctx, messageID, userID, req.Content, req.EncryptedContent)
+	if err != nil {
+		return nil, err
+	}
+
+	otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+	if err != nil {
+		// The edit is saved; just don't reveal a read time we can't check
+		log.Printf("[MESSAGE] Failed to look up other user for edited message %s: %v", messageID, err)
+		edited.ReadAt = nil
+		return edited, nil
+	}
+
+	if s.hub != nil {
+		s.hub.SendToUser(otherUserID, WSMessage{
+			Type: EventMessageEdited,
+			Payload: NewMessagePayload{
+				Message: *edited,
+			},
+		})
+	}
+
+	// The edited message goes back to the sender, so hide the recipient's read time too
+	s.hideReadReceipts(ctx, userID, otherUserID, edited)
+	return edited, nil
+}
+
+// UnsendMessage takes back the user's own message, leaving a tombstone in the chat
+func (s *Service) UnsendMessage(ctx context.Context, userID, matchID, messageID uuid.UUID) error {
+	msg, err := s.getMatchMessage(ctx, userID, matchID, messageID)
+	if err != nil {
+		return err
+	}
+	if msg.SenderID != userID {
+		return ErrNotMessageSender
+	}
+	if msg.UnsentAt != nil {
+		return nil
+	}
+
+	if err := s.repo.UnsendMessage(ctx, messageID, userID); err != nil {
+		return err
+	}
+
+	if s.hub != nil {
+		otherUserID, _ := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+		s.hub.SendToUser(otherUserID, WSMessage{
+			Type: EventMessageUnsent,
+			Payload: MessageUnsentPayload{
+				MatchID:   matchID,
+				MessageID: messageID,
+			},
+		})
+	}
+
+	return nil
+}
+
+// ReactToMessage sets the user's reaction to a message, replacing any previous one
+func (s *Service) ReactToMessage(ctx context.Context, userID, matchID, messageID uuid.UUID, emoji string) error {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxReactionRunes || strings.ContainsAny(emoji, " \t\n") {
+		return ErrInvalidReaction
+	}
+
+	msg, err := s.getMatchMessage(ctx, userID, matchID, messageID)
+	if err != nil {
+		return err
+	}
+	if msg.UnsentAt != nil {
+		return ErrMessageUnsent
+	}
+
+	if err := s.repo.SetReaction(ctx, messageID, userID, emoji); err != nil {
+		return err
+	}
+
+	s.notifyReaction(ctx, userID, matchID, messageID, emoji)
+	return nil
+}
+
+// RemoveReaction clears the user's reaction to a message
+func (s *Service) RemoveReaction(ctx context.Context, userID, matchID, messageID uuid.UUID) error {
+	if _, err := s.getMatchMessage(ctx, userID, matchID, messageID); err != nil {
+		return err
+	}
+
+	removed, err := s.repo.DeleteReaction(ctx, messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	if removed {
+		s.notifyReaction(ctx, userID, matchID, messageID, "")
+	}
+	return nil
+}
+
+func (s *Service) notifyReaction(ctx context.Context, userID, matchID, messageID uuid.UUID, emoji string) {
+	if s.hub == nil {
+		return
+	}
+	otherUserID, _ := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+	s.hub.SendToUser(otherUserID, WSMessage{
+		Type: EventMessageReaction,
+		Payload: MessageReactionPayload{
+			MatchID:   matchID,
+			MessageID: messageID,
+			UserID:    userID,
+			Emoji:     emoji,
+		},
+	})
+}
+
+// attachReactions loads reactions for a page of messages
+func (s *Service) attachReactions(ctx context.Context, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+
+	reactions, err := s.repo.GetReactions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		messages[i].Reactions = reactions[messages[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package message
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// editRepo is an in-memory message store that keeps edit history
+type editRepo struct {
+	Repository
+	messages map[uuid.UUID]*Message
+	history  []string // "<action> <message id>", oldest first
+}
+
+func (r *editRepo) GetByID(ctx context.Context, msgID uuid.UUID) (*Message, error) {
+	msg, ok := r.messages[msgID]
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+	copied := *msg
+	return &copied, nil
+}
+
+func (r *editRepo) EditMessage(ctx context.Context, msgID, editorID uuid.UUID, content, encryptedContent *string) (*Message, error) {
+	msg := r.messages[msgID]
+	r.history = append(r.history, EditActionEdit+" "+msgID.String())
+	now := time.Now()
+	msg.Content, msg.EncryptedContent, msg.EditedAt = content, encryptedContent, &now
+	copied := *msg
+	return &copied, nil
+}
+
+func (r *editRepo) UnsendMessage(ctx context.Context, msgID, userID uuid.UUID) error {
+	msg := r.messages[msgID]
+	r.history = append(r.history, EditActionUnsend+" "+msgID.String())
+	now := time.Now()
+	msg.Content, msg.EncryptedContent, msg.ImageURL, msg.UnsentAt = nil, nil, nil, &now
+	return nil
+}
+
+func newEditService(t *testing.T) (*Service, *editRepo, *matchPair, *recordingHub) {
+	t.Helper()
+	matches := &matchPair{matchID: uuid.New(), userA: uuid.New(), userB: uuid.New()}
+	repo := &editRepo{messages: make(map[uuid.UUID]*Message)}
+	hub := &recordingHub{sent: make(map[uuid.UUID][]WSMessage)}
+	return NewService(repo, matches, hub), repo, matches, hub
+}
+
+// addMessage stores a text message from sender, sent age ago
+func (r *editRepo) addMessage(matchID, senderID uuid.UUID, age time.Duration) *Message {
+	content := "original"
+	msg := &Message{ID: uuid.New(), MatchID: matchID, SenderID: senderID, Kind: KindText, Content: &content, CreatedAt: time.Now().Add(-age)}
+	r.messages[msg.ID] = msg
+	return msg
+}
+
+func text(s string) *string { return &s }
+
+func TestEditMessage_Window(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     time.Duration
+		wantErr error
+	}{
+		{"just sent", 0, nil},
+		{"inside the window", EditWindow - time.Minute, nil},
+		{"after the window", EditWindow + time.Second, ErrEditWindowExpired},
+		{"long after", 24 * time.Hour, ErrEditWindowExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, matches, hub := newEditService(t)
+			msg := repo.addMessage(matches.matchID, matches.userA, tt.age)
+
+			edited, err := svc.EditMessage(context.Background(), matches.userA, matches.matchID, msg.ID, &EditMessageRequest{Content: text("fixed")})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, "original", *repo.messages[msg.ID].Content)
+				assert.Empty(t, repo.history)
+				assert.Empty(t, hub.sent[matches.userB])
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "fixed", *edited.Content)
+			assert.NotNil(t, edited.EditedAt)
+			assert.Equal(t, []string{"edit " + msg.ID.String()}, repo.history)
+			require.Len(t, hub.sent[matches.userB], 1)
+			assert.Equal(t, EventMessageEdited, hub.sent[matches.userB][0].Type)
+		})
+	}
+}
+
+func TestEditMessage_Rejections(t *testing.T) {
+	svc, repo, matches, _ := newEditService(t)
+	ctx := context.Background()
+
+	theirs := repo.addMessage(matches.matchID, matches.userB, 0)
+	unsent := repo.addMessage(matches.matchID, matches.userA, 0)
+	unsent.UnsentAt = &unsent.CreatedAt
+	encrypted := repo.addMessage(matches.matchID, matches.userA, 0)
+	encrypted.DeviceEncrypted = true
+	mine := repo.addMessage(matches.matchID, matches.userA, 0)
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		msgID   uuid.UUID
+		req     *EditMessageRequest
+		wantErr error
+	}{
+		{"someone else's message", matches.userA, theirs.ID, &EditMessageRequest{Content: text("x")}, ErrNotMessageSender},
+		{"unsent message", matches.userA, unsent.ID, &EditMessageRequest{Content: text("x")}, ErrMessageUnsent},
+		{"device-encrypted message", matches.userA, encrypted.ID, &EditMessageRequest{Content: text("x")}, ErrDeviceEncryptedEdit},
+		{"empty edit", matches.userA, mine.ID, &EditMessageRequest{Content: text("")}, ErrEmptyMessage},
+		{"not in match", uuid.New(), mine.ID, &EditMessageRequest{Content: text("x")}, ErrNotInMatch},
+		{"unknown message", matches.userA, uuid.New(), &EditMessageRequest{Content: text("x")}, ErrMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.EditMessage(ctx, tt.userID, matches.matchID, tt.msgID, tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	assert.Empty(t, repo.history)
+}
+
+func TestUnsendMessage(t *testing.T) {
+	svc, repo, matches, hub := newEditService(t)
+	ctx := context.Background()
+
+	// Unsending has no time limit
+	msg := repo.addMessage(matches.matchID, matches.userA, 24*time.Hour)
+
+	require.NoError(t, svc.UnsendMessage(ctx, matches.userA, matches.matchID, msg.ID))
+	stored := repo.messages[msg.ID]
+	assert.NotNil(t, stored.UnsentAt)
+	assert.Nil(t, stored.Content)
+	assert.Equal(t, []string{"unsend " + msg.ID.String()}, repo.history)
+	require.Len(t, hub.sent[matches.userB], 1)
+	assert.Equal(t, EventMessageUnsent, hub.sent[matches.userB][0].Type)
+	assert.Equal(t, MessageUnsentPayload{MatchID: matches.matchID, MessageID: msg.ID}, hub.sent[matches.userB][0].Payload)
+
+	// Unsending again is a no-op
+	require.NoError(t, svc.UnsendMessage(ctx, matches.userA, matches.matchID, msg.ID))
+	assert.Len(t, repo.history, 1)
+	assert.Len(t, hub.sent[matches.userB], 1)
+
+	// An unsent message can't be edited or reacted to
+	_, err := svc.EditMessage(ctx, matches.userA, matches.matchID, msg.ID, &EditMessageRequest{Content: text("back")})
+	assert.ErrorIs(t, err, ErrMessageUnsent)
+	assert.ErrorIs(t, svc.ReactToMessage(ctx, matches.userB, matches.matchID, msg.ID, "❤️"), ErrMessageUnsent)
+}
+
+func TestUnsendMessage_Rejections(t *testing.T) {
+	svc, repo, matches, hub := newEditService(t)
+	ctx := context.Background()
+	theirs := repo.addMessage(matches.matchID, matches.userB, 0)
+
+	assert.ErrorIs(t, svc.UnsendMessage(ctx, matches.userA, matches.matchID, theirs.ID), ErrNotMessageSender)
+	assert.ErrorIs(t, svc.UnsendMessage(ctx, uuid.New(), matches.matchID, theirs.ID), ErrNotInMatch)
+	assert.ErrorIs(t, svc.UnsendMessage(ctx, matches.userA, matches.matchID, uuid.New()), ErrMessageNotFound)
+	assert.ErrorIs(t, svc.UnsendMessage(ctx, matches.userA, uuid.New(), theirs.ID), ErrNotInMatch)
+
+	assert.Nil(t, repo.messages[theirs.ID].UnsentAt)
+	assert.Empty(t, repo.history)
+	assert.Empty(t, hub.sent)
+}
+
+func TestEditMessage_ReadReceipts(t *testing.T) {
+	tests := []struct {
+		name       string
+		otherHides bool
+		wantReadAt bool
+	}{
+		{"other user shows read receipts", false, true},
+		{"other user hides read receipts", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, matches, hub := newEditService(t)
+			svc.SetPrivacyService(readReceipts{matches.userB: tt.otherHides})
+			msg := repo.addMessage(matches.matchID, matches.userA, time.Minute)
+			readAt := time.Now()
+			msg.ReadAt = &readAt
+
+			edited, err := svc.EditMessage(context.Background(), matches.userA, matches.matchID, msg.ID, &EditMessageRequest{Content: text("edited")})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantReadAt, edited.ReadAt != nil)
+
+			// The recipient is told about the edit either way
+			require.Len(t, hub.sent[matches.userB], 1)
+			assert.Equal(t, EventMessageEdited, hub.sent[matches.userB][0].Type)
+		})
+	}
+}
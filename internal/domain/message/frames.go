@@ -0,0 +1,229 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Client -> server frame types
+const (
+	FrameTypingStart = EventTypingStart
+	FrameTypingStop  = EventTypingStop
+	FrameMarkRead    = "mark_read"
+	FramePing        = "ping"
+	FrameAck         = "ack"
+)
+
+// Server -> client replies to frames
+const (
+	EventPong             = "pong"
+	EventMessageDelivered = "message_delivered"
+	EventFrameError       = "frame_error" // unparseable or unknown frame
+)
+
+// Frame error codes
+const (
+	FrameErrInvalidFrame   = "invalid_frame"
+	FrameErrUnknownType    = "unknown_type"
+	FrameErrInvalidPayload = "invalid_payload"
+	FrameErrNotInMatch     = "not_in_match"
+	FrameErrNotFound       = "not_found"
+	FrameErrRateLimited    = "rate_limited"
+	FrameErrInternal       = "internal_error"
+)
+
+// Frames bypass the HTTP rate limiter, so each user gets frameRateLimit frames of each
+// type per frameRateWindow; pings are exempt as they never touch the database
+const (
+	frameRateLimit  = 20
+	frameRateWindow = 10 * time.Second
+)
+
+// limitedFrames are the frame types counted by the limiter; anything else is rejected
+// before it is counted, so made-up types can't grow the limiter's table
+var limitedFrames = map[string]bool{
+	FrameTypingStart: true,
+	FrameTypingStop:  true,
+	FrameMarkRead:    true,
+	FrameAck:         true,
+}
+
+// ErrMessageNotFound is returned when an acked message isn't in the match
+var ErrMessageNotFound = errors.New("message not found")
+
+// InboundFrame is a frame sent by the client over the socket
+// ID is optional and echoed back in replies so the client can correlate them
+type InboundFrame struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// MatchFramePayload is the payload for typing and mark_read frames
+type MatchFramePayload struct {
+	MatchID uuid.UUID `json:"match_id"`
+}
+
+// AckFramePayload is the payload for delivery ack frames
+type AckFramePayload struct {
+	MatchID   uuid.UUID `json:"match_id"`
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+// PongPayload is the reply to a ping frame
+type PongPayload struct {
+	ID string `json:"id,omitempty"`
+}
+
+// FrameErrorPayload describes why a frame was rejected
+type FrameErrorPayload struct {
+	ID      string `json:"id,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// MessageDeliveredPayload tells a sender their message reached the recipient's device
+type MessageDeliveredPayload struct {
+	MatchID   uuid.UUID `json:"match_id"`
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+// FrameErrorType is the error reply type for a frame type, e.g. mark_read_error
+func FrameErrorType(frameType string) string {
+	return frameType + "_error"
+}
+
+// HandleFrame processes one client frame and returns the reply for that connection, or nil
+func (s *Service) HandleFrame(ctx context.Context, userID uuid.UUID, data []byte) interface{} {
+	var frame InboundFrame
+	if err := json.Unmarshal(data, &frame); err != nil || frame.Type == "" {
+		return frameError(EventFrameError, "", FrameErrInvalidFrame, "frame must be a JSON object with a type")
+	}
+
+	if frame.Type == FramePing {
+		return WSMessage{Type: EventPong, Payload: PongPayload{ID: frame.ID}}
+	}
+	if !limitedFrames[frame.Type] {
+		return frameError(EventFrameError, frame.ID, FrameErrUnknownType, "unknown frame type: "+frame.Type)
+	}
+	if !s.frameLimiter.allow(userID, frame.Type, time.Now()) {
+		return frameError(FrameErrorType(frame.Type), frame.ID, FrameErrRateLimited, "too many frames, slow down")
+	}
+
+	switch frame.Type {
+
+	case FrameTypingStart, FrameTypingStop:
+		var p MatchFramePayload
+		if err := json.Unmarshal(frame.Payload, &p); err != nil || p.MatchID == uuid.Nil {
+			return frameError(FrameErrorType(frame.Type), frame.ID, FrameErrInvalidPayload, "match_id required")
+		}
+		if err := s.SendTypingIndicator(ctx, userID, p.MatchID, frame.Type == FrameTypingStart); err != nil {
+			return s.frameFailure(frame, err)
+		}
+		return nil
+
+	case FrameMarkRead:
+		var p MatchFramePayload
+		if err := json.Unmarshal(frame.Payload, &p); err != nil || p.MatchID == uuid.Nil {
+			return frameError(FrameErrorType(frame.Type), frame.ID, FrameErrInvalidPayload, "match_id required")
+		}
+		if err := s.MarkRead(ctx, userID, p.MatchID); err != nil {
+			return s.frameFailure(frame, err)
+		}
+		return nil
+
+	case FrameAck:
+		var p AckFramePayload
+		if err := json.Unmarshal(frame.Payload, &p); err != nil || p.MatchID == uuid.Nil || p.MessageID == uuid.Nil {
+			return frameError(FrameErrorType(frame.Type), frame.ID, FrameErrInvalidPayload, "match_id and message_id required")
+		}
+		if err := s.AckDelivery(ctx, userID, p.MatchID, p.MessageID); err != nil {
+			return s.frameFailure(frame, err)
+		}
+	}
+	return nil
+}
+
+// frameFailure maps a service error to the frame's error reply
+func (s *Service) frameFailure(frame InboundFrame, err error) WSMessage {
+	errType := FrameErrorType(frame.Type)
+	switch {
+	case errors.Is(err, ErrNotInMatch):
+		return frameError(errType, frame.ID, FrameErrNotInMatch, "not in match")
+	case errors.Is(err, ErrMessageNotFound):
+		return frameError(errType, frame.ID, FrameErrNotFound, "message not found")
+	default:
+		log.Printf("[WS] %s frame failed: %v", frame.Type, err)
+		return frameError(errType, frame.ID, FrameErrInternal, "failed to process frame")
+	}
+}
+
+func frameError(eventType, id, code, msg string) WSMessage {
+	return WSMessage{
+		Type: eventType,
+		Payload: FrameErrorPayload{
+			ID:      id,
+			Code:    code,
+			Message: msg,
+		},
+	}
+}
+
+// frameLimiter counts frames per user and type in fixed windows
+type frameLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	counts    map[frameKey]*frameWindow
+	lastSweep time.Time
+}
+
+type frameKey struct {
+	userID    uuid.UUID
+	frameType string
+}
+
+type frameWindow struct {
+	start time.Time
+	count int
+}
+
+func newFrameLimiter(limit int, window time.Duration) *frameLimiter {
+	return &frameLimiter{
+		limit:  limit,
+		window: window,
+		counts: make(map[frameKey]*frameWindow),
+	}
+}
+
+// allow counts a frame and reports whether it is within the limit
+func (l *frameLimiter) allow(userID uuid.UUID, frameType string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop finished windows now and then so disconnected users don't pile up
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, w := range l.counts {
+			if now.Sub(w.start) >= l.window {
+				delete(l.counts, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := frameKey{userID: userID, frameType: frameType}
+	w, ok := l.counts[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &frameWindow{start: now}
+		l.counts[key] = w
+	}
+	w.count++
+	return w.count <= l.limit
+}
@@ -0,0 +1,196 @@
+package message
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frameRepo implements the parts of Repository that frames touch
+type frameRepo struct {
+	Repository
+	messages map[uuid.UUID]*Message
+}
+
+func (r *frameRepo) MarkMessagesRead(ctx context.Context, matchID, readerID uuid.UUID) (int, error) {
+	return 1, nil
+}
+
+func (r *frameRepo) GetByID(ctx context.Context, msgID uuid.UUID) (*Message, error) {
+	msg, ok := r.messages[msgID]
+	if !ok {
+		return nil, fmt.Errorf("message %s not found", msgID)
+	}
+	return msg, nil
+}
+
+// matchPair is a single two-person match
+type matchPair struct {
+	matchID, userA, userB uuid.UUID
+}
+
+func (m *matchPair) IsUserInMatch(ctx context.Context, matchID, userID uuid.UUID) (bool, error) {
+	return matchID == m.matchID && (userID == m.userA || userID == m.userB), nil
+}
+
+func (m *matchPair) GetOtherUserID(ctx context.Context, matchID, userID uuid.UUID) (uuid.UUID, error) {
+	if userID == m.userA {
+		return m.userB, nil
+	}
+	return m.userA, nil
+}
+
+// recordingHub keeps every event sent to each user
+type recordingHub struct {
+	mu   sync.Mutex
+	sent map[uuid.UUID][]WSMessage
+}
+
+func (h *recordingHub) SendToUser(userID uuid.UUID, msg interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sent[userID] = append(h.sent[userID], msg.(WSMessage))
+}
+
+func newFrameService(t *testing.T) (*Service, *matchPair, *recordingHub, uuid.UUID) {
+	t.Helper()
+	matches := &matchPair{matchID: uuid.New(), userA: uuid.New(), userB: uuid.New()}
+	msgID := uuid.New()
+	repo := &frameRepo{messages: map[uuid.UUID]*Message{
+		msgID: {ID: msgID, MatchID: matches.matchID, SenderID: matches.userB},
+	}}
+	hub := &recordingHub{sent: make(map[uuid.UUID][]WSMessage)}
+	return NewService(repo, matches, hub), matches, hub, msgID
+}
+
+func frame(t *testing.T, frameType string, payload interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"type": frameType, "id": "f1", "payload": payload})
+	require.NoError(t, err)
+	return data
+}
+
+// frameErrorCode returns the error reply's type and code, failing if the reply isn't an error
+func frameErrorCode(t *testing.T, reply interface{}) (string, string) {
+	t.Helper()
+	msg, ok := reply.(WSMessage)
+	require.True(t, ok, "expected an error reply, got %#v", reply)
+	payload, ok := msg.Payload.(FrameErrorPayload)
+	require.True(t, ok, "expected an error payload, got %#v", msg.Payload)
+	return msg.Type, payload.Code
+}
+
+func TestHandleFrame_Validation(t *testing.T) {
+	svc, matches, _, msgID := newFrameService(t)
+	outsider := uuid.New()
+	otherMatch := uuid.New()
+
+	tests := []struct {
+		name     string
+		userID   uuid.UUID
+		data     []byte
+		wantType string
+		wantCode string
+	}{
+		{"not json", matches.userA, []byte("typing"), EventFrameError, FrameErrInvalidFrame},
+		{"missing type", matches.userA, []byte(`{"payload":{}}`), EventFrameError, FrameErrInvalidFrame},
+		{"unknown type", matches.userA, frame(t, "shout", nil), EventFrameError, FrameErrUnknownType},
+		{"typing without payload", matches.userA, frame(t, FrameTypingStart, nil), "typing_start_error", FrameErrInvalidPayload},
+		{"typing with bad match id", matches.userA, frame(t, FrameTypingStop, map[string]string{"match_id": "nope"}), "typing_stop_error", FrameErrInvalidPayload},
+		{"typing from non-participant", outsider, frame(t, FrameTypingStart, MatchFramePayload{MatchID: matches.matchID}), "typing_start_error", FrameErrNotInMatch},
+		{"typing in another match", matches.userA, frame(t, FrameTypingStart, MatchFramePayload{MatchID: otherMatch}), "typing_start_error", FrameErrNotInMatch},
+		{"mark_read without match id", matches.userA, frame(t, FrameMarkRead, map[string]string{}), "mark_read_error", FrameErrInvalidPayload},
+		{"mark_read from non-participant", outsider, frame(t, FrameMarkRead, MatchFramePayload{MatchID: matches.matchID}), "mark_read_error", FrameErrNotInMatch},
+		{"ack without message id", matches.userA, frame(t, FrameAck, MatchFramePayload{MatchID: matches.matchID}), "ack_error", FrameErrInvalidPayload},
+		{"ack of own message", matches.userB, frame(t, FrameAck, AckFramePayload{MatchID: matches.matchID, MessageID: msgID}), "ack_error", FrameErrNotFound},
+		{"ack of unknown message", matches.userA, frame(t, FrameAck, AckFramePayload{MatchID: matches.matchID, MessageID: uuid.New()}), "ack_error", FrameErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotCode := frameErrorCode(t, svc.HandleFrame(context.Background(), tt.userID, tt.data))
+			assert.Equal(t, tt.wantType, gotType)
+			assert.Equal(t, tt.wantCode, gotCode)
+		})
+	}
+}
+
+func TestHandleFrame_ValidFramesReachTheOtherUser(t *testing.T) {
+	svc, matches, hub, msgID := newFrameService(t)
+	ctx := context.Background()
+
+	assert.Nil(t, svc.HandleFrame(ctx, matches.userA, frame(t, FrameTypingStart, MatchFramePayload{MatchID: matches.matchID})))
+	assert.Nil(t, svc.HandleFrame(ctx, matches.userA, frame(t, FrameMarkRead, MatchFramePayload{MatchID: matches.matchID})))
+	assert.Nil(t, svc.HandleFrame(ctx, matches.userA, frame(t, FrameAck, AckFramePayload{MatchID: matches.matchID, MessageID: msgID})))
+
+	var types []string
+	for _, msg := range hub.sent[matches.userB] {
+		types = append(types, msg.Type)
+	}
+	assert.Equal(t, []string{EventTypingStart, EventMessageRead, EventMessageDelivered}, types)
+	assert.Empty(t, hub.sent[matches.userA])
+}
+
+func TestHandleFrame_PingEchoesID(t *testing.T) {
+	svc, matches, _, _ := newFrameService(t)
+
+	reply, ok := svc.HandleFrame(context.Background(), matches.userA, []byte(`{"type":"ping","id":"p7"}`)).(WSMessage)
+	require.True(t, ok)
+	assert.Equal(t, EventPong, reply.Type)
+	assert.Equal(t, PongPayload{ID: "p7"}, reply.Payload)
+}
+
+func TestHandleFrame_RateLimit(t *testing.T) {
+	svc, matches, hub, _ := newFrameService(t)
+	ctx := context.Background()
+	typing := frame(t, FrameTypingStart, MatchFramePayload{MatchID: matches.matchID})
+
+	for i := 0; i < frameRateLimit; i++ {
+		require.Nil(t, svc.HandleFrame(ctx, matches.userA, typing), "frame %d", i)
+	}
+
+	gotType, gotCode := frameErrorCode(t, svc.HandleFrame(ctx, matches.userA, typing))
+	assert.Equal(t, "typing_start_error", gotType)
+	assert.Equal(t, FrameErrRateLimited, gotCode)
+	assert.Len(t, hub.sent[matches.userB], frameRateLimit, "limited frames must not be forwarded")
+
+	// Limits are per user and per frame type, and pings are never limited
+	assert.Nil(t, svc.HandleFrame(ctx, matches.userB, frame(t, FrameTypingStart, MatchFramePayload{MatchID: matches.matchID})))
+	assert.Nil(t, svc.HandleFrame(ctx, matches.userA, frame(t, FrameMarkRead, MatchFramePayload{MatchID: matches.matchID})))
+	reply, ok := svc.HandleFrame(ctx, matches.userA, []byte(`{"type":"ping"}`)).(WSMessage)
+	require.True(t, ok)
+	assert.Equal(t, EventPong, reply.Type)
+}
+
+func TestFrameLimiter_ResetsEachWindow(t *testing.T) {
+	l := newFrameLimiter(2, frameRateWindow)
+	userID := uuid.New()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, l.allow(userID, FrameMarkRead, now))
+	assert.True(t, l.allow(userID, FrameMarkRead, now.Add(frameRateWindow/2)))
+	assert.False(t, l.allow(userID, FrameMarkRead, now.Add(frameRateWindow-1)))
+	assert.True(t, l.allow(userID, FrameMarkRead, now.Add(frameRateWindow)))
+
+	// Finished windows are swept
+	l.allow(uuid.New(), FrameMarkRead, now.Add(3*frameRateWindow))
+	assert.Len(t, l.counts, 1)
+}
+
+func TestHandleFrame_UnknownTypesAreNotCounted(t *testing.T) {
+	svc, matches, _, _ := newFrameService(t)
+
+	for i := 0; i < 3*frameRateLimit; i++ {
+		gotType, gotCode := frameErrorCode(t, svc.HandleFrame(context.Background(), matches.userA, frame(t, fmt.Sprintf("made_up_%d", i), nil)))
+		assert.Equal(t, EventFrameError, gotType)
+		require.Equal(t, FrameErrUnknownType, gotCode, "frame %d", i)
+	}
+	assert.Empty(t, svc.frameLimiter.counts)
+}
@@ -8,16 +8,76 @@ import (
 
 // Message represents a chat message
 type Message struct {
-	ID               uuid.UUID  `json:"id"`
-	MatchID          uuid.UUID  `json:"match_id"`
-	SenderID         uuid.UUID  `json:"sender_id"`
-	Content          *string    `json:"content,omitempty"`
-	EncryptedContent *string    `json:"encrypted_content,omitempty"`
-	ImageURL         *string    `json:"image_url,omitempty"`
-	CreatedAt        time.Time  `json:"created_at"`
-	ReadAt           *time.Time `json:"read_at,omitempty"`
+	ID               uuid.UUID      `json:"id"`
+	MatchID          uuid.UUID      `json:"match_id"`
+	SenderID         uuid.UUID      `json:"sender_id"`
+	Content          *string        `json:"content,omitempty"`
+	EncryptedContent *string        `json:"encrypted_content,omitempty"`
+	ImageURL         *string        `json:"image_url,omitempty"`
+	Kind             string         `json:"kind"`
+	Audio            *VoiceNote     `json:"audio,omitempty"`
+	ViewOnce         bool           `json:"view_once,omitempty"`
+	ViewedAt         *time.Time     `json:"viewed_at,omitempty"` // when a view-once image was opened
+	PrivateObject    *string        `json:"-"`                   // private bucket key of a view-once image
+	CreatedAt        time.Time      `json:"created_at"`
+	ReadAt           *time.Time     `json:"read_at,omitempty"`
+	EditedAt         *time.Time     `json:"edited_at,omitempty"`
+	UnsentAt         *time.Time     `json:"unsent_at,omitempty"` // tombstone: content is cleared when set
+	ReplyToID        *uuid.UUID     `json:"reply_to_id,omitempty"`
+	ReplyTo          *QuotedMessage `json:"reply_to,omitempty"`
+	Reactions        []Reaction     `json:"reactions,omitempty"`
+
+	// Multi-device E2E: one ciphertext per recipient device, filtered to the viewer's devices
+	SenderDeviceID    *string            `json:"sender_device_id,omitempty"`
+	DeviceEncrypted   bool               `json:"device_encrypted,omitempty"`
+	Ciphertexts       map[string]string  `json:"ciphertexts,omitempty"` // device ID -> ciphertext
+	DeviceCiphertexts []DeviceCiphertext `json:"-"`                     // every recipient device, as sent
+}
+
+// DeviceCiphertext is a message encrypted for one device of one user
+// The sender includes its own other devices so they can show the message too
+type DeviceCiphertext struct {
+	UserID     uuid.UUID `json:"user_id"`
+	DeviceID   string    `json:"device_id"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+// Message kinds
+const (
+	KindText  = "text"
+	KindImage = "image"
+	KindVoice = "voice"
+)
+
+// VoiceNote is the audio attached to a voice message
+// Waveform is a downsampled amplitude envelope (0-255) for drawing the bubble
+type VoiceNote struct {
+	URL        string  `json:"url"`
+	DurationMs int     `json:"duration_ms"`
+	Waveform   []int16 `json:"waveform,omitempty"`
+}
+
+// QuotedMessage is a compact preview of the message being replied to
+type QuotedMessage struct {
+	ID          uuid.UUID `json:"id"`
+	SenderID    uuid.UUID `json:"sender_id"`
+	Preview     string    `json:"preview"`
+	Placeholder bool      `json:"placeholder,omitempty"` // original is unsent, encrypted or gone
 }
 
+// Reaction is one user's reaction to a message
+type Reaction struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Edit history actions
+const (
+	EditActionEdit   = "edit"
+	EditActionUnsend = "unsend"
+)
+
 // ImagePermission tracks whether a user has enabled image sharing in a match
 type ImagePermission struct {
 	MatchID   uuid.UUID  `json:"match_id"`
@@ -28,9 +88,56 @@ type ImagePermission struct {
 
 // SendMessageRequest is the request to send a message
 type SendMessageRequest struct {
+	Content          *string    `json:"content,omitempty"`
+	EncryptedContent *string    `json:"encrypted_content,omitempty"` // E2E encrypted content
+	ImageURL         *string    `json:"image_url,omitempty"`         // For image messages
+	Audio            *VoiceNote `json:"audio,omitempty"`             // For voice notes (URL from audio upload)
+	ViewOnceImage    *string    `json:"view_once_image,omitempty"`   // Object key from a view-once image upload
+	ReplyToID        *uuid.UUID `json:"reply_to_id,omitempty"`       // Message being replied to (same match)
+
+	// Multi-device E2E: the sending device and a ciphertext for every keyed device in the match
+	SenderDeviceID    string             `json:"sender_device_id,omitempty"`
+	DeviceCiphertexts []DeviceCiphertext `json:"device_ciphertexts,omitempty"`
+}
+
+// EditMessageRequest is the request to edit a message's text
+type EditMessageRequest struct {
 	Content          *string `json:"content,omitempty"`
-	EncryptedContent *string `json:"encrypted_content,omitempty"` // E2E encrypted content
-	ImageURL         *string `json:"image_url,omitempty"`         // For image messages
+	EncryptedContent *string `json:"encrypted_content,omitempty"`
+}
+
+// ReactionRequest is the request to react to a message
+type ReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// Cursor is a keyset position in a chat, either a message or a point in time
+type Cursor struct {
+	MessageID *uuid.UUID
+	Time      *time.Time
+}
+
+// PageQuery selects a page of messages
+// With After set the page starts just after the cursor, otherwise it ends at
+// Before (or the newest message); Offset is only used when no cursor is given
+type PageQuery struct {
+	Limit  int
+	Offset int
+	Before *Cursor
+	After  *Cursor
+}
+
+// SearchResult is a message matching a search; Highlight is HTML-escaped with the
+// matched terms wrapped in <mark></mark>
+type SearchResult struct {
+	Message   Message `json:"message"`
+	Highlight string  `json:"highlight"`
+}
+
+// SearchResponse is the response for searching a chat
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	HasMore bool           `json:"has_more"`
 }
 
 // MessagesResponse is the response for getting messages
@@ -38,19 +145,52 @@ type MessagesResponse struct {
 	Messages    []Message `json:"messages"`
 	HasMore     bool      `json:"has_more"`
 	ImageStatus struct {
-		YouEnabled   bool `json:"you_enabled"`
-		TheyEnabled  bool `json:"they_enabled"`
-		BothEnabled  bool `json:"both_enabled"`
+		YouEnabled    bool   `json:"you_enabled"`
+		TheyEnabled   bool   `json:"they_enabled"`
+		BothEnabled   bool   `json:"both_enabled"`
+		Unlocked      bool   `json:"unlocked"`                 // 5+5 alternating rule met, so images can be enabled
+		CanSend       bool   `json:"can_send"`                 // false while the user's message input is greyed out
+		BlockedReason string `json:"blocked_reason,omitempty"` // why CanSend is false
 	} `json:"image_status"`
 }
 
 // Conversation represents a match with message context
 type Conversation struct {
-	MatchID      uuid.UUID  `json:"match_id"`
-	OtherUserID  uuid.UUID  `json:"other_user_id"`
-	LastMessage  *Message   `json:"last_message,omitempty"`
-	UnreadCount  int        `json:"unread_count"`
-	ImageEnabled bool       `json:"image_enabled"`
+	MatchID        uuid.UUID  `json:"match_id"`
+	OtherUserID    uuid.UUID  `json:"other_user_id"`
+	OtherUserName  string     `json:"other_user_name"`
+	OtherUserPhoto *string    `json:"other_user_photo,omitempty"`
+	LastMessage    *Message   `json:"last_message,omitempty"`
+	LastActivityAt time.Time  `json:"last_activity_at"` // last message, or when the match was made
+	UnreadCount    int        `json:"unread_count"`
+	ImageEnabled   bool       `json:"image_enabled"`
+	Muted          bool       `json:"muted"`
+	MutedUntil     *time.Time `json:"muted_until,omitempty"` // nil while muted means indefinitely
+	Archived       bool       `json:"archived"`
+	Pinned         bool       `json:"pinned"`
+}
+
+// ConversationSettings is a user's mute, archive and pin state for one match
+type ConversationSettings struct {
+	Muted      bool       `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	Archived   bool       `json:"archived"`
+	Pinned     bool       `json:"pinned"`
+}
+
+// UpdateConversationRequest changes conversation settings; omitted fields are left as they are
+// MutedUntil only applies when muting, and is cleared by muting again without it
+type UpdateConversationRequest struct {
+	Muted      *bool      `json:"muted,omitempty"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	Archived   *bool      `json:"archived,omitempty"`
+	Pinned     *bool      `json:"pinned,omitempty"`
+}
+
+// ConversationsResponse is the response for listing conversations
+type ConversationsResponse struct {
+	Conversations []Conversation `json:"conversations"`
+	ArchivedCount int            `json:"archived_count"` // shown on the archive folder entry in the inbox
 }
 
 // WebSocket event types
@@ -65,6 +205,13 @@ const (
 	EventMatchDeleted   = "match_deleted"
 )
 
+// WebSocket events for changes to an existing message
+const (
+	EventMessageEdited   = "message_edited"
+	EventMessageUnsent   = "message_unsent"
+	EventMessageReaction = "message_reaction"
+)
+
 // WSMessage is a WebSocket message envelope
 type WSMessage struct {
 	Type    string      `json:"type"`
@@ -94,3 +241,17 @@ type MessageReadPayload struct {
 	MatchID  uuid.UUID `json:"match_id"`
 	ReaderID uuid.UUID `json:"reader_id"`
 }
+
+// MessageUnsentPayload is the payload for message unsent events
+type MessageUnsentPayload struct {
+	MatchID   uuid.UUID `json:"match_id"`
+	MessageID uuid.UUID `json:"message_id"`
+}
+
+// MessageReactionPayload is the payload for reaction events (Emoji is empty when removed)
+type MessageReactionPayload struct {
+	MatchID   uuid.UUID `json:"match_id"`
+	MessageID uuid.UUID `json:"message_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Emoji     string    `json:"emoji,omitempty"`
+}
@@ -0,0 +1,85 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidReply is returned when reply_to_id isn't a message in the same match
+var ErrInvalidReply = errors.New("reply_to_id must be a message in this match")
+
+// maxQuotePreviewRunes is the length of the quoted text shown above a reply
+const maxQuotePreviewRunes = 100
+
+// Placeholder previews for messages whose text can't be quoted
+const (
+	QuoteUnsent    = "Message unsent"
+	QuoteEncrypted = "Encrypted message"
+	QuoteImage     = "Photo"
+	QuoteVoice     = "Voice note"
+	QuoteViewOnce  = "View once photo"
+	QuoteMissing   = "Message unavailable"
+)
+
+// quoteMessage builds the compact preview of a replied-to message
+// Encrypted text is never quoted by the server; clients that hold the key can
+// look up the original by ID
+func quoteMessage(msg *Message) *QuotedMessage {
+	q := &QuotedMessage{ID: msg.ID, SenderID: msg.SenderID}
+
+	switch {
+	case msg.UnsentAt != nil:
+		q.Preview, q.Placeholder = QuoteUnsent, true
+	case msg.Content != nil && *msg.Content != "":
+		q.Preview = truncateRunes(*msg.Content, maxQuotePreviewRunes)
+	case msg.DeviceEncrypted, msg.EncryptedContent != nil && *msg.EncryptedContent != "":
+		q.Preview, q.Placeholder = QuoteEncrypted, true
+	case msg.ViewOnce:
+		q.Preview, q.Placeholder = QuoteViewOnce, true
+	case msg.Audio != nil:
+		q.Preview = QuoteVoice
+	case msg.ImageURL != nil && *msg.ImageURL != "":
+		q.Preview = QuoteImage
+	default:
+		q.Preview, q.Placeholder = QuoteMissing, true
+	}
+	return q
+}
+
+// attachReplyPreviews fills in ReplyTo for replies in a page of messages
+func (s *Service) attachReplyPreviews(ctx context.Context, messages []Message) error {
+	var ids []uuid.UUID
+	for _, m := range messages {
+		if m.ReplyToID != nil {
+			ids = append(ids, *m.ReplyToID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	originals, err := s.repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range messages {
+		if messages[i].ReplyToID == nil {
+			continue
+		}
+		if orig, ok := originals[*messages[i].ReplyToID]; ok {
+			messages[i].ReplyTo = quoteMessage(orig)
+		}
+	}
+	return nil
+}
+
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max]) + "…"
+}
@@ -0,0 +1,74 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// ErrEmptySearch is returned when a search query has no terms
+var ErrEmptySearch = errors.New("search query required")
+
+// maxSearchQueryLength bounds the query text passed to Postgres
+const maxSearchQueryLength = 200
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return 50
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
+}
+
+// SearchMessages searches the plaintext messages of a match, newest first
+// Encrypted messages can't be searched server-side and are never returned
+func (s *Service) SearchMessages(ctx context.Context, userID, matchID uuid.UUID, query string, before *Cursor, limit int) (*SearchResponse, error) {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !inMatch {
+		return nil, ErrNotInMatch
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearch
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		query = string([]rune(query)[:maxSearchQueryLength])
+	}
+
+	limit = clampLimit(limit)
+	results, err := s.repo.SearchMessages(ctx, matchID, query, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &SearchResponse{
+		Results: results,
+		HasMore: len(results) > limit,
+	}
+	if resp.HasMore {
+		resp.Results = resp.Results[:limit]
+	}
+	if resp.Results == nil {
+		resp.Results = []SearchResult{}
+	}
+
+	otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]*Message, len(resp.Results))
+	for i := range resp.Results {
+		msgs[i] = &resp.Results[i].Message
+	}
+	s.hideReadReceipts(ctx, userID, otherUserID, msgs...)
+	return resp, nil
+}
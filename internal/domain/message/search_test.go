@@ -0,0 +1,59 @@
+package message
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/feels/feels/internal/domain/settings"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// searchRepo returns canned search results
+type searchRepo struct {
+	Repository
+	results []SearchResult
+}
+
+func (r *searchRepo) SearchMessages(ctx context.Context, matchID uuid.UUID, search string, before *Cursor, limit int) ([]SearchResult, error) {
+	return r.results, nil
+}
+
+// readReceipts is a PrivacyService where only the listed users hide read receipts
+type readReceipts map[uuid.UUID]bool
+
+func (h readReceipts) GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*settings.PrivacySettings, error) {
+	return &settings.PrivacySettings{UserID: userID, ShowReadReceipts: !h[userID]}, nil
+}
+
+func TestSearchMessages_ReadReceipts(t *testing.T) {
+	tests := []struct {
+		name          string
+		otherHides    bool
+		wantOwnReadAt bool
+	}{
+		{"other user shows read receipts", false, true},
+		{"other user hides read receipts", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := &matchPair{matchID: uuid.New(), userA: uuid.New(), userB: uuid.New()}
+			readAt := time.Now()
+			repo := &searchRepo{results: []SearchResult{
+				{Message: Message{ID: uuid.New(), SenderID: matches.userA, ReadAt: &readAt}},
+				{Message: Message{ID: uuid.New(), SenderID: matches.userB, ReadAt: &readAt}},
+			}}
+			svc := NewService(repo, matches, nil)
+			svc.SetPrivacyService(readReceipts{matches.userB: tt.otherHides})
+
+			resp, err := svc.SearchMessages(context.Background(), matches.userA, matches.matchID, "hello", nil, 10)
+			require.NoError(t, err)
+			require.Len(t, resp.Results, 2)
+			assert.Equal(t, tt.wantOwnReadAt, resp.Results[0].Message.ReadAt != nil, "own message read_at")
+			assert.NotNil(t, resp.Results[1].Message.ReadAt, "the caller's own read time is never hidden")
+		})
+	}
+}
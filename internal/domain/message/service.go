@@ -3,8 +3,10 @@ package message
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
+	"github.com/feels/feels/internal/domain/settings"
 	"github.com/google/uuid"
 )
 
@@ -12,24 +14,45 @@ var (
 	ErrNotInMatch           = errors.New("not in match")
 	ErrEmptyMessage         = errors.New("message content or image required")
 	ErrImageNotEnabled      = errors.New("image sharing not enabled by both users")
-	ErrNotEnoughMessages    = errors.New("need at least 5 messages before enabling photos")
+	ErrNotEnoughMessages    = errors.New("need 5 back-and-forth messages from each person before enabling photos")
+	ErrSendingDisabled      = errors.New("turn images back on to send messages in this conversation")
 )
 
-// MinMessagesForPhotos is the minimum number of messages required before photos can be enabled
+// SendBlockedImagesDisabled is the blocked reason while the sender has turned images off
+const SendBlockedImagesDisabled = "images_disabled"
+
+// MinMessagesForPhotos is the number of alternating turns each person needs before photos can be enabled
 const MinMessagesForPhotos = 5
 
 type Repository interface {
 	Create(ctx context.Context, msg *Message) error
 	GetByID(ctx context.Context, msgID uuid.UUID) (*Message, error)
-	GetByMatch(ctx context.Context, matchID uuid.UUID, limit, offset int) ([]Message, error)
+	GetByIDs(ctx context.Context, msgIDs []uuid.UUID) (map[uuid.UUID]*Message, error)
+	GetByMatch(ctx context.Context, matchID uuid.UUID, page PageQuery) ([]Message, error)
+	SearchMessages(ctx context.Context, matchID uuid.UUID, search string, before *Cursor, limit int) ([]SearchResult, error)
 	GetLastMessage(ctx context.Context, matchID uuid.UUID) (*Message, error)
-	CountMessages(ctx context.Context, matchID uuid.UUID) (int, error)
+	CountTurns(ctx context.Context, matchID uuid.UUID) (map[uuid.UUID]int, error)
+	ImagePromptSent(ctx context.Context, matchID uuid.UUID) (bool, error)
+	MarkImagePromptSent(ctx context.Context, matchID uuid.UUID) (bool, error)
 	GetImagePermission(ctx context.Context, matchID, userID uuid.UUID) (*ImagePermission, error)
 	GetBothImagePermissions(ctx context.Context, matchID, userID, otherUserID uuid.UUID) (youEnabled, theyEnabled bool, err error)
 	SetImagePermission(ctx context.Context, matchID, userID uuid.UUID, enabled bool) error
+	MarkImagesBothEnabled(ctx context.Context, matchID uuid.UUID) error
+	ImagesEverBothEnabled(ctx context.Context, matchID uuid.UUID) (bool, error)
 	MarkMessagesRead(ctx context.Context, matchID, readerID uuid.UUID) (int, error)
 	CountUnreadMessages(ctx context.Context, matchID, userID uuid.UUID) (int, error)
 	GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int, error)
+	EditMessage(ctx context.Context, msgID, editorID uuid.UUID, content, encryptedContent *string) (*Message, error)
+	UnsendMessage(ctx context.Context, msgID, userID uuid.UUID) error
+	SetReaction(ctx context.Context, msgID, userID uuid.UUID, emoji string) error
+	DeleteReaction(ctx context.Context, msgID, userID uuid.UUID) (bool, error)
+	GetReactions(ctx context.Context, msgIDs []uuid.UUID) (map[uuid.UUID][]Reaction, error)
+	MarkViewed(ctx context.Context, msgID uuid.UUID) error // ErrAlreadyViewed if it was consumed already
+	GetCiphertexts(ctx context.Context, msgIDs []uuid.UUID, recipientID uuid.UUID) (map[uuid.UUID]map[string]string, error)
+	GetConversations(ctx context.Context, userID uuid.UUID, archived bool) ([]Conversation, error)
+	CountArchivedConversations(ctx context.Context, userID uuid.UUID) (int, error)
+	GetConversationSettings(ctx context.Context, matchID, userID uuid.UUID) (*ConversationSettings, error)
+	SaveConversationSettings(ctx context.Context, matchID, userID uuid.UUID, cs *ConversationSettings) error
 }
 
 type MatchRepository interface {
@@ -57,6 +80,11 @@ type ModerationService interface {
 	CheckContent(ctx context.Context, userID uuid.UUID, messageID *uuid.UUID, content string) error
 }
 
+// PrivacyService provides users' privacy settings (defaults when unset)
+type PrivacyService interface {
+	GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*settings.PrivacySettings, error)
+}
+
 type Service struct {
 	repo                Repository
 	matchRepo           MatchRepository
@@ -64,13 +92,19 @@ type Service struct {
 	notificationService NotificationService
 	profileRepo         ProfileRepository
 	moderationService   ModerationService
+	privacyService      PrivacyService
+	mediaStore          MediaStore
+	audioStore          AudioStore
+	deviceDirectory     DeviceDirectory
+	frameLimiter        *frameLimiter
 }
 
 func NewService(repo Repository, matchRepo MatchRepository, hub Hub) *Service {
 	return &Service{
-		repo:      repo,
-		matchRepo: matchRepo,
-		hub:       hub,
+		repo:         repo,
+		matchRepo:    matchRepo,
+		hub:          hub,
+		frameLimiter: newFrameLimiter(frameRateLimit, frameRateWindow),
 	}
 }
 
@@ -89,8 +123,39 @@ func (s *Service) SetModerationService(ms ModerationService) {
 	s.moderationService = ms
 }
 
-// GetMessages gets messages for a match and marks them as read
-func (s *Service) GetMessages(ctx context.Context, userID, matchID uuid.UUID, limit, offset int) (*MessagesResponse, error) {
+// SetPrivacyService sets the privacy settings provider used for read receipts
+func (s *Service) SetPrivacyService(ps PrivacyService) {
+	s.privacyService = ps
+}
+
+// showsReadReceipts reports whether the user lets others see when they've read a message
+func (s *Service) showsReadReceipts(ctx context.Context, userID uuid.UUID) bool {
+	if s.privacyService == nil {
+		return true
+	}
+	ps, err := s.privacyService.GetPrivacySettings(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return ps.ShowReadReceipts
+}
+
+// hideReadReceipts clears when the other user read the user's own messages if they've
+// turned off read receipts
+func (s *Service) hideReadReceipts(ctx context.Context, userID, otherUserID uuid.UUID, msgs ...*Message) {
+	if s.showsReadReceipts(ctx, otherUserID) {
+		return
+	}
+	for _, m := range msgs {
+		if m.SenderID == userID {
+			m.ReadAt = nil
+		}
+	}
+}
+
+// GetMessages gets a page of messages for a match and marks them as read
+// HasMore reports whether there are more messages past the page in the direction being paged
+func (s *Service) GetMessages(ctx context.Context, userID, matchID uuid.UUID, page PageQuery) (*MessagesResponse, error) {
 	// Verify user is in match
 	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
 	if err != nil {
@@ -101,21 +166,22 @@ func (s *Service) GetMessages(ctx context.Context, userID, matchID uuid.UUID, li
 	}
 
 	// Get messages
-	if limit <= 0 {
-		limit = 50
-	}
-	if limit > 100 {
-		limit = 100
-	}
+	limit := clampLimit(page.Limit)
+	page.Limit = limit + 1
 
-	messages, err := s.repo.GetByMatch(ctx, matchID, limit+1, offset)
+	messages, err := s.repo.GetByMatch(ctx, matchID, page)
 	if err != nil {
 		return nil, err
 	}
 
+	// The extra row is the oldest one, unless paging forward from an after cursor
 	hasMore := len(messages) > limit
 	if hasMore {
-		messages = messages[:limit]
+		if page.After != nil && page.Before == nil {
+			messages = messages[:limit]
+		} else {
+			messages = messages[1:]
+		}
 	}
 
 	// Get image permissions
@@ -136,20 +202,72 @@ func (s *Service) GetMessages(ctx context.Context, userID, matchID uuid.UUID, li
 	resp.ImageStatus.YouEnabled = youEnabled
 	resp.ImageStatus.TheyEnabled = theyEnabled
 	resp.ImageStatus.BothEnabled = youEnabled && theyEnabled
+	if resp.ImageStatus.Unlocked, err = s.imagesUnlocked(ctx, matchID, userID, otherUserID); err != nil {
+		return nil, err
+	}
+	blockedReason, err := s.sendBlockedReason(ctx, matchID, youEnabled)
+	if err != nil {
+		return nil, err
+	}
+	resp.ImageStatus.CanSend = blockedReason == ""
+	resp.ImageStatus.BlockedReason = blockedReason
 
 	if resp.Messages == nil {
 		resp.Messages = []Message{}
 	}
 
+	if err := s.attachReactions(ctx, resp.Messages); err != nil {
+		return nil, err
+	}
+	if err := s.attachReplyPreviews(ctx, resp.Messages); err != nil {
+		return nil, err
+	}
+	if err := s.attachCiphertexts(ctx, userID, resp.Messages); err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*Message, len(resp.Messages))
+	for i := range resp.Messages {
+		msgs[i] = &resp.Messages[i]
+	}
+	s.hideReadReceipts(ctx, userID, otherUserID, msgs...)
+
 	// Mark messages as read (messages from the other user)
+	if _, err := s.markRead(ctx, userID, otherUserID, matchID); err != nil {
+		// Log but don't fail the request
+		log.Printf("[MESSAGE] Failed to mark messages read: %v", err)
+	}
+
+	return resp, nil
+}
+
+// MarkRead marks the other user's messages in a match as read
+func (s *Service) MarkRead(ctx context.Context, userID, matchID uuid.UUID) error {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return err
+	}
+	if !inMatch {
+		return ErrNotInMatch
+	}
+
+	otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.markRead(ctx, userID, otherUserID, matchID)
+	return err
+}
+
+// markRead marks messages read and tells the sender, unless the reader hides read receipts
+func (s *Service) markRead(ctx context.Context, userID, otherUserID, matchID uuid.UUID) (int, error) {
 	markedCount, err := s.repo.MarkMessagesRead(ctx, matchID, userID)
 	if err != nil {
-		// Log but don't fail the request
-		// log.Printf("failed to mark messages read: %v", err)
+		return 0, err
 	}
 
-	// Notify sender that their messages were read
-	if markedCount > 0 && s.hub != nil {
+	if markedCount > 0 && s.hub != nil && s.showsReadReceipts(ctx, userID) {
 		s.hub.SendToUser(otherUserID, WSMessage{
 			Type: EventMessageRead,
 			Payload: MessageReadPayload{
@@ -159,7 +277,35 @@ func (s *Service) GetMessages(ctx context.Context, userID, matchID uuid.UUID, li
 		})
 	}
 
-	return resp, nil
+	return markedCount, nil
+}
+
+// AckDelivery tells the sender that their message reached one of the recipient's devices
+func (s *Service) AckDelivery(ctx context.Context, userID, matchID, messageID uuid.UUID) error {
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return err
+	}
+	if !inMatch {
+		return ErrNotInMatch
+	}
+
+	msg, err := s.repo.GetByID(ctx, messageID)
+	if err != nil || msg.MatchID != matchID || msg.SenderID == userID {
+		return ErrMessageNotFound
+	}
+
+	if s.hub != nil {
+		s.hub.SendToUser(msg.SenderID, WSMessage{
+			Type: EventMessageDelivered,
+			Payload: MessageDeliveredPayload{
+				MatchID:   matchID,
+				MessageID: messageID,
+			},
+		})
+	}
+
+	return nil
 }
 
 // SendMessage sends a message in a match
@@ -174,9 +320,20 @@ func (s *Service) SendMessage(ctx context.Context, userID, matchID uuid.UUID, re
 	}
 
 	// Validate message
-	if (req.Content == nil || *req.Content == "") && (req.ImageURL == nil || *req.ImageURL == "") {
+	hasImage := req.ImageURL != nil && *req.ImageURL != ""
+	viewOnce := req.ViewOnceImage != nil && *req.ViewOnceImage != ""
+	deviceEncrypted := len(req.DeviceCiphertexts) > 0
+	if (req.Content == nil || *req.Content == "") && !hasImage && !viewOnce && req.Audio == nil && !deviceEncrypted {
 		return nil, ErrEmptyMessage
 	}
+	if viewOnce && (hasImage || !validViewOnceKey(userID, *req.ViewOnceImage)) {
+		return nil, ErrInvalidViewOnceImage
+	}
+	if req.Audio != nil {
+		if err := s.validateVoiceNote(userID, req.Audio); err != nil {
+			return nil, err
+		}
+	}
 
 	// Check content with moderation service (only for text content)
 	if s.moderationService != nil && req.Content != nil && *req.Content != "" {
@@ -185,8 +342,39 @@ func (s *Service) SendMessage(ctx context.Context, userID, matchID uuid.UUID, re
 		}
 	}
 
-	// If sending image, check permissions
-	if req.ImageURL != nil && *req.ImageURL != "" {
+	// Once images have been on for both, a user who turns them off can't send anything
+	perm, err := s.repo.GetImagePermission(ctx, matchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if reason, err := s.sendBlockedReason(ctx, matchID, perm.Enabled); err != nil {
+		return nil, err
+	} else if reason != "" {
+		return nil, ErrSendingDisabled
+	}
+
+	// Per-device ciphertexts may only go to the two people in the match
+	if deviceEncrypted {
+		otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.validateDeviceCiphertexts(ctx, userID, otherUserID, req); err != nil {
+			return nil, err
+		}
+	}
+
+	// A reply must quote a message from the same match
+	var replyTo *Message
+	if req.ReplyToID != nil {
+		replyTo, err = s.repo.GetByID(ctx, *req.ReplyToID)
+		if err != nil || replyTo.MatchID != matchID {
+			return nil, ErrInvalidReply
+		}
+	}
+
+	// Images and voice notes both need media sharing enabled by both users
+	if hasImage || viewOnce || req.Audio != nil {
 		otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
 		if err != nil {
 			return nil, err
@@ -209,17 +397,51 @@ func (s *Service) SendMessage(ctx context.Context, userID, matchID uuid.UUID, re
 		Content:          req.Content,
 		EncryptedContent: req.EncryptedContent,
 		ImageURL:         req.ImageURL,
+		Kind:             KindText,
+		Audio:            req.Audio,
 		CreatedAt:        time.Now(),
+		ReplyToID:        req.ReplyToID,
+	}
+	if deviceEncrypted {
+		msg.SenderDeviceID = &req.SenderDeviceID
+		msg.DeviceEncrypted = true
+		msg.DeviceCiphertexts = req.DeviceCiphertexts
+	}
+	switch {
+	case req.Audio != nil:
+		msg.Kind = KindVoice
+	case hasImage:
+		msg.Kind = KindImage
+	case viewOnce:
+		msg.Kind = KindImage
+		msg.ViewOnce = true
+		msg.PrivateObject = req.ViewOnceImage
 	}
 
 	if err := s.repo.Create(ctx, msg); err != nil {
 		return nil, err
 	}
+	if replyTo != nil {
+		msg.ReplyTo = quoteMessage(replyTo)
+	}
 
 	// Notify other user via WebSocket
 	otherUserID, _ := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
 	if s.hub != nil {
+		recipientCopy := *msg
+		recipientCopy.Ciphertexts = ciphertextsFor(msg.DeviceCiphertexts, otherUserID)
 		s.hub.SendToUser(otherUserID, WSMessage{
+			Type: EventNewMessage,
+			Payload: NewMessagePayload{
+				Message: recipientCopy,
+			},
+		})
+	}
+
+	// The sender's other devices only learn about device-encrypted messages this way
+	msg.Ciphertexts = ciphertextsFor(msg.DeviceCiphertexts, userID)
+	if s.hub != nil && deviceEncrypted {
+		s.hub.SendToUser(userID, WSMessage{
 			Type: EventNewMessage,
 			Payload: NewMessagePayload{
 				Message: *msg,
@@ -227,8 +449,10 @@ func (s *Service) SendMessage(ctx context.Context, userID, matchID uuid.UUID, re
 		})
 	}
 
-	// Send push notification for new message
-	if s.notificationService != nil && otherUserID != uuid.Nil {
+	s.maybeSendImagePrompt(ctx, matchID, userID, otherUserID)
+
+	// Send push notification for new message, unless the recipient muted this chat
+	if s.notificationService != nil && otherUserID != uuid.Nil && !s.conversationMuted(ctx, matchID, otherUserID) {
 		senderName := "Someone"
 		if s.profileRepo != nil {
 			if name, err := s.profileRepo.GetNameByUserID(ctx, userID); err == nil && name != "" {
@@ -239,6 +463,12 @@ func (s *Service) SendMessage(ctx context.Context, userID, matchID uuid.UUID, re
 		messagePreview := ""
 		if msg.Content != nil {
 			messagePreview = *msg.Content
+		} else if msg.DeviceEncrypted {
+			messagePreview = "Sent a message"
+		} else if msg.ViewOnce {
+			messagePreview = "Sent a view once photo"
+		} else if msg.Kind == KindVoice {
+			messagePreview = "Sent a voice note"
 		} else if msg.ImageURL != nil {
 			messagePreview = "Sent an image"
 		}
@@ -250,7 +480,7 @@ func (s *Service) SendMessage(ctx context.Context, userID, matchID uuid.UUID, re
 }
 
 // EnableImages enables image sharing for a user in a match
-// Requires at least MinMessagesForPhotos messages in the conversation
+// Requires MinMessagesForPhotos alternating turns from each person
 func (s *Service) EnableImages(ctx context.Context, userID, matchID uuid.UUID) error {
 	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
 	if err != nil {
@@ -260,12 +490,16 @@ func (s *Service) EnableImages(ctx context.Context, userID, matchID uuid.UUID) e
 		return ErrNotInMatch
 	}
 
-	// Check message count requirement
-	msgCount, err := s.repo.CountMessages(ctx, matchID)
+	otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
+	if err != nil {
+		return err
+	}
+
+	unlocked, err := s.imagesUnlocked(ctx, matchID, userID, otherUserID)
 	if err != nil {
 		return err
 	}
-	if msgCount < MinMessagesForPhotos {
+	if !unlocked {
 		return ErrNotEnoughMessages
 	}
 
@@ -273,9 +507,18 @@ func (s *Service) EnableImages(ctx context.Context, userID, matchID uuid.UUID) e
 		return err
 	}
 
+	_, theyEnabled, err := s.repo.GetBothImagePermissions(ctx, matchID, userID, otherUserID)
+	if err != nil {
+		return err
+	}
+	if theyEnabled {
+		if err := s.repo.MarkImagesBothEnabled(ctx, matchID); err != nil {
+			return err
+		}
+	}
+
 	// Notify other user
 	if s.hub != nil {
-		otherUserID, _ := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
 		s.hub.SendToUser(otherUserID, WSMessage{
 			Type: EventImageEnabled,
 			Payload: ImagePermissionPayload{
@@ -289,7 +532,24 @@ func (s *Service) EnableImages(ctx context.Context, userID, matchID uuid.UUID) e
 	return nil
 }
 
+// sendBlockedReason returns why the user can't send messages, or "" if they can
+// Per the spec, turning images off after both users had them on greys out your own input
+func (s *Service) sendBlockedReason(ctx context.Context, matchID uuid.UUID, youEnabled bool) (string, error) {
+	if youEnabled {
+		return "", nil
+	}
+	everBoth, err := s.repo.ImagesEverBothEnabled(ctx, matchID)
+	if err != nil {
+		return "", err
+	}
+	if everBoth {
+		return SendBlockedImagesDisabled, nil
+	}
+	return "", nil
+}
+
 // DisableImages disables image sharing for a user in a match
+// If both users had images on, this also stops the user sending messages until they re-enable
 func (s *Service) DisableImages(ctx context.Context, userID, matchID uuid.UUID) error {
 	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
 	if err != nil {
@@ -348,6 +608,14 @@ func (s *Service) SendTypingIndicator(ctx context.Context, userID, matchID uuid.
 		return nil
 	}
 
+	inMatch, err := s.matchRepo.IsUserInMatch(ctx, matchID, userID)
+	if err != nil {
+		return err
+	}
+	if !inMatch {
+		return ErrNotInMatch
+	}
+
 	otherUserID, err := s.matchRepo.GetOtherUserID(ctx, matchID, userID)
 	if err != nil {
 		return err
@@ -0,0 +1,72 @@
+package message
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// EventImageUnlockPrompt privately asks each user whether they're ready to share photos
+const EventImageUnlockPrompt = "image_unlock_prompt"
+
+// ImageUnlockPromptText is the system prompt shown once a match qualifies for images
+const ImageUnlockPromptText = "Ready to share photos?"
+
+// ImageUnlockPromptPayload is the payload for image unlock prompt events
+type ImageUnlockPromptPayload struct {
+	MatchID uuid.UUID `json:"match_id"`
+	Prompt  string    `json:"prompt"`
+}
+
+// imagesUnlocked applies the 5+5 rule: both users need MinMessagesForPhotos
+// turns, so the conversation has gone back and forth rather than one person
+// sending ten messages
+func (s *Service) imagesUnlocked(ctx context.Context, matchID, userID, otherUserID uuid.UUID) (bool, error) {
+	turns, err := s.repo.CountTurns(ctx, matchID)
+	if err != nil {
+		return false, err
+	}
+	return turns[userID] >= MinMessagesForPhotos && turns[otherUserID] >= MinMessagesForPhotos, nil
+}
+
+// maybeSendImagePrompt sends the unlock prompt to both users the first time the match qualifies
+func (s *Service) maybeSendImagePrompt(ctx context.Context, matchID, userID, otherUserID uuid.UUID) {
+	if s.hub == nil || otherUserID == uuid.Nil {
+		return
+	}
+
+	sent, err := s.repo.ImagePromptSent(ctx, matchID)
+	if err != nil || sent {
+		return
+	}
+
+	unlocked, err := s.imagesUnlocked(ctx, matchID, userID, otherUserID)
+	if err != nil {
+		log.Printf("[MESSAGE] Failed to check image unlock for match %s: %v", matchID, err)
+		return
+	}
+	if !unlocked {
+		return
+	}
+
+	// Only the request that records the prompt sends it, so concurrent messages can't double it
+	marked, err := s.repo.MarkImagePromptSent(ctx, matchID)
+	if err != nil {
+		log.Printf("[MESSAGE] Failed to record image prompt for match %s: %v", matchID, err)
+		return
+	}
+	if !marked {
+		return
+	}
+
+	prompt := WSMessage{
+		Type: EventImageUnlockPrompt,
+		Payload: ImageUnlockPromptPayload{
+			MatchID: matchID,
+			Prompt:  ImageUnlockPromptText,
+		},
+	}
+	s.hub.SendToUser(userID, prompt)
+	s.hub.SendToUser(otherUserID, prompt)
+}
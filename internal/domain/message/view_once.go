@@ -0,0 +1,108 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAlreadyViewed         = errors.New("view-once image was already viewed")
+	ErrNotRecipient          = errors.New("only the recipient can view this image")
+	ErrInvalidViewOnceImage  = errors.New("view_once_image must come from your own view-once upload")
+	ErrMediaStoreUnavailable = errors.New("private media storage not available")
+)
+
+// EventMessageViewed tells the sender their view-once image was opened
+const EventMessageViewed = "message_viewed"
+
+// ViewOnceLinkExpiry is how long the link handed to the recipient works
+const ViewOnceLinkExpiry = time.Minute
+
+// MediaStore issues short-lived links to private objects
+type MediaStore interface {
+	GetPresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error)
+}
+
+// ViewOnceLink is the one-time link to a view-once image
+type ViewOnceLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MessageViewedPayload is the payload for message viewed events
+type MessageViewedPayload struct {
+	MatchID   uuid.UUID `json:"match_id"`
+	MessageID uuid.UUID `json:"message_id"`
+	ViewedAt  time.Time `json:"viewed_at"`
+}
+
+// ViewOnceKeyPrefix is where a user's view-once uploads are stored in the private bucket
+func ViewOnceKeyPrefix(userID uuid.UUID) string {
+	return "view-once/" + userID.String() + "/"
+}
+
+// SetMediaStore sets the private storage used to serve view-once images
+func (s *Service) SetMediaStore(ms MediaStore) {
+	s.mediaStore = ms
+}
+
+// validViewOnceKey checks an uploaded object key belongs to the sender
+func validViewOnceKey(userID uuid.UUID, key string) bool {
+	prefix := ViewOnceKeyPrefix(userID)
+	return strings.HasPrefix(key, prefix) && len(key) > len(prefix) && !strings.Contains(key, "..")
+}
+
+// ViewImage consumes a view-once image and returns a short-lived link to it
+// The first call wins; the object is removed later by the view-once sweep job
+func (s *Service) ViewImage(ctx context.Context, userID, matchID, messageID uuid.UUID) (*ViewOnceLink, error) {
+	if s.mediaStore == nil {
+		return nil, ErrMediaStoreUnavailable
+	}
+
+	msg, err := s.getMatchMessage(ctx, userID, matchID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !msg.ViewOnce || msg.UnsentAt != nil {
+		return nil, ErrMessageNotFound
+	}
+	if msg.SenderID == userID {
+		return nil, ErrNotRecipient
+	}
+	if msg.ViewedAt != nil {
+		return nil, ErrAlreadyViewed
+	}
+	if msg.PrivateObject == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	// Sign the link before consuming the image, so a storage error doesn't burn the view
+	issuedAt := time.Now()
+	url, err := s.mediaStore.GetPresignedURL(ctx, *msg.PrivateObject, ViewOnceLinkExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	// ErrAlreadyViewed if another device won the race or the image was unsent meanwhile
+	if err := s.repo.MarkViewed(ctx, messageID); err != nil {
+		return nil, err
+	}
+	viewedAt := time.Now()
+
+	if s.hub != nil {
+		s.hub.SendToUser(msg.SenderID, WSMessage{
+			Type: EventMessageViewed,
+			Payload: MessageViewedPayload{
+				MatchID:   matchID,
+				MessageID: messageID,
+				ViewedAt:  viewedAt,
+			},
+		})
+	}
+
+	return &ViewOnceLink{URL: url, ExpiresAt: issuedAt.Add(ViewOnceLinkExpiry)}, nil
+}
@@ -0,0 +1,108 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// viewOnceRepo serves one view-once message and records MarkViewed calls
+type viewOnceRepo struct {
+	Repository
+	msg       *Message
+	markErr   error
+	markCalls int
+}
+
+func (r *viewOnceRepo) GetByID(ctx context.Context, msgID uuid.UUID) (*Message, error) {
+	if msgID != r.msg.ID {
+		return nil, ErrMessageNotFound
+	}
+	copied := *r.msg
+	return &copied, nil
+}
+
+func (r *viewOnceRepo) MarkViewed(ctx context.Context, msgID uuid.UUID) error {
+	r.markCalls++
+	return r.markErr
+}
+
+type fakeMediaStore struct {
+	err error
+}
+
+func (m *fakeMediaStore) GetPresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	return "https://private.example.com/" + objectName + "?sig=1", nil
+}
+
+func TestViewImage(t *testing.T) {
+	errStorage := errors.New("storage unavailable")
+	errDB := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		presignErr error
+		markErr    error
+		wantErr    error
+		wantMarked bool
+		wantEvent  bool
+	}{
+		{"first view", nil, nil, nil, true, true},
+		{"presign fails before the view is used", errStorage, nil, errStorage, false, false},
+		{"another device viewed it first", nil, ErrAlreadyViewed, ErrAlreadyViewed, true, false},
+		{"database error is not a used view", nil, errDB, errDB, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := &matchPair{matchID: uuid.New(), userA: uuid.New(), userB: uuid.New()}
+			key := ViewOnceKeyPrefix(matches.userB) + "photo.jpg"
+			repo := &viewOnceRepo{
+				msg:     &Message{ID: uuid.New(), MatchID: matches.matchID, SenderID: matches.userB, ViewOnce: true, PrivateObject: &key},
+				markErr: tt.markErr,
+			}
+			hub := &recordingHub{sent: make(map[uuid.UUID][]WSMessage)}
+			svc := NewService(repo, matches, hub)
+			svc.SetMediaStore(&fakeMediaStore{err: tt.presignErr})
+
+			link, err := svc.ViewImage(context.Background(), matches.userA, matches.matchID, repo.msg.ID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				if !errors.Is(tt.wantErr, ErrAlreadyViewed) {
+					assert.NotErrorIs(t, err, ErrAlreadyViewed)
+				}
+			} else {
+				require.NoError(t, err)
+				assert.Contains(t, link.URL, key)
+			}
+			assert.Equal(t, tt.wantMarked, repo.markCalls == 1)
+			assert.Equal(t, tt.wantEvent, len(hub.sent[matches.userB]) == 1)
+		})
+	}
+}
+
+func TestViewImage_OnlyRecipientOnce(t *testing.T) {
+	matches := &matchPair{matchID: uuid.New(), userA: uuid.New(), userB: uuid.New()}
+	key := ViewOnceKeyPrefix(matches.userB) + "photo.jpg"
+	viewedAt := time.Now()
+	repo := &viewOnceRepo{msg: &Message{ID: uuid.New(), MatchID: matches.matchID, SenderID: matches.userB, ViewOnce: true, PrivateObject: &key}}
+	svc := NewService(repo, matches, nil)
+	svc.SetMediaStore(&fakeMediaStore{})
+	ctx := context.Background()
+
+	_, err := svc.ViewImage(ctx, matches.userB, matches.matchID, repo.msg.ID)
+	assert.ErrorIs(t, err, ErrNotRecipient)
+
+	repo.msg.ViewedAt = &viewedAt
+	_, err = svc.ViewImage(ctx, matches.userA, matches.matchID, repo.msg.ID)
+	assert.ErrorIs(t, err, ErrAlreadyViewed)
+	assert.Zero(t, repo.markCalls)
+}
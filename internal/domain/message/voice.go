@@ -0,0 +1,65 @@
+package message
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidVoiceNote is returned when voice note metadata is missing or out of range
+	ErrInvalidVoiceNote = errors.New("voice note needs a url, a duration up to 5 minutes and a waveform of at most 256 samples in 0-255")
+	// ErrInvalidVoiceNoteURL is returned when a voice note wasn't uploaded by its sender
+	ErrInvalidVoiceNoteURL = errors.New("voice note url must come from your own voice upload")
+)
+
+const (
+	// MaxVoiceNoteDuration is the longest voice note that can be sent
+	MaxVoiceNoteDuration = 5 * time.Minute
+
+	// MaxWaveformSamples bounds the waveform stored with each voice note
+	MaxWaveformSamples = 256
+)
+
+// AudioStore maps public media URLs back to their object keys
+type AudioStore interface {
+	ObjectName(url string) string // "" if the URL isn't in our bucket
+}
+
+// SetAudioStore sets the storage used to check voice note URLs
+func (s *Service) SetAudioStore(as AudioStore) {
+	s.audioStore = as
+}
+
+// VoiceKeyPrefix is where a user's voice note uploads are stored in the public bucket
+func VoiceKeyPrefix(userID uuid.UUID) string {
+	return "voice/" + userID.String() + "/"
+}
+
+// validateVoiceNote checks a voice note's metadata and that its audio is one of the
+// sender's own uploads
+func (s *Service) validateVoiceNote(userID uuid.UUID, v *VoiceNote) error {
+	if v.URL == "" || v.DurationMs <= 0 || v.DurationMs > int(MaxVoiceNoteDuration/time.Millisecond) {
+		return ErrInvalidVoiceNote
+	}
+	if len(v.Waveform) > MaxWaveformSamples {
+		return ErrInvalidVoiceNote
+	}
+	for _, sample := range v.Waveform {
+		if sample < 0 || sample > 255 {
+			return ErrInvalidVoiceNote
+		}
+	}
+
+	if s.audioStore == nil {
+		return ErrInvalidVoiceNoteURL
+	}
+	key := s.audioStore.ObjectName(v.URL)
+	prefix := VoiceKeyPrefix(userID)
+	if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) || strings.Contains(key, "..") {
+		return ErrInvalidVoiceNoteURL
+	}
+	return nil
+}
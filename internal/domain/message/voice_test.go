@@ -0,0 +1,55 @@
+package message
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// bucketURLs maps URLs under one public base to object keys, like the S3 client
+type bucketURLs string
+
+func (b bucketURLs) ObjectName(url string) string {
+	if !strings.HasPrefix(url, string(b)) {
+		return ""
+	}
+	return strings.TrimPrefix(url, string(b))
+}
+
+func TestValidateVoiceNote_URL(t *testing.T) {
+	const base = "https://media.example.com/"
+	userID := uuid.New()
+	own := base + VoiceKeyPrefix(userID)
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{"own upload", own + "a.m4a", nil},
+		{"another user's upload", base + VoiceKeyPrefix(uuid.New()) + "a.m4a", ErrInvalidVoiceNoteURL},
+		{"a photo in our bucket", base + userID.String() + "/a.jpg", ErrInvalidVoiceNoteURL},
+		{"outside our bucket", "https://evil.example.com/" + VoiceKeyPrefix(userID) + "a.m4a", ErrInvalidVoiceNoteURL},
+		{"prefix only", own, ErrInvalidVoiceNoteURL},
+		{"path traversal", own + "../other/a.m4a", ErrInvalidVoiceNoteURL},
+		{"missing", "", ErrInvalidVoiceNote},
+	}
+
+	svc := &Service{audioStore: bucketURLs(base)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.validateVoiceNote(userID, &VoiceNote{URL: tt.url, DurationMs: 1500, Waveform: []int16{0, 128, 255}})
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	// Without storage there are no uploads to point at
+	err := (&Service{}).validateVoiceNote(userID, &VoiceNote{URL: own + "a.m4a", DurationMs: 1500})
+	assert.ErrorIs(t, err, ErrInvalidVoiceNoteURL)
+}
@@ -0,0 +1,70 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ContactRepository looks up where to email or text a user
+// phone is empty unless the user has verified it
+type ContactRepository interface {
+	GetContact(ctx context.Context, userID uuid.UUID) (email, phone string, err error)
+}
+
+// EmailSender sends a notification email
+type EmailSender interface {
+	SendNotificationEmail(ctx context.Context, toEmail, title, body string) error
+}
+
+// EmailChannel emails notifications to the user's account address
+type EmailChannel struct {
+	sender   EmailSender
+	contacts ContactRepository
+}
+
+func NewEmailChannel(sender EmailSender, contacts ContactRepository) *EmailChannel {
+	return &EmailChannel{sender: sender, contacts: contacts}
+}
+
+func (c *EmailChannel) Name() string { return ChannelEmail }
+
+func (c *EmailChannel) Deliver(ctx context.Context, msg *PushMessage) error {
+	email, _, err := c.contacts.GetContact(ctx, msg.UserID)
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return ErrNoRecipient
+	}
+	return c.sender.SendNotificationEmail(ctx, email, msg.Title, msg.Body)
+}
+
+// TextSender sends an SMS
+type TextSender interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// SMSChannel texts notifications to the user's verified phone number
+type SMSChannel struct {
+	sender   TextSender
+	contacts ContactRepository
+}
+
+func NewSMSChannel(sender TextSender, contacts ContactRepository) *SMSChannel {
+	return &SMSChannel{sender: sender, contacts: contacts}
+}
+
+func (c *SMSChannel) Name() string { return ChannelSMS }
+
+func (c *SMSChannel) Deliver(ctx context.Context, msg *PushMessage) error {
+	_, phone, err := c.contacts.GetContact(ctx, msg.UserID)
+	if err != nil {
+		return err
+	}
+	if phone == "" {
+		return ErrNoRecipient
+	}
+	return c.sender.Send(ctx, phone, fmt.Sprintf("Feels: %s - %s", msg.Title, msg.Body))
+}
@@ -0,0 +1,468 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoRecipient is returned by a channel when the user can't be reached on it,
+// e.g. no push token or no verified phone number
+var ErrNoRecipient = errors.New("no recipient for channel")
+
+// Channel delivers a notification to a user over one medium
+type Channel interface {
+	Name() string
+	Deliver(ctx context.Context, msg *PushMessage) error
+}
+
+// DeliveryRepository stores the delivery log and notifications held for later
+type DeliveryRepository interface {
+	LogDeliveries(ctx context.Context, deliveries []Delivery) error
+	GetDeliveries(ctx context.Context, userID uuid.UUID, limit int) ([]Delivery, error)
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// QueuePending adds to the user's pending notification for p.CollapseKey, creating
+	// it if there is none, and reports whether it was created. An existing row keeps its
+	// DeliverAfter, takes p's title, body and data, and has its count raised by p.Count
+	// (at least 1).
+	QueuePending(ctx context.Context, p *PendingNotification) (created bool, err error)
+
+	// ClaimDuePending leases and returns pending notifications due by now; leased rows
+	// aren't returned again until the lease runs out
+	ClaimDuePending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]PendingNotification, error)
+
+	// CompletePending deletes a claimed notification once it has been sent. If more
+	// were queued onto it while it was claimed, it instead takes off the p.Count that
+	// were sent and releases the row so the rest go out on the next flush.
+	CompletePending(ctx context.Context, p *PendingNotification) error
+
+	// DeferPending releases a claimed notification to be delivered at deliverAfter
+	DeferPending(ctx context.Context, id uuid.UUID, deliverAfter time.Time) error
+}
+
+const (
+	// BatchWindow is how long after a batchable notification further ones of the same
+	// kind are collapsed into a single summary
+	BatchWindow = 5 * time.Minute
+
+	// DeliveryLogRetention is how long delivery log entries are kept
+	DeliveryLogRetention = 30 * 24 * time.Hour
+
+	flushBatch = 500
+
+	// flushLease is how long a flush holds the notifications it claimed; ones it didn't
+	// finish, e.g. because the process died, are claimed again after it
+	flushLease = 5 * time.Minute
+)
+
+// defaultChannels are used for notification types without a route of their own
+var defaultChannels = []string{ChannelPush, ChannelInApp}
+
+// defaultRoutes reach further for safety check-ins, which shouldn't go unseen, and keep
+// messages out of the inbox since the conversation list has its own unread counts
+var defaultRoutes = map[NotificationType][]string{
+	NotificationTypeNewMessage:    {ChannelPush},
+	NotificationTypeSafetyCheckIn: {ChannelPush, ChannelInApp, ChannelSMS, ChannelEmail},
+}
+
+// urgentTypes skip quiet hours and batching
+var urgentTypes = map[NotificationType]bool{
+	NotificationTypeSafetyCheckIn: true,
+}
+
+// Dispatcher routes notifications to channels, applying the user's settings,
+// quiet hours and burst batching, and records every delivery
+//
+// The in-app channel is always delivered immediately; the others (push, email, SMS)
+// interrupt the user, so they are held during quiet hours and batched. Batching sends
+// the first notification of a burst right away and folds the rest of the burst, up
+// to BatchWindow later, into one summary ("you have 10 new likes").
+type Dispatcher struct {
+	settingsRepo SettingsRepository
+	deliveries   DeliveryRepository
+	channels     map[string]Channel
+	routes       map[NotificationType][]string
+}
+
+func NewDispatcher(settingsRepo SettingsRepository) *Dispatcher {
+	routes := make(map[NotificationType][]string, len(defaultRoutes))
+	for t, channels := range defaultRoutes {
+		routes[t] = channels
+	}
+	return &Dispatcher{
+		settingsRepo: settingsRepo,
+		channels:     make(map[string]Channel),
+		routes:       routes,
+	}
+}
+
+// SetDeliveryRepository enables the delivery log, quiet hours and batching
+// Without it every notification is delivered immediately
+func (d *Dispatcher) SetDeliveryRepository(repo DeliveryRepository) {
+	d.deliveries = repo
+}
+
+// AddChannel registers a channel, replacing any with the same name
+func (d *Dispatcher) AddChannel(ch Channel) {
+	d.channels[ch.Name()] = ch
+}
+
+// SetRoute sets the channels a notification type is sent on
+func (d *Dispatcher) SetRoute(t NotificationType, channels ...string) {
+	d.routes[t] = channels
+}
+
+func (d *Dispatcher) route(t NotificationType) []string {
+	if channels, ok := d.routes[t]; ok {
+		return channels
+	}
+	return defaultChannels
+}
+
+// Dispatch sends a notification on each of its channels that the user allows
+// It returns an error only when every attempted delivery failed
+func (d *Dispatcher) Dispatch(ctx context.Context, msg *PushMessage) error {
+	prefs := d.preferences(ctx, msg.UserID)
+	now := time.Now()
+
+	var logged []Delivery
+	var immediate, interruptive []string
+	for _, name := range d.route(msg.Type) {
+		if _, ok := d.channels[name]; !ok {
+			continue
+		}
+		if !prefs.allows(name, msg.Type) {
+			logged = append(logged, newDelivery(msg, name, DeliveryStatusSuppressed, "", nil))
+			continue
+		}
+		if name == ChannelInApp || urgentTypes[msg.Type] || d.deliveries == nil {
+			immediate = append(immediate, name)
+		} else {
+			interruptive = append(interruptive, name)
+		}
+	}
+
+	if len(interruptive) > 0 {
+		ok, status, key := d.hold(ctx, msg, prefs, now)
+		if ok {
+			for _, name := range interruptive {
+				logged = append(logged, newDelivery(msg, name, status, key, nil))
+			}
+		} else {
+			immediate = append(immediate, interruptive...)
+		}
+	}
+
+	entries, err := d.deliver(ctx, msg, immediate, "")
+	d.record(ctx, append(logged, entries...))
+	return err
+}
+
+// hold queues the interruptive part of a notification when the user is in quiet hours
+// or it belongs to a burst already being batched. It reports whether it was held.
+func (d *Dispatcher) hold(ctx context.Context, msg *PushMessage, prefs *Preferences, now time.Time) (bool, string, string) {
+	key := collapseKey(msg)
+	quietUntil, quiet := prefs.quietUntil(now)
+	if key == "" && !quiet {
+		return false, "", ""
+	}
+
+	p := &PendingNotification{
+		ID:          uuid.New(),
+		UserID:      msg.UserID,
+		Type:        msg.Type,
+		CollapseKey: key,
+		Title:       msg.Title,
+		Body:        msg.Body,
+		Data:        msg.Data,
+		CreatedAt:   now,
+	}
+	status := DeliveryStatusBatched
+	if quiet {
+		// Held whole until morning; non-batchable ones get a key of their own
+		status = DeliveryStatusDeferred
+		if p.CollapseKey == "" {
+			p.CollapseKey = fmt.Sprintf("%s:%s", msg.Type, p.ID)
+		}
+		p.Count, p.DeliverAfter = 1, quietUntil
+	} else {
+		// Opens a batching window; this first one goes out now
+		p.Count, p.DeliverAfter = 0, now.Add(BatchWindow)
+	}
+
+	created, err := d.deliveries.QueuePending(ctx, p)
+	if err != nil {
+		log.Printf("[NOTIFY] Failed to queue %s for %s, sending now: %v", msg.Type, msg.UserID, err)
+		return false, "", ""
+	}
+	if created && !quiet {
+		return false, "", ""
+	}
+	return true, status, p.CollapseKey
+}
+
+// FlushPending delivers held notifications that have come due, collapsing each
+// batch into a summary. It returns the number of notifications delivered.
+//
+// Each notification is removed only after it was sent, so a flush that dies part
+// way through sends the rest once their lease runs out; the ones it had already
+// sent but not removed may go out twice.
+func (d *Dispatcher) FlushPending(ctx context.Context, now time.Time) (int, error) {
+	if d.deliveries == nil {
+		return 0, nil
+	}
+
+	due, err := d.deliveries.ClaimDuePending(ctx, now, flushLease, flushBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range due {
+		p := &due[i]
+		if p.Count == 0 {
+			d.complete(ctx, p) // batching window closed with nothing more to say
+			continue
+		}
+
+		prefs := d.preferences(ctx, p.UserID)
+		if until, quiet := prefs.quietUntil(now); quiet && !urgentTypes[p.Type] {
+			if err := d.deliveries.DeferPending(ctx, p.ID, until); err != nil {
+				log.Printf("[NOTIFY] Failed to defer %s for %s: %v", p.CollapseKey, p.UserID, err)
+			}
+			continue
+		}
+
+		msg := collapse(p)
+		var channels []string
+		var suppressed []Delivery
+		for _, name := range d.route(msg.Type) {
+			if _, ok := d.channels[name]; !ok || name == ChannelInApp {
+				continue // in-app was delivered as each notification arrived
+			}
+			if !prefs.allows(name, msg.Type) {
+				suppressed = append(suppressed, newDelivery(msg, name, DeliveryStatusSuppressed, p.CollapseKey, nil))
+				continue
+			}
+			channels = append(channels, name)
+		}
+
+		entries, err := d.deliver(ctx, msg, channels, p.CollapseKey)
+		d.record(ctx, append(suppressed, entries...))
+		d.complete(ctx, p)
+		if err == nil && len(channels) > 0 {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// complete removes a flushed notification; if that fails it is sent again once its
+// lease runs out
+func (d *Dispatcher) complete(ctx context.Context, p *PendingNotification) {
+	if err := d.deliveries.CompletePending(ctx, p); err != nil {
+		log.Printf("[NOTIFY] Failed to complete %s for %s: %v", p.CollapseKey, p.UserID, err)
+	}
+}
+
+// deliver sends msg on each channel, returning the log entries and an error if
+// every delivery failed
+func (d *Dispatcher) deliver(ctx context.Context, msg *PushMessage, channels []string, key string) ([]Delivery, error) {
+	entries := make([]Delivery, 0, len(channels))
+	var errs []error
+	delivered := false
+	for _, name := range channels {
+		err := d.channels[name].Deliver(ctx, msg)
+		switch {
+		case err == nil:
+			delivered = true
+			entries = append(entries, newDelivery(msg, name, DeliveryStatusSent, key, nil))
+		case errors.Is(err, ErrNoRecipient):
+			entries = append(entries, newDelivery(msg, name, DeliveryStatusSkipped, key, nil))
+		default:
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			entries = append(entries, newDelivery(msg, name, DeliveryStatusFailed, key, err))
+		}
+	}
+	if delivered || len(errs) == 0 {
+		return entries, nil
+	}
+	return entries, errors.Join(errs...)
+}
+
+func (d *Dispatcher) record(ctx context.Context, entries []Delivery) {
+	if d.deliveries == nil || len(entries) == 0 {
+		return
+	}
+	if err := d.deliveries.LogDeliveries(ctx, entries); err != nil {
+		log.Printf("[NOTIFY] Failed to record %d deliveries: %v", len(entries), err)
+	}
+}
+
+// preferences loads the user's settings, falling back to the defaults so a
+// settings lookup failure doesn't drop notifications
+func (d *Dispatcher) preferences(ctx context.Context, userID uuid.UUID) *Preferences {
+	if d.settingsRepo != nil {
+		prefs, err := d.settingsRepo.GetPreferences(ctx, userID)
+		if err == nil && prefs != nil {
+			return prefs
+		}
+		if err != nil {
+			log.Printf("[NOTIFY] Failed to load settings for %s: %v", userID, err)
+		}
+	}
+	return DefaultPreferences()
+}
+
+// GetDeliveries returns a user's most recent deliveries
+func (d *Dispatcher) GetDeliveries(ctx context.Context, userID uuid.UUID, limit int) ([]Delivery, error) {
+	if d.deliveries == nil {
+		return []Delivery{}, nil
+	}
+	deliveries, err := d.deliveries.GetDeliveries(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []Delivery{}
+	}
+	return deliveries, nil
+}
+
+// PruneDeliveryLog deletes delivery log entries older than DeliveryLogRetention
+func (d *Dispatcher) PruneDeliveryLog(ctx context.Context, now time.Time) (int64, error) {
+	if d.deliveries == nil {
+		return 0, nil
+	}
+	return d.deliveries.DeleteDeliveriesBefore(ctx, now.Add(-DeliveryLogRetention))
+}
+
+func newDelivery(msg *PushMessage, channel, status, key string, err error) Delivery {
+	dl := Delivery{
+		ID:          uuid.New(),
+		UserID:      msg.UserID,
+		Type:        msg.Type,
+		Channel:     channel,
+		Status:      status,
+		CollapseKey: key,
+		CreatedAt:   time.Now(),
+	}
+	if err != nil {
+		dl.Error = err.Error()
+	}
+	return dl
+}
+
+// allows reports whether the user wants this type of notification on the channel
+func (p *Preferences) allows(channel string, t NotificationType) bool {
+	if channel == ChannelInApp || urgentTypes[t] {
+		return true
+	}
+	if channel == ChannelPush && !p.PushEnabled {
+		return false
+	}
+	switch t {
+	case NotificationTypeNewMatch:
+		return p.NewMatches
+	case NotificationTypeNewMessage:
+		return p.NewMessages
+	case NotificationTypeLikeReceived:
+		return p.LikesReceived
+	case NotificationTypeSuperLike:
+		return p.SuperLikes
+	}
+	return true
+}
+
+// quietUntil reports whether now falls in the user's quiet hours and, if so, when they end
+func (p *Preferences) quietUntil(now time.Time) (time.Time, bool) {
+	if !p.QuietHoursEnabled {
+		return time.Time{}, false
+	}
+	start, err := time.Parse("15:04", p.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse("15:04", p.QuietHoursEnd)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	var quiet bool
+	if startMinute < endMinute {
+		quiet = minute >= startMinute && minute < endMinute
+	} else {
+		// Wraps past midnight, e.g. 22:00-08:00
+		quiet = minute >= startMinute || minute < endMinute
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// collapseKey groups notifications that batch together; empty means the type isn't batched
+func collapseKey(msg *PushMessage) string {
+	switch msg.Type {
+	case NotificationTypeLikeReceived, NotificationTypeSuperLike, NotificationTypeNewMatch:
+		return string(msg.Type)
+	case NotificationTypeNewMessage:
+		if matchID, ok := msg.Data["matchId"].(string); ok {
+			return string(msg.Type) + ":" + matchID
+		}
+	}
+	return ""
+}
+
+// collapse turns a pending notification into the message to send, summarising
+// when it stands for more than one
+func collapse(p *PendingNotification) *PushMessage {
+	msg := &PushMessage{
+		UserID: p.UserID,
+		Type:   p.Type,
+		Title:  p.Title,
+		Body:   p.Body,
+		Data:   make(map[string]interface{}, len(p.Data)+1),
+	}
+	for k, v := range p.Data {
+		msg.Data[k] = v
+	}
+	if p.Count <= 1 {
+		return msg
+	}
+
+	msg.Data["count"] = p.Count
+	switch p.Type {
+	case NotificationTypeLikeReceived:
+		msg.Title = "New Likes"
+		msg.Body = fmt.Sprintf("You have %d new likes - see who's interested!", p.Count)
+	case NotificationTypeSuperLike:
+		msg.Title = "Super Likes!"
+		msg.Body = fmt.Sprintf("You have %d new Super Likes", p.Count)
+	case NotificationTypeNewMatch:
+		msg.Title = "New Matches"
+		msg.Body = fmt.Sprintf("You have %d new matches waiting!", p.Count)
+	case NotificationTypeNewMessage:
+		msg.Body = fmt.Sprintf("%d new messages", p.Count)
+	}
+	return msg
+}
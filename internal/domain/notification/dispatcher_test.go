@@ -0,0 +1,250 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferences_QuietUntil(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		start     string
+		end       string
+		timezone  string
+		now       time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"disabled", false, "22:00", "08:00", "UTC", at(10, 23, 0), false, time.Time{}},
+		{"same day window, inside", true, "13:00", "15:00", "UTC", at(10, 14, 30), true, at(10, 15, 0)},
+		{"same day window, at start", true, "13:00", "15:00", "UTC", at(10, 13, 0), true, at(10, 15, 0)},
+		{"same day window, at end", true, "13:00", "15:00", "UTC", at(10, 15, 0), false, time.Time{}},
+		{"same day window, before", true, "13:00", "15:00", "UTC", at(10, 12, 59), false, time.Time{}},
+		{"across midnight, evening", true, "22:00", "08:00", "UTC", at(10, 23, 15), true, at(11, 8, 0)},
+		{"across midnight, at start", true, "22:00", "08:00", "UTC", at(10, 22, 0), true, at(11, 8, 0)},
+		{"across midnight, after midnight", true, "22:00", "08:00", "UTC", at(11, 2, 0), true, at(11, 8, 0)},
+		{"across midnight, at end", true, "22:00", "08:00", "UTC", at(11, 8, 0), false, time.Time{}},
+		{"across midnight, daytime", true, "22:00", "08:00", "UTC", at(11, 12, 0), false, time.Time{}},
+		{"across month end", true, "22:00", "08:00", "UTC", time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC), true, time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC)},
+		{"in the user's time zone", true, "22:00", "08:00", "America/New_York", at(10, 3, 0), true, time.Date(2026, 3, 10, 8, 0, 0, 0, newYork)},
+		{"outside in the user's time zone", true, "22:00", "08:00", "America/New_York", at(10, 23, 0), false, time.Time{}},
+		{"unknown time zone uses UTC", true, "22:00", "08:00", "Mars/Olympus", at(10, 23, 0), true, at(11, 8, 0)},
+		{"malformed start", true, "10pm", "08:00", "UTC", at(10, 23, 0), false, time.Time{}},
+		{"malformed end", true, "22:00", "", "UTC", at(10, 23, 0), false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := &Preferences{QuietHoursEnabled: tt.enabled, QuietHoursStart: tt.start, QuietHoursEnd: tt.end, Timezone: tt.timezone}
+			until, quiet := prefs.quietUntil(tt.now)
+			assert.Equal(t, tt.wantQuiet, quiet)
+			assert.True(t, tt.wantUntil.Equal(until), "until = %v, want %v", until, tt.wantUntil)
+		})
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name      string
+		p         PendingNotification
+		wantTitle string
+		wantBody  string
+		wantCount interface{}
+	}{
+		{
+			"single notification is sent as is",
+			PendingNotification{Type: NotificationTypeLikeReceived, Title: "New Like", Body: "Someone likes you", Count: 1},
+			"New Like", "Someone likes you", nil,
+		},
+		{
+			"likes",
+			PendingNotification{Type: NotificationTypeLikeReceived, Title: "New Like", Body: "Someone likes you", Count: 10},
+			"New Likes", "You have 10 new likes - see who's interested!", 10,
+		},
+		{
+			"super likes",
+			PendingNotification{Type: NotificationTypeSuperLike, Title: "Super Like!", Body: "Someone super liked you", Count: 3},
+			"Super Likes!", "You have 3 new Super Likes", 3,
+		},
+		{
+			"matches",
+			PendingNotification{Type: NotificationTypeNewMatch, Title: "New Match!", Body: "You matched", Count: 2},
+			"New Matches", "You have 2 new matches waiting!", 2,
+		},
+		{
+			"messages keep the sender as title",
+			PendingNotification{Type: NotificationTypeNewMessage, Title: "Sam", Body: "hey", Count: 4, Data: map[string]interface{}{"matchId": "m1"}},
+			"Sam", "4 new messages", 4,
+		},
+		{
+			"other types keep the latest text",
+			PendingNotification{Type: NotificationTypeSafetyCheckIn, Title: "Check in", Body: "Are you ok?", Count: 2},
+			"Check in", "Are you ok?", 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.UserID = userID
+			msg := collapse(&tt.p)
+			assert.Equal(t, userID, msg.UserID)
+			assert.Equal(t, tt.p.Type, msg.Type)
+			assert.Equal(t, tt.wantTitle, msg.Title)
+			assert.Equal(t, tt.wantBody, msg.Body)
+			assert.Equal(t, tt.wantCount, msg.Data["count"])
+			for k, v := range tt.p.Data {
+				assert.Equal(t, v, msg.Data[k])
+			}
+		})
+	}
+}
+
+func TestCollapse_DoesNotChangeThePendingData(t *testing.T) {
+	p := &PendingNotification{Type: NotificationTypeLikeReceived, Count: 5, Data: map[string]interface{}{"screen": "likes"}}
+	collapse(p)
+	assert.Equal(t, map[string]interface{}{"screen": "likes"}, p.Data)
+}
+
+// pendingStore is an in-memory DeliveryRepository for the pending queue
+type pendingStore struct {
+	DeliveryRepository
+	rows    map[uuid.UUID]*PendingNotification
+	claimed map[uuid.UUID]time.Time
+}
+
+func newPendingStore(rows ...PendingNotification) *pendingStore {
+	s := &pendingStore{rows: make(map[uuid.UUID]*PendingNotification), claimed: make(map[uuid.UUID]time.Time)}
+	for i := range rows {
+		s.rows[rows[i].ID] = &rows[i]
+	}
+	return s
+}
+
+func (s *pendingStore) ClaimDuePending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]PendingNotification, error) {
+	var due []PendingNotification
+	for id, p := range s.rows {
+		if p.DeliverAfter.After(now) || s.claimed[id].After(now) {
+			continue
+		}
+		s.claimed[id] = now.Add(lease)
+		due = append(due, *p)
+	}
+	return due, nil
+}
+
+func (s *pendingStore) CompletePending(ctx context.Context, p *PendingNotification) error {
+	if s.rows[p.ID].Count == p.Count {
+		delete(s.rows, p.ID)
+		return nil
+	}
+	s.rows[p.ID].Count -= p.Count
+	delete(s.claimed, p.ID)
+	return nil
+}
+
+func (s *pendingStore) DeferPending(ctx context.Context, id uuid.UUID, deliverAfter time.Time) error {
+	s.rows[id].DeliverAfter = deliverAfter
+	delete(s.claimed, id)
+	return nil
+}
+
+func (s *pendingStore) LogDeliveries(ctx context.Context, deliveries []Delivery) error {
+	return nil
+}
+
+// countingChannel counts deliveries and can queue more onto a pending row mid-flush
+type countingChannel struct {
+	delivered []*PushMessage
+	onDeliver func()
+}
+
+func (c *countingChannel) Name() string { return ChannelPush }
+
+func (c *countingChannel) Deliver(ctx context.Context, msg *PushMessage) error {
+	c.delivered = append(c.delivered, msg)
+	if c.onDeliver != nil {
+		c.onDeliver()
+	}
+	return nil
+}
+
+func newFlushDispatcher(store *pendingStore, ch *countingChannel) *Dispatcher {
+	d := NewDispatcher(nil)
+	d.SetDeliveryRepository(store)
+	d.AddChannel(ch)
+	return d
+}
+
+func TestFlushPending_RemovesOnlyDeliveredNotifications(t *testing.T) {
+	now := time.Now()
+	p := PendingNotification{ID: uuid.New(), UserID: uuid.New(), Type: NotificationTypeLikeReceived, CollapseKey: "like_received", Count: 3, DeliverAfter: now.Add(-time.Second)}
+	store := newPendingStore(p)
+	ch := &countingChannel{onDeliver: func() {
+		// another like arrives while the summary is being sent
+		store.rows[p.ID].Count++
+	}}
+
+	sent, err := newFlushDispatcher(store, ch).FlushPending(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, ch.delivered, 1)
+	assert.Equal(t, 3, ch.delivered[0].Data["count"])
+
+	// The like that arrived mid-flush is still held and goes out next time
+	require.Contains(t, store.rows, p.ID)
+	assert.Equal(t, 1, store.rows[p.ID].Count)
+	ch.onDeliver = nil
+	sent, err = newFlushDispatcher(store, ch).FlushPending(context.Background(), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Empty(t, store.rows)
+}
+
+func TestFlushPending_UnfinishedClaimsAreRetriedAfterTheLease(t *testing.T) {
+	now := time.Now()
+	p := PendingNotification{ID: uuid.New(), UserID: uuid.New(), Type: NotificationTypeNewMatch, CollapseKey: "new_match", Count: 2, DeliverAfter: now.Add(-time.Second)}
+	store := newPendingStore(p)
+
+	// A flush that died after claiming leaves the row in place
+	_, err := store.ClaimDuePending(context.Background(), now, flushLease, flushBatch)
+	require.NoError(t, err)
+
+	ch := &countingChannel{}
+	d := newFlushDispatcher(store, ch)
+	sent, err := d.FlushPending(context.Background(), now.Add(flushLease/2))
+	require.NoError(t, err)
+	assert.Zero(t, sent, "still leased")
+	assert.Contains(t, store.rows, p.ID)
+
+	sent, err = d.FlushPending(context.Background(), now.Add(flushLease))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Empty(t, store.rows)
+}
+
+func TestFlushPending_ClosedBatchWindowIsRemovedWithoutSending(t *testing.T) {
+	now := time.Now()
+	p := PendingNotification{ID: uuid.New(), UserID: uuid.New(), Type: NotificationTypeLikeReceived, CollapseKey: "like_received", DeliverAfter: now.Add(-time.Second)}
+	store := newPendingStore(p)
+	ch := &countingChannel{}
+
+	sent, err := newFlushDispatcher(store, ch).FlushPending(context.Background(), now)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Empty(t, ch.delivered)
+	assert.Empty(t, store.rows)
+}
@@ -0,0 +1,393 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Expo push endpoints
+const (
+	DefaultExpoPushURL     = "https://exp.host/--/api/v2/push/send"
+	DefaultExpoReceiptsURL = "https://exp.host/--/api/v2/push/getReceipts"
+)
+
+// Expo error codes we act on
+const (
+	ExpoErrDeviceNotRegistered = "DeviceNotRegistered" // the app was uninstalled or the token expired
+	ExpoErrMessageRateExceeded = "MessageRateExceeded" // too many messages to one device
+)
+
+const (
+	// ReceiptDelay is how long after sending a push its receipt is checked; Expo
+	// recommends waiting around 15 minutes
+	ReceiptDelay = 15 * time.Minute
+
+	// TicketExpiry is when an unanswered ticket is given up on; Expo keeps receipts for a day
+	TicketExpiry = 24 * time.Hour
+
+	// TokenThrottle is how long a token is skipped after a receipt reports MessageRateExceeded
+	TokenThrottle = 15 * time.Minute
+
+	expoBatchSize        = 100  // most messages Expo accepts in one send
+	expoReceiptBatchSize = 1000 // most ticket IDs Expo accepts in one receipts request
+	expoMaxAttempts      = 4
+	expoInitialBackoff   = time.Second
+	receiptPollLimit     = 5000
+)
+
+// ErrExpoRateLimited is returned when Expo keeps answering MessageRateExceeded or HTTP 429
+var ErrExpoRateLimited = errors.New("expo rate limited")
+
+// ExpoErrorDetails carries the machine-readable error code of a ticket or receipt
+type ExpoErrorDetails struct {
+	Error string `json:"error,omitempty"`
+}
+
+// ExpoTicket is Expo's answer for one message sent; ID is set when Status is "ok"
+type ExpoTicket struct {
+	Status  string            `json:"status"`
+	ID      string            `json:"id,omitempty"`
+	Message string            `json:"message,omitempty"`
+	Details *ExpoErrorDetails `json:"details,omitempty"`
+}
+
+// ExpoReceipt is the final delivery result for a ticket
+type ExpoReceipt struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message,omitempty"`
+	Details *ExpoErrorDetails `json:"details,omitempty"`
+}
+
+// PushTicket is a push Expo accepted, kept until its receipt has been checked
+type PushTicket struct {
+	ID        string
+	UserID    uuid.UUID
+	Token     string
+	CreatedAt time.Time
+}
+
+// TicketRepository stores push tickets awaiting receipts
+type TicketRepository interface {
+	SaveTickets(ctx context.Context, tickets []PushTicket) error
+	GetTicketsForReceipts(ctx context.Context, sentBefore time.Time, limit int) ([]PushTicket, error)
+	DeleteTickets(ctx context.Context, ids []string) error
+	DeleteTicketsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ExpoChannel sends push notifications to every device the user registered, keeps
+// the tickets Expo hands back and prunes tokens Expo says are dead
+type ExpoChannel struct {
+	repo        Repository
+	tickets     TicketRepository
+	pushURL     string
+	receiptsURL string
+	httpClient  *http.Client
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+// NewExpoChannel creates the push channel; without ExpoReceiptsURL the receipts
+// endpoint sits beside ExpoPushURL, so one setting points both at a stand-in
+func NewExpoChannel(repo Repository, cfg Config) *ExpoChannel {
+	pushURL, receiptsURL := cfg.ExpoPushURL, cfg.ExpoReceiptsURL
+	if pushURL == "" {
+		pushURL = DefaultExpoPushURL
+	}
+	if receiptsURL == "" {
+		receiptsURL = strings.TrimSuffix(pushURL, "/send") + "/getReceipts"
+	}
+	return &ExpoChannel{
+		repo:        repo,
+		pushURL:     pushURL,
+		receiptsURL: receiptsURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		sleep: sleepContext,
+	}
+}
+
+// SetTicketRepository enables receipt checking; without it tickets are discarded
+func (c *ExpoChannel) SetTicketRepository(tickets TicketRepository) {
+	c.tickets = tickets
+}
+
+func (c *ExpoChannel) Name() string { return ChannelPush }
+
+// Deliver sends to each of the user's tokens, expoBatchSize per request
+// It succeeds if Expo accepted the push for at least one device
+func (c *ExpoChannel) Deliver(ctx context.Context, msg *PushMessage) error {
+	tokens, err := c.repo.GetTokensByUserID(ctx, msg.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get push tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return ErrNoRecipient
+	}
+
+	payloads := make([]PushPayload, len(tokens))
+	for i, token := range tokens {
+		payloads[i] = PushPayload{
+			To:       token.Token,
+			Title:    msg.Title,
+			Body:     msg.Body,
+			Sound:    "default",
+			Priority: "high",
+			Data:     msg.Data,
+		}
+	}
+
+	accepted, dead := 0, 0
+	var lastErr error
+	for start := 0; start < len(payloads); start += expoBatchSize {
+		end := min(start+expoBatchSize, len(payloads))
+		a, d, err := c.sendWithRetry(ctx, msg.UserID, payloads[start:end])
+		accepted += a
+		dead += d
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	switch {
+	case accepted > 0:
+		return nil
+	case dead == len(payloads):
+		return ErrNoRecipient
+	case lastErr != nil:
+		return lastErr
+	}
+	return fmt.Errorf("expo rejected all %d messages", len(payloads))
+}
+
+// sendWithRetry sends one batch, retrying messages Expo rate limits with exponential
+// backoff. It returns how many were accepted and how many tokens were dead.
+func (c *ExpoChannel) sendWithRetry(ctx context.Context, userID uuid.UUID, payloads []PushPayload) (int, int, error) {
+	accepted, dead := 0, 0
+	backoff := expoInitialBackoff
+	var lastErr error
+
+	for attempt := 1; ; attempt++ {
+		var retry []PushPayload
+		tickets, err := c.send(ctx, payloads)
+		switch {
+		case errors.Is(err, ErrExpoRateLimited):
+			retry, lastErr = payloads, err
+		case err != nil:
+			return accepted, dead, err
+		default:
+			var saved []PushTicket
+			for i, t := range tickets {
+				token := payloads[i].To
+				switch {
+				case t.Status == "ok":
+					accepted++
+					saved = append(saved, PushTicket{ID: t.ID, UserID: userID, Token: token, CreatedAt: time.Now()})
+				case t.errorCode() == ExpoErrDeviceNotRegistered:
+					dead++
+					c.pruneToken(ctx, token)
+				case t.errorCode() == ExpoErrMessageRateExceeded:
+					retry = append(retry, payloads[i])
+				default:
+					lastErr = fmt.Errorf("expo rejected push: %s", t.Message)
+					log.Printf("[PUSH] Expo rejected push to %s: %s (%s)", tokenPrefix(token), t.Message, t.errorCode())
+				}
+			}
+			c.saveTickets(ctx, saved)
+			if len(retry) > 0 {
+				lastErr = ErrExpoRateLimited
+			}
+		}
+
+		if len(retry) == 0 || attempt == expoMaxAttempts {
+			if len(retry) == 0 && accepted > 0 {
+				return accepted, dead, nil
+			}
+			return accepted, dead, lastErr
+		}
+		if err := c.sleep(ctx, backoff); err != nil {
+			return accepted, dead, err
+		}
+		backoff *= 2
+		payloads = retry
+	}
+}
+
+// send posts one batch of messages and returns a ticket per message, in order
+func (c *ExpoChannel) send(ctx context.Context, payloads []PushPayload) ([]ExpoTicket, error) {
+	var resp struct {
+		Data []ExpoTicket `json:"data"`
+	}
+	if err := c.post(ctx, c.pushURL, payloads, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(payloads) {
+		return nil, fmt.Errorf("expo returned %d tickets for %d messages", len(resp.Data), len(payloads))
+	}
+	return resp.Data, nil
+}
+
+// PollReceipts checks receipts for tickets sent at least ReceiptDelay ago. Tokens
+// reported DeviceNotRegistered are deleted and rate limited ones throttled; tickets
+// whose receipts aren't ready yet are kept for the next poll, up to TicketExpiry.
+// It returns the number of receipts checked and tokens pruned.
+func (c *ExpoChannel) PollReceipts(ctx context.Context, now time.Time) (int, int, error) {
+	if c.tickets == nil {
+		return 0, 0, nil
+	}
+	if _, err := c.tickets.DeleteTicketsBefore(ctx, now.Add(-TicketExpiry)); err != nil {
+		return 0, 0, err
+	}
+
+	tickets, err := c.tickets.GetTicketsForReceipts(ctx, now.Add(-ReceiptDelay), receiptPollLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	checked := 0
+	pruned := make(map[string]bool)
+	for start := 0; start < len(tickets); start += expoReceiptBatchSize {
+		batch := tickets[start:min(start+expoReceiptBatchSize, len(tickets))]
+		ids := make([]string, len(batch))
+		for i, t := range batch {
+			ids[i] = t.ID
+		}
+
+		receipts, err := c.getReceipts(ctx, ids)
+		if err != nil {
+			// On ErrExpoRateLimited this leaves the rest for the next run
+			return checked, len(pruned), err
+		}
+
+		var done []string
+		for _, t := range batch {
+			r, ok := receipts[t.ID]
+			if !ok {
+				continue
+			}
+			done = append(done, t.ID)
+			checked++
+
+			switch r.errorCode() {
+			case "":
+			case ExpoErrDeviceNotRegistered:
+				if !pruned[t.Token] {
+					c.pruneToken(ctx, t.Token)
+					pruned[t.Token] = true
+				}
+			case ExpoErrMessageRateExceeded:
+				if err := c.repo.ThrottleToken(ctx, t.Token, now.Add(TokenThrottle)); err != nil {
+					log.Printf("[PUSH] Failed to throttle %s: %v", tokenPrefix(t.Token), err)
+				}
+			default:
+				log.Printf("[PUSH] Push to %s failed: %s (%s)", tokenPrefix(t.Token), r.Message, r.errorCode())
+			}
+		}
+		if len(done) > 0 {
+			if err := c.tickets.DeleteTickets(ctx, done); err != nil {
+				return checked, len(pruned), err
+			}
+		}
+	}
+	return checked, len(pruned), nil
+}
+
+func (c *ExpoChannel) getReceipts(ctx context.Context, ids []string) (map[string]ExpoReceipt, error) {
+	var resp struct {
+		Data map[string]ExpoReceipt `json:"data"`
+	}
+	if err := c.post(ctx, c.receiptsURL, map[string][]string{"ids": ids}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (c *ExpoChannel) post(ctx context.Context, url string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return ErrExpoRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("expo returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode expo response: %w", err)
+	}
+	return nil
+}
+
+func (c *ExpoChannel) saveTickets(ctx context.Context, tickets []PushTicket) {
+	if c.tickets == nil || len(tickets) == 0 {
+		return
+	}
+	if err := c.tickets.SaveTickets(ctx, tickets); err != nil {
+		log.Printf("[PUSH] Failed to save %d tickets: %v", len(tickets), err)
+	}
+}
+
+func (c *ExpoChannel) pruneToken(ctx context.Context, token string) {
+	if err := c.repo.DeleteToken(ctx, token); err != nil {
+		log.Printf("[PUSH] Failed to delete dead token %s: %v", tokenPrefix(token), err)
+		return
+	}
+	log.Printf("[PUSH] Deleted unregistered token %s", tokenPrefix(token))
+}
+
+func (t ExpoTicket) errorCode() string {
+	if t.Details == nil {
+		return ""
+	}
+	return t.Details.Error
+}
+
+func (r ExpoReceipt) errorCode() string {
+	if r.Details == nil {
+		return ""
+	}
+	return r.Details.Error
+}
+
+// tokenPrefix shortens a token for logs
+func tokenPrefix(token string) string {
+	if len(token) > 20 {
+		return token[:20] + "..."
+	}
+	return token
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,425 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTokens is an in-memory Repository
+type memoryTokens struct {
+	mu        sync.Mutex
+	tokens    map[string]uuid.UUID
+	throttled map[string]time.Time
+}
+
+func newMemoryTokens(userID uuid.UUID, tokens ...string) *memoryTokens {
+	m := &memoryTokens{tokens: make(map[string]uuid.UUID), throttled: make(map[string]time.Time)}
+	for _, t := range tokens {
+		m.tokens[t] = userID
+	}
+	return m
+}
+
+func (m *memoryTokens) SaveToken(ctx context.Context, token *PushToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[token.Token] = token.UserID
+	return nil
+}
+
+func (m *memoryTokens) GetTokensByUserID(ctx context.Context, userID uuid.UUID) ([]PushToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []PushToken
+	for t, uid := range m.tokens {
+		if uid == userID {
+			out = append(out, PushToken{UserID: uid, Token: t})
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryTokens) DeleteToken(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tokens, token)
+	return nil
+}
+
+func (m *memoryTokens) DeleteUserTokens(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for t, uid := range m.tokens {
+		if uid == userID {
+			delete(m.tokens, t)
+		}
+	}
+	return nil
+}
+
+func (m *memoryTokens) ThrottleToken(ctx context.Context, token string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.throttled[token] = until
+	return nil
+}
+
+func (m *memoryTokens) has(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.tokens[token]
+	return ok
+}
+
+// memoryTickets is an in-memory TicketRepository
+type memoryTickets struct {
+	mu      sync.Mutex
+	tickets map[string]PushTicket
+}
+
+func newMemoryTickets() *memoryTickets {
+	return &memoryTickets{tickets: make(map[string]PushTicket)}
+}
+
+func (m *memoryTickets) SaveTickets(ctx context.Context, tickets []PushTicket) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range tickets {
+		m.tickets[t.ID] = t
+	}
+	return nil
+}
+
+func (m *memoryTickets) GetTicketsForReceipts(ctx context.Context, sentBefore time.Time, limit int) ([]PushTicket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []PushTicket
+	for _, t := range m.tickets {
+		if !t.CreatedAt.After(sentBefore) && len(out) < limit {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryTickets) DeleteTickets(ctx context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.tickets, id)
+	}
+	return nil
+}
+
+func (m *memoryTickets) DeleteTicketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, t := range m.tickets {
+		if t.CreatedAt.Before(before) {
+			delete(m.tickets, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryTickets) ids() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for id := range m.tickets {
+		out = append(out, id)
+	}
+	return out
+}
+
+// fakeExpo stands in for Expo's push service
+type fakeExpo struct {
+	mu       sync.Mutex
+	sends    [][]PushPayload
+	send     func(call int, msgs []PushPayload) (int, []ExpoTicket)
+	receipts func(ids []string) (int, map[string]ExpoReceipt)
+}
+
+func (f *fakeExpo) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/--/api/v2/push/send", func(w http.ResponseWriter, r *http.Request) {
+		var msgs []PushPayload
+		if err := json.NewDecoder(r.Body).Decode(&msgs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.sends = append(f.sends, msgs)
+		call := len(f.sends)
+		f.mu.Unlock()
+
+		status, tickets := f.send(call, msgs)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": tickets})
+	})
+	mux.HandleFunc("/--/api/v2/push/getReceipts", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		status, receipts := f.receipts(req.IDs)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": receipts})
+	})
+	return mux
+}
+
+func (f *fakeExpo) sendCalls() [][]PushPayload {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sends
+}
+
+// okTickets accepts every message, using the token as the ticket ID
+func okTickets(msgs []PushPayload) []ExpoTicket {
+	tickets := make([]ExpoTicket, len(msgs))
+	for i, m := range msgs {
+		tickets[i] = ExpoTicket{Status: "ok", ID: "ticket-" + m.To}
+	}
+	return tickets
+}
+
+func expoError(code string) *ExpoErrorDetails {
+	return &ExpoErrorDetails{Error: code}
+}
+
+// newTestExpoChannel starts a fake Expo server and returns a channel pointed at it,
+// recording backoff sleeps instead of waiting
+func newTestExpoChannel(t *testing.T, expo *fakeExpo, repo Repository) (*ExpoChannel, *memoryTickets, *[]time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(expo.handler())
+	t.Cleanup(srv.Close)
+
+	ch := NewExpoChannel(repo, Config{ExpoPushURL: srv.URL + "/--/api/v2/push/send"})
+	tickets := newMemoryTickets()
+	ch.SetTicketRepository(tickets)
+
+	var sleeps []time.Duration
+	ch.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+	return ch, tickets, &sleeps
+}
+
+func TestNewExpoChannel_ReceiptsURL(t *testing.T) {
+	ch := NewExpoChannel(nil, Config{})
+	assert.Equal(t, DefaultExpoPushURL, ch.pushURL)
+	assert.Equal(t, DefaultExpoReceiptsURL, ch.receiptsURL)
+
+	ch = NewExpoChannel(nil, Config{ExpoPushURL: "http://localhost:9000/push/send"})
+	assert.Equal(t, "http://localhost:9000/push/getReceipts", ch.receiptsURL)
+
+	ch = NewExpoChannel(nil, Config{ExpoPushURL: "http://a/send", ExpoReceiptsURL: "http://b/receipts"})
+	assert.Equal(t, "http://b/receipts", ch.receiptsURL)
+}
+
+func TestExpoChannel_Deliver_BatchesAndStoresTickets(t *testing.T) {
+	userID := uuid.New()
+	var tokens []string
+	for i := 0; i < 150; i++ {
+		tokens = append(tokens, fmt.Sprintf("ExponentPushToken[%03d]", i))
+	}
+	repo := newMemoryTokens(userID, tokens...)
+
+	expo := &fakeExpo{send: func(call int, msgs []PushPayload) (int, []ExpoTicket) {
+		return http.StatusOK, okTickets(msgs)
+	}}
+	ch, tickets, _ := newTestExpoChannel(t, expo, repo)
+
+	err := ch.Deliver(context.Background(), &PushMessage{UserID: userID, Title: "Hi", Body: "there"})
+	require.NoError(t, err)
+
+	calls := expo.sendCalls()
+	require.Len(t, calls, 2)
+	assert.Len(t, calls[0], expoBatchSize)
+	assert.Len(t, calls[1], 50)
+	assert.Equal(t, "Hi", calls[0][0].Title)
+	assert.Len(t, tickets.ids(), 150)
+}
+
+func TestExpoChannel_Deliver_NoTokens(t *testing.T) {
+	expo := &fakeExpo{send: func(call int, msgs []PushPayload) (int, []ExpoTicket) {
+		return http.StatusOK, okTickets(msgs)
+	}}
+	ch, _, _ := newTestExpoChannel(t, expo, newMemoryTokens(uuid.New()))
+
+	err := ch.Deliver(context.Background(), &PushMessage{UserID: uuid.New()})
+	assert.ErrorIs(t, err, ErrNoRecipient)
+	assert.Empty(t, expo.sendCalls())
+}
+
+func TestExpoChannel_Deliver_PrunesUnregisteredTokens(t *testing.T) {
+	userID := uuid.New()
+	repo := newMemoryTokens(userID, "live", "dead")
+
+	expo := &fakeExpo{send: func(call int, msgs []PushPayload) (int, []ExpoTicket) {
+		tickets := okTickets(msgs)
+		for i, m := range msgs {
+			if m.To == "dead" {
+				tickets[i] = ExpoTicket{Status: "error", Message: "not registered", Details: expoError(ExpoErrDeviceNotRegistered)}
+			}
+		}
+		return http.StatusOK, tickets
+	}}
+	ch, tickets, _ := newTestExpoChannel(t, expo, repo)
+
+	err := ch.Deliver(context.Background(), &PushMessage{UserID: userID})
+	require.NoError(t, err)
+	assert.True(t, repo.has("live"))
+	assert.False(t, repo.has("dead"))
+	assert.Equal(t, []string{"ticket-live"}, tickets.ids())
+}
+
+func TestExpoChannel_Deliver_AllTokensDead(t *testing.T) {
+	userID := uuid.New()
+	repo := newMemoryTokens(userID, "dead")
+
+	expo := &fakeExpo{send: func(call int, msgs []PushPayload) (int, []ExpoTicket) {
+		return http.StatusOK, []ExpoTicket{{Status: "error", Details: expoError(ExpoErrDeviceNotRegistered)}}
+	}}
+	ch, _, _ := newTestExpoChannel(t, expo, repo)
+
+	err := ch.Deliver(context.Background(), &PushMessage{UserID: userID})
+	assert.ErrorIs(t, err, ErrNoRecipient)
+	assert.False(t, repo.has("dead"))
+}
+
+func TestExpoChannel_Deliver_RetriesRateLimitedMessages(t *testing.T) {
+	userID := uuid.New()
+	repo := newMemoryTokens(userID, "a", "b")
+
+	// "b" is rate limited on the first two attempts
+	expo := &fakeExpo{send: func(call int, msgs []PushPayload) (int, []ExpoTicket) {
+		tickets := okTickets(msgs)
+		for i, m := range msgs {
+			if m.To == "b" && call <= 2 {
+				tickets[i] = ExpoTicket{Status: "error", Details: expoError(ExpoErrMessageRateExceeded)}
+			}
+		}
+		return http.StatusOK, tickets
+	}}
+	ch, tickets, sleeps := newTestExpoChannel(t, expo, repo)
+
+	err := ch.Deliver(context.Background(), &PushMessage{UserID: userID})
+	require.NoError(t, err)
+
+	calls := expo.sendCalls()
+	require.Len(t, calls, 3)
+	assert.Len(t, calls[0], 2)
+	require.Len(t, calls[1], 1)
+	assert.Equal(t, "b", calls[1][0].To)
+	assert.Equal(t, []time.Duration{expoInitialBackoff, 2 * expoInitialBackoff}, *sleeps)
+	assert.ElementsMatch(t, []string{"ticket-a", "ticket-b"}, tickets.ids())
+}
+
+func TestExpoChannel_Deliver_RetriesHTTP429(t *testing.T) {
+	userID := uuid.New()
+	repo := newMemoryTokens(userID, "a")
+
+	expo := &fakeExpo{send: func(call int, msgs []PushPayload) (int, []ExpoTicket) {
+		if call == 1 {
+			return http.StatusTooManyRequests, nil
+		}
+		return http.StatusOK, okTickets(msgs)
+	}}
+	ch, _, sleeps := newTestExpoChannel(t, expo, repo)
+
+	err := ch.Deliver(context.Background(), &PushMessage{UserID: userID})
+	require.NoError(t, err)
+	assert.Len(t, expo.sendCalls(), 2)
+	assert.Equal(t, []time.Duration{expoInitialBackoff}, *sleeps)
+}
+
+func TestExpoChannel_Deliver_GivesUpWhenRateLimited(t *testing.T) {
+	userID := uuid.New()
+	repo := newMemoryTokens(userID, "a")
+
+	expo := &fakeExpo{send: func(call int, msgs []PushPayload) (int, []ExpoTicket) {
+		return http.StatusTooManyRequests, nil
+	}}
+	ch, _, sleeps := newTestExpoChannel(t, expo, repo)
+
+	err := ch.Deliver(context.Background(), &PushMessage{UserID: userID})
+	assert.ErrorIs(t, err, ErrExpoRateLimited)
+	assert.Len(t, expo.sendCalls(), expoMaxAttempts)
+	assert.Len(t, *sleeps, expoMaxAttempts-1)
+	assert.True(t, repo.has("a"))
+}
+
+func TestExpoChannel_PollReceipts(t *testing.T) {
+	userID := uuid.New()
+	repo := newMemoryTokens(userID, "ok", "dead", "busy", "pending")
+	now := time.Now()
+
+	expo := &fakeExpo{receipts: func(ids []string) (int, map[string]ExpoReceipt) {
+		return http.StatusOK, map[string]ExpoReceipt{
+			"t-ok":   {Status: "ok"},
+			"t-dead": {Status: "error", Details: expoError(ExpoErrDeviceNotRegistered)},
+			"t-busy": {Status: "error", Details: expoError(ExpoErrMessageRateExceeded)},
+			// t-pending has no receipt yet
+		}
+	}}
+	ch, tickets, _ := newTestExpoChannel(t, expo, repo)
+
+	sent := now.Add(-ReceiptDelay - time.Minute)
+	require.NoError(t, tickets.SaveTickets(context.Background(), []PushTicket{
+		{ID: "t-ok", UserID: userID, Token: "ok", CreatedAt: sent},
+		{ID: "t-dead", UserID: userID, Token: "dead", CreatedAt: sent},
+		{ID: "t-busy", UserID: userID, Token: "busy", CreatedAt: sent},
+		{ID: "t-pending", UserID: userID, Token: "pending", CreatedAt: sent},
+		{ID: "t-recent", UserID: userID, Token: "ok", CreatedAt: now},
+		{ID: "t-expired", UserID: userID, Token: "ok", CreatedAt: now.Add(-TicketExpiry - time.Hour)},
+	}))
+
+	checked, pruned, err := ch.PollReceipts(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, checked)
+	assert.Equal(t, 1, pruned)
+
+	assert.False(t, repo.has("dead"))
+	assert.True(t, repo.has("ok"))
+	assert.Equal(t, now.Add(TokenThrottle), repo.throttled["busy"])
+	assert.ElementsMatch(t, []string{"t-pending", "t-recent"}, tickets.ids())
+}
+
+func TestExpoChannel_PollReceipts_BacksOffWhenRateLimited(t *testing.T) {
+	userID := uuid.New()
+	repo := newMemoryTokens(userID, "dead")
+	now := time.Now()
+
+	expo := &fakeExpo{receipts: func(ids []string) (int, map[string]ExpoReceipt) {
+		return http.StatusTooManyRequests, nil
+	}}
+	ch, tickets, _ := newTestExpoChannel(t, expo, repo)
+	require.NoError(t, tickets.SaveTickets(context.Background(), []PushTicket{
+		{ID: "t-dead", UserID: userID, Token: "dead", CreatedAt: now.Add(-ReceiptDelay)},
+	}))
+
+	checked, pruned, err := ch.PollReceipts(context.Background(), now)
+	assert.ErrorIs(t, err, ErrExpoRateLimited)
+	assert.Zero(t, checked)
+	assert.Zero(t, pruned)
+	assert.True(t, repo.has("dead"))
+	assert.Equal(t, []string{"t-dead"}, tickets.ids())
+}
@@ -0,0 +1,198 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebSocket event types for the inbox
+const (
+	EventNotificationCreated = "notification_created"
+	EventNotificationsRead   = "notifications_read"
+)
+
+const (
+	// InboxRetention is how long inbox items are kept
+	InboxRetention = 90 * 24 * time.Hour
+
+	maxInboxPage   = 100
+	maxMarkReadIDs = 500
+)
+
+var (
+	ErrTooManyIDs = errors.New("too many notification ids")
+	// ErrUnknownCursor is returned for a before ID that isn't one of the user's
+	// notifications, e.g. one that has since been pruned
+	ErrUnknownCursor = errors.New("unknown notification cursor")
+)
+
+// InboxRepository stores the in-app notification inbox
+type InboxRepository interface {
+	CreateNotification(ctx context.Context, n *Notification) error
+	// GetNotifications returns up to limit notifications, newest first, older than
+	// the before notification when it is set; ErrUnknownCursor if the user has no
+	// notification with that ID
+	GetNotifications(ctx context.Context, userID uuid.UUID, before *uuid.UUID, limit int) ([]Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error)
+	MarkNotificationsRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error)
+	MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteNotificationsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EventSender delivers WebSocket events to a user's connections
+type EventSender interface {
+	SendToUser(userID uuid.UUID, msg interface{})
+}
+
+// WSMessage is a WebSocket message envelope
+type WSMessage struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// NotificationCreatedPayload is the payload of a notification_created event
+type NotificationCreatedPayload struct {
+	Notification Notification `json:"notification"`
+	UnreadCount  int          `json:"unread_count"`
+}
+
+// NotificationsReadPayload is the payload of a notifications_read event, sent so
+// the user's other sessions can update their badge
+type NotificationsReadPayload struct {
+	IDs         []uuid.UUID `json:"ids,omitempty"` // empty when everything was marked read
+	UnreadCount int         `json:"unread_count"`
+}
+
+// InAppChannel stores notifications in the user's inbox and announces them to
+// open app sessions over WebSocket, so nothing is lost when push is off or dismissed
+type InAppChannel struct {
+	inbox  InboxRepository
+	events EventSender
+}
+
+func NewInAppChannel(inbox InboxRepository, events EventSender) *InAppChannel {
+	return &InAppChannel{inbox: inbox, events: events}
+}
+
+func (c *InAppChannel) Name() string { return ChannelInApp }
+
+func (c *InAppChannel) Deliver(ctx context.Context, msg *PushMessage) error {
+	n := &Notification{
+		ID:        uuid.New(),
+		UserID:    msg.UserID,
+		Type:      msg.Type,
+		Title:     msg.Title,
+		Body:      msg.Body,
+		Data:      msg.Data,
+		CreatedAt: time.Now(),
+	}
+	if err := c.inbox.CreateNotification(ctx, n); err != nil {
+		return err
+	}
+
+	if c.events != nil {
+		unread, err := c.inbox.CountUnreadNotifications(ctx, msg.UserID)
+		if err != nil {
+			return err
+		}
+		c.events.SendToUser(msg.UserID, WSMessage{
+			Type:    EventNotificationCreated,
+			Payload: NotificationCreatedPayload{Notification: *n, UnreadCount: unread},
+		})
+	}
+	return nil
+}
+
+// SetInbox enables the in-app inbox, delivering every notification routed to the
+// in-app channel into it
+func (s *Service) SetInbox(inbox InboxRepository, events EventSender) {
+	s.inbox = inbox
+	s.events = events
+	s.dispatcher.AddChannel(NewInAppChannel(inbox, events))
+}
+
+// GetInbox returns a page of the user's notifications, newest first
+func (s *Service) GetInbox(ctx context.Context, userID uuid.UUID, before *uuid.UUID, limit int) (*InboxResponse, error) {
+	if limit <= 0 || limit > maxInboxPage {
+		limit = 30
+	}
+
+	// Fetch one extra to know whether there is another page
+	items, err := s.inbox.GetNotifications(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.inbox.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &InboxResponse{
+		Notifications: items,
+		UnreadCount:   unread,
+		HasMore:       len(items) > limit,
+	}
+	if resp.HasMore {
+		resp.Notifications = items[:limit]
+	}
+	if resp.Notifications == nil {
+		resp.Notifications = []Notification{}
+	}
+	return resp, nil
+}
+
+// GetUnreadCount returns how many of the user's notifications are unread
+func (s *Service) GetUnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	return s.inbox.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkRead marks the given notifications read; IDs that aren't the user's are ignored
+func (s *Service) MarkRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int, error) {
+	if len(ids) > maxMarkReadIDs {
+		return 0, ErrTooManyIDs
+	}
+	if len(ids) == 0 {
+		return s.inbox.CountUnreadNotifications(ctx, userID)
+	}
+
+	marked, err := s.inbox.MarkNotificationsRead(ctx, userID, ids)
+	if err != nil {
+		return 0, err
+	}
+	return s.afterRead(ctx, userID, ids, marked)
+}
+
+// MarkAllRead marks every notification in the user's inbox read
+func (s *Service) MarkAllRead(ctx context.Context, userID uuid.UUID) (int, error) {
+	marked, err := s.inbox.MarkAllNotificationsRead(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return s.afterRead(ctx, userID, nil, marked)
+}
+
+// afterRead returns the new unread count, telling the user's sessions if anything changed
+func (s *Service) afterRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, marked int64) (int, error) {
+	unread, err := s.inbox.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if marked > 0 && s.events != nil {
+		s.events.SendToUser(userID, WSMessage{
+			Type:    EventNotificationsRead,
+			Payload: NotificationsReadPayload{IDs: ids, UnreadCount: unread},
+		})
+	}
+	return unread, nil
+}
+
+// PruneInbox deletes inbox items older than InboxRetention
+func (s *Service) PruneInbox(ctx context.Context, now time.Time) (int64, error) {
+	if s.inbox == nil {
+		return 0, nil
+	}
+	return s.inbox.DeleteNotificationsBefore(ctx, now.Add(-InboxRetention))
+}
@@ -26,8 +26,106 @@ const (
 	NotificationTypeSuperLike          NotificationType = "super_like"
 	NotificationTypeDailyDigest        NotificationType = "daily_digest"
 	NotificationTypeInactivityReminder NotificationType = "inactivity_reminder"
+	NotificationTypeConversationNudge  NotificationType = "conversation_nudge"
+	NotificationTypeDatePlan           NotificationType = "date_plan"
+	NotificationTypeSafetyCheckIn      NotificationType = "safety_check_in"
 )
 
+// Delivery channels
+const (
+	ChannelPush  = "push"   // Expo push notification
+	ChannelInApp = "in_app" // WebSocket event to open app sessions
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Delivery statuses recorded in the delivery log
+const (
+	DeliveryStatusSent       = "sent"
+	DeliveryStatusFailed     = "failed"
+	DeliveryStatusSkipped    = "skipped"    // the user has no address for the channel
+	DeliveryStatusSuppressed = "suppressed" // turned off in the user's settings
+	DeliveryStatusBatched    = "batched"    // folded into a summary sent later
+	DeliveryStatusDeferred   = "deferred"   // held until quiet hours end
+)
+
+// Preferences are the notification settings the dispatcher applies
+type Preferences struct {
+	PushEnabled       bool
+	NewMatches        bool
+	NewMessages       bool
+	LikesReceived     bool
+	SuperLikes        bool
+	QuietHoursEnabled bool
+	QuietHoursStart   string // "HH:MM" local time
+	QuietHoursEnd     string
+	Timezone          string
+}
+
+// DefaultPreferences are used when a user has never saved notification settings
+func DefaultPreferences() *Preferences {
+	return &Preferences{
+		PushEnabled:   true,
+		NewMatches:    true,
+		NewMessages:   true,
+		LikesReceived: true,
+		SuperLikes:    true,
+		Timezone:      "UTC",
+	}
+}
+
+// Notification is an item in a user's in-app notification inbox
+type Notification struct {
+	ID        uuid.UUID              `json:"id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	Type      NotificationType       `json:"type"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	ReadAt    *time.Time             `json:"read_at,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// InboxResponse is a page of the notification inbox, newest first
+type InboxResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	HasMore       bool           `json:"has_more"`
+}
+
+// MarkReadRequest marks notifications in the inbox as read
+type MarkReadRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// Delivery is one attempt to deliver a notification over one channel
+type Delivery struct {
+	ID          uuid.UUID        `json:"id"`
+	UserID      uuid.UUID        `json:"user_id"`
+	Type        NotificationType `json:"type"`
+	Channel     string           `json:"channel"`
+	Status      string           `json:"status"`
+	CollapseKey string           `json:"collapse_key,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// PendingNotification is a notification held back for batching or quiet hours
+// Count is how many notifications it stands for that have not been delivered;
+// a row with Count 0 only marks an open batching window
+type PendingNotification struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Type         NotificationType
+	CollapseKey  string
+	Title        string
+	Body         string
+	Data         map[string]interface{}
+	Count        int
+	DeliverAfter time.Time
+	CreatedAt    time.Time
+}
+
 // PushPayload is the data sent to Expo push service
 type PushPayload struct {
 	To       string                 `json:"to"`
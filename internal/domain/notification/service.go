@@ -1,45 +1,69 @@
 package notification
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-const expoPushURL = "https://exp.host/--/api/v2/push/send"
-
 type Repository interface {
 	SaveToken(ctx context.Context, token *PushToken) error
 	GetTokensByUserID(ctx context.Context, userID uuid.UUID) ([]PushToken, error)
 	DeleteToken(ctx context.Context, token string) error
 	DeleteUserTokens(ctx context.Context, userID uuid.UUID) error
+	// ThrottleToken skips the token in GetTokensByUserID until the given time
+	ThrottleToken(ctx context.Context, token string, until time.Time) error
 }
 
+// SettingsRepository loads the notification settings the dispatcher applies
 type SettingsRepository interface {
-	GetNotificationSettings(ctx context.Context, userID uuid.UUID) (enabled bool, err error)
+	GetPreferences(ctx context.Context, userID uuid.UUID) (*Preferences, error)
+}
+
+// Config configures notification delivery
+type Config struct {
+	ExpoPushURL     string // defaults to DefaultExpoPushURL; tests point it at a local server
+	ExpoReceiptsURL string // defaults to getReceipts beside ExpoPushURL
 }
 
 type Service struct {
-	repo         Repository
-	settingsRepo SettingsRepository
-	httpClient   *http.Client
+	repo       Repository
+	dispatcher *Dispatcher
+	expo       *ExpoChannel
+	inbox      InboxRepository
+	events     EventSender
 }
 
-func NewService(repo Repository, settingsRepo SettingsRepository) *Service {
+// NewService creates the service with push delivery through Expo; further channels
+// are added on the Dispatcher
+func NewService(repo Repository, settingsRepo SettingsRepository, cfg Config) *Service {
+	dispatcher := NewDispatcher(settingsRepo)
+	expo := NewExpoChannel(repo, cfg)
+	dispatcher.AddChannel(expo)
 	return &Service{
-		repo:         repo,
-		settingsRepo: settingsRepo,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		repo:       repo,
+		dispatcher: dispatcher,
+		expo:       expo,
 	}
 }
 
+// SetTicketRepository keeps Expo push tickets so their receipts can be checked
+func (s *Service) SetTicketRepository(tickets TicketRepository) {
+	s.expo.SetTicketRepository(tickets)
+}
+
+// PollReceipts checks Expo receipts and prunes dead push tokens
+func (s *Service) PollReceipts(ctx context.Context, now time.Time) (int, int, error) {
+	return s.expo.PollReceipts(ctx, now)
+}
+
+// Dispatcher returns the dispatcher every notification is sent through
+func (s *Service) Dispatcher() *Dispatcher {
+	return s.dispatcher
+}
+
 // RegisterToken registers a push token for a user
 func (s *Service) RegisterToken(ctx context.Context, userID uuid.UUID, token, platform string) error {
 	pushToken := &PushToken{
@@ -58,74 +82,9 @@ func (s *Service) UnregisterToken(ctx context.Context, token string) error {
 	return s.repo.DeleteToken(ctx, token)
 }
 
-// Send sends a push notification to a user
+// Send sends a notification to a user on each of its channels
 func (s *Service) Send(ctx context.Context, msg *PushMessage) error {
-	// Check user notification settings
-	if s.settingsRepo != nil {
-		enabled, err := s.settingsRepo.GetNotificationSettings(ctx, msg.UserID)
-		if err == nil && !enabled {
-			return nil // User has notifications disabled
-		}
-	}
-
-	// Get user's push tokens
-	tokens, err := s.repo.GetTokensByUserID(ctx, msg.UserID)
-	if err != nil {
-		return fmt.Errorf("failed to get push tokens: %w", err)
-	}
-
-	if len(tokens) == 0 {
-		return nil // No tokens registered
-	}
-
-	// Send to all user's devices
-	for _, token := range tokens {
-		payload := PushPayload{
-			To:       token.Token,
-			Title:    msg.Title,
-			Body:     msg.Body,
-			Sound:    "default",
-			Priority: "high",
-			Data:     msg.Data,
-		}
-
-		if err := s.sendToExpo(ctx, payload); err != nil {
-			// Log error but don't fail - token might be invalid
-			fmt.Printf("Failed to send push to token %s: %v\n", token.Token[:20], err)
-			// TODO: Handle invalid tokens by deleting them
-		}
-	}
-
-	return nil
-}
-
-// sendToExpo sends a push notification via Expo's push service
-func (s *Service) sendToExpo(ctx context.Context, payload PushPayload) error {
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", expoPushURL, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Accept-Encoding", "gzip, deflate")
-
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("expo returned status %d", resp.StatusCode)
-	}
-
-	return nil
+	return s.dispatcher.Dispatch(ctx, msg)
 }
 
 // SendNewMatchNotification sends a notification when a new match occurs
@@ -239,6 +198,72 @@ func (s *Service) SendInactivityReminderNotification(ctx context.Context, userID
 	})
 }
 
+// SendConversationNudgeNotification nudges a user about a stalled match, suggesting
+// one of the other person's prompts as an icebreaker when there is one
+func (s *Service) SendConversationNudgeNotification(ctx context.Context, userID uuid.UUID, otherName, reason, icebreaker string, matchID uuid.UUID) error {
+	var title, body string
+	switch reason {
+	case "no_reply":
+		title = fmt.Sprintf("%s is waiting on you", otherName)
+		body = "Pick up where you left off"
+	default:
+		title = fmt.Sprintf("Say hi to %s", otherName)
+		body = "Don't let your match go quiet"
+	}
+	if icebreaker != "" {
+		body = fmt.Sprintf("Ask them about \"%s\"", icebreaker)
+	}
+
+	return s.Send(ctx, &PushMessage{
+		UserID: userID,
+		Type:   NotificationTypeConversationNudge,
+		Title:  title,
+		Body:   body,
+		Data: map[string]interface{}{
+			"type":    string(NotificationTypeConversationNudge),
+			"matchId": matchID.String(),
+			"reason":  reason,
+		},
+	})
+}
+
+// SendDatePlanNotification tells a user their match proposed, accepted, declined or cancelled a date
+func (s *Service) SendDatePlanNotification(ctx context.Context, userID uuid.UUID, fromName, body string, matchID uuid.UUID) error {
+	return s.Send(ctx, &PushMessage{
+		UserID: userID,
+		Type:   NotificationTypeDatePlan,
+		Title:  fromName,
+		Body:   body,
+		Data: map[string]interface{}{
+			"type":    string(NotificationTypeDatePlan),
+			"matchId": matchID.String(),
+		},
+	})
+}
+
+// SendCheckInNotification reminds a user to check in after a date, or tells them
+// their trusted contact has been alerted because they didn't
+func (s *Service) SendCheckInNotification(ctx context.Context, userID, planID uuid.UUID, escalated bool) error {
+	title := "Time to check in"
+	body := "Let us know you're safe after your date"
+	if escalated {
+		title = "You missed your check-in"
+		body = "We've let your trusted contact know. Check in to tell us you're safe."
+	}
+
+	return s.Send(ctx, &PushMessage{
+		UserID: userID,
+		Type:   NotificationTypeSafetyCheckIn,
+		Title:  title,
+		Body:   body,
+		Data: map[string]interface{}{
+			"type":      string(NotificationTypeSafetyCheckIn),
+			"planId":    planID.String(),
+			"escalated": escalated,
+		},
+	})
+}
+
 func pluralize(n int) string {
 	if n == 1 {
 		return ""
@@ -3,8 +3,10 @@ package payment
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
+	"github.com/feels/feels/internal/domain/mailer"
 	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/checkout/session"
@@ -68,6 +70,11 @@ type UserRepository interface {
 	GetEmail(ctx context.Context, userID uuid.UUID) (string, error)
 }
 
+// ReceiptMailer emails subscription receipts
+type ReceiptMailer interface {
+	SendSubscriptionReceipt(ctx context.Context, userID uuid.UUID, r *mailer.Receipt) (bool, error)
+}
+
 type Config struct {
 	SecretKey         string
 	WebhookSecret     string
@@ -80,6 +87,7 @@ type Service struct {
 	repo     Repository
 	userRepo UserRepository
 	config   Config
+	mailer   ReceiptMailer
 }
 
 func NewService(repo Repository, userRepo UserRepository, config Config) *Service {
@@ -106,6 +114,11 @@ func NewService(repo Repository, userRepo UserRepository, config Config) *Servic
 	}
 }
 
+// SetReceiptMailer sets the mailer for subscription receipts
+func (s *Service) SetReceiptMailer(m ReceiptMailer) {
+	s.mailer = m
+}
+
 // GetPlans returns available subscription plans
 func (s *Service) GetPlans() map[PlanType]Plan {
 	return Plans
@@ -254,6 +267,8 @@ func (s *Service) HandleWebhook(ctx context.Context, event *stripe.Event) error
 		return s.handleSubscriptionUpdated(ctx, event)
 	case "customer.subscription.deleted":
 		return s.handleSubscriptionDeleted(ctx, event)
+	case "invoice.paid":
+		return s.handleInvoicePaid(ctx, event)
 	case "invoice.payment_failed":
 		return s.handlePaymentFailed(ctx, event)
 	}
@@ -296,7 +311,26 @@ func (s *Service) handleCheckoutCompleted(ctx context.Context, event *stripe.Eve
 		UpdatedAt:            time.Now(),
 	}
 
-	return s.repo.SaveSubscription(ctx, newSub)
+	if err := s.repo.SaveSubscription(ctx, newSub); err != nil {
+		return err
+	}
+
+	if s.mailer != nil {
+		sessionID, _ := sess["id"].(string)
+		amountTotal, _ := sess["amount_total"].(float64) // in cents
+		currency, _ := sess["currency"].(string)
+		_, err := s.mailer.SendSubscriptionReceipt(ctx, userID, &mailer.Receipt{
+			ID:          sessionID,
+			Plan:        planType,
+			Amount:      mailer.FormatAmount(amountTotal/100, currency),
+			PurchasedAt: newSub.CurrentPeriodStart,
+			RenewsAt:    newSub.CurrentPeriodEnd,
+		})
+		if err != nil {
+			log.Printf("[PAYMENT] Failed to send receipt for user %s: %v", userID, err)
+		}
+	}
+	return nil
 }
 
 func (s *Service) handleSubscriptionUpdated(ctx context.Context, event *stripe.Event) error {
@@ -317,6 +351,17 @@ func (s *Service) handleSubscriptionUpdated(ctx context.Context, event *stripe.E
 	existing.CurrentPeriodEnd = time.Unix(int64(periodEnd), 0)
 	existing.UpdatedAt = time.Now()
 
+	// Canceling in the billing portal ends the subscription at period end; keep
+	// canceled_at in step so renewal reminders stop
+	if cancelAtPeriodEnd, ok := subData["cancel_at_period_end"].(bool); ok && status != "canceled" {
+		if !cancelAtPeriodEnd {
+			existing.CanceledAt = nil
+		} else if existing.CanceledAt == nil {
+			now := time.Now()
+			existing.CanceledAt = &now
+		}
+	}
+
 	return s.repo.UpdateSubscription(ctx, existing)
 }
 
@@ -337,6 +382,64 @@ func (s *Service) handleSubscriptionDeleted(ctx context.Context, event *stripe.E
 	return s.repo.UpdateSubscription(ctx, existing)
 }
 
+// handleInvoicePaid emails a receipt for each renewal; the first invoice is paid at
+// checkout, which sends its own receipt
+func (s *Service) handleInvoicePaid(ctx context.Context, event *stripe.Event) error {
+	invoice := event.Data.Object
+	subscriptionID, _ := invoice["subscription"].(string)
+	billingReason, _ := invoice["billing_reason"].(string)
+
+	if s.mailer == nil || subscriptionID == "" || billingReason == "subscription_create" {
+		return nil
+	}
+
+	existing, err := s.repo.GetSubscriptionByStripeID(ctx, subscriptionID)
+	if err != nil {
+		return nil // Subscription not in our system
+	}
+
+	invoiceID, _ := invoice["id"].(string)
+	amountPaid, _ := invoice["amount_paid"].(float64) // in cents
+	currency, _ := invoice["currency"].(string)
+	paidAt, renewsAt := invoicePeriod(invoice)
+
+	_, err = s.mailer.SendSubscriptionReceipt(ctx, existing.UserID, &mailer.Receipt{
+		ID:          invoiceID,
+		Plan:        string(existing.PlanType),
+		Amount:      mailer.FormatAmount(amountPaid/100, currency),
+		PurchasedAt: paidAt,
+		RenewsAt:    renewsAt,
+	})
+	if err != nil {
+		log.Printf("[PAYMENT] Failed to send renewal receipt for user %s: %v", existing.UserID, err)
+	}
+	return nil
+}
+
+// invoicePeriod returns when an invoice was paid and when the period it pays for ends
+func invoicePeriod(invoice map[string]interface{}) (time.Time, time.Time) {
+	paidAt := time.Now()
+	if transitions, ok := invoice["status_transitions"].(map[string]interface{}); ok {
+		if ts, ok := transitions["paid_at"].(float64); ok && ts > 0 {
+			paidAt = time.Unix(int64(ts), 0)
+		}
+	}
+
+	var periodEnd time.Time
+	if lines, ok := invoice["lines"].(map[string]interface{}); ok {
+		if data, ok := lines["data"].([]interface{}); ok && len(data) > 0 {
+			if line, ok := data[0].(map[string]interface{}); ok {
+				if period, ok := line["period"].(map[string]interface{}); ok {
+					if end, ok := period["end"].(float64); ok {
+						periodEnd = time.Unix(int64(end), 0)
+					}
+				}
+			}
+		}
+	}
+	return paidAt, periodEnd
+}
+
 func (s *Service) handlePaymentFailed(ctx context.Context, event *stripe.Event) error {
 	invoice := event.Data.Object
 	subscriptionID, _ := invoice["subscription"].(string)
@@ -0,0 +1,32 @@
+package presence
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ConnectionTTL is how long a connection counts as online without a heartbeat
+	// The hub pings every 30s, so this tolerates two missed pongs
+	ConnectionTTL = 90 * time.Second
+
+	// LastActiveDebounce limits how often profiles.last_active is written per user
+	LastActiveDebounce = 5 * time.Minute
+)
+
+// WebSocket event type
+const EventPresence = "presence"
+
+// WSMessage is a WebSocket message envelope
+type WSMessage struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// PresencePayload is sent to a user's matches when they come online or go offline
+type PresencePayload struct {
+	UserID   uuid.UUID  `json:"user_id"`
+	Online   bool       `json:"online"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
@@ -0,0 +1,210 @@
+package presence
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/feels/feels/internal/domain/settings"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	connectionsKeyPrefix = "presence:conns:"
+	touchedKeyPrefix     = "presence:touched:"
+)
+
+// connectScript drops expired connections, adds this one and returns how many were live before
+var connectScript = redis.NewScript(`
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+	local before = redis.call("ZCARD", KEYS[1])
+	redis.call("ZADD", KEYS[1], ARGV[2], ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], ARGV[4])
+	return before
+`)
+
+// disconnectScript removes this connection and returns how many are still live
+var disconnectScript = redis.NewScript(`
+	redis.call("ZREM", KEYS[1], ARGV[2])
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+	return redis.call("ZCARD", KEYS[1])
+`)
+
+// MatchRepository lists who should hear about a user's presence
+type MatchRepository interface {
+	GetMatchedUserIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// ProfileRepository persists last active
+type ProfileRepository interface {
+	UpdateLastActive(ctx context.Context, userID uuid.UUID) error
+}
+
+// PrivacyService provides users' privacy settings (defaults when unset)
+type PrivacyService interface {
+	GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*settings.PrivacySettings, error)
+}
+
+// Hub interface for real-time notifications
+type Hub interface {
+	SendToUser(userID uuid.UUID, msg interface{})
+}
+
+// Service tracks which users have live WebSocket connections across all replicas
+// Each connection is a member of a per-user sorted set scored by its expiry, so
+// connections on a crashed replica age out on their own
+type Service struct {
+	redis          *redis.Client
+	matchRepo      MatchRepository
+	profileRepo    ProfileRepository
+	privacyService PrivacyService
+	hub            Hub
+}
+
+func NewService(redisClient *redis.Client, matchRepo MatchRepository, profileRepo ProfileRepository) *Service {
+	return &Service{
+		redis:       redisClient,
+		matchRepo:   matchRepo,
+		profileRepo: profileRepo,
+	}
+}
+
+// SetHub sets the WebSocket hub used to announce presence changes
+func (s *Service) SetHub(hub Hub) {
+	s.hub = hub
+}
+
+// SetPrivacyService sets the privacy settings provider used to honour hidden online status
+func (s *Service) SetPrivacyService(ps PrivacyService) {
+	s.privacyService = ps
+}
+
+// Connected records a new connection and announces the user if they just came online
+func (s *Service) Connected(userID uuid.UUID, connID string) {
+	ctx := context.Background()
+	now := time.Now()
+
+	before, err := connectScript.Run(ctx, s.redis, []string{connectionsKey(userID)},
+		now.UnixMilli(), now.Add(ConnectionTTL).UnixMilli(), connID, ConnectionTTL.Milliseconds(),
+	).Int()
+	if err != nil {
+		log.Printf("[PRESENCE] Failed to record connection for user %s: %v", userID, err)
+		return
+	}
+
+	s.touch(ctx, userID)
+
+	if before == 0 {
+		s.announce(ctx, userID, PresencePayload{UserID: userID, Online: true})
+	}
+}
+
+// Heartbeat extends a connection's lease and keeps last active fresh
+func (s *Service) Heartbeat(userID uuid.UUID, connID string) {
+	ctx := context.Background()
+	key := connectionsKey(userID)
+
+	pipe := s.redis.Pipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().Add(ConnectionTTL).UnixMilli()), Member: connID})
+	pipe.PExpire(ctx, key, ConnectionTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[PRESENCE] Failed to refresh connection for user %s: %v", userID, err)
+		return
+	}
+
+	s.touch(ctx, userID)
+}
+
+// Disconnected drops a connection and announces the user if it was their last one
+func (s *Service) Disconnected(userID uuid.UUID, connID string) {
+	ctx := context.Background()
+	now := time.Now()
+
+	remaining, err := disconnectScript.Run(ctx, s.redis, []string{connectionsKey(userID)},
+		now.UnixMilli(), connID,
+	).Int()
+	if err != nil {
+		log.Printf("[PRESENCE] Failed to remove connection for user %s: %v", userID, err)
+		return
+	}
+	if remaining > 0 {
+		return
+	}
+
+	// Last seen is exact, not debounced
+	if err := s.profileRepo.UpdateLastActive(ctx, userID); err != nil {
+		log.Printf("[PRESENCE] Failed to update last active for user %s: %v", userID, err)
+	}
+	s.announce(ctx, userID, PresencePayload{UserID: userID, Online: false, LastSeen: &now})
+}
+
+// GetOnline reports which of the given users have a live connection
+func (s *Service) GetOnline(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	online := make(map[uuid.UUID]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return online, nil
+	}
+
+	now := time.Now().UnixMilli()
+	pipe := s.redis.Pipeline()
+	counts := make([]*redis.IntCmd, len(userIDs))
+	for i, id := range userIDs {
+		counts[i] = pipe.ZCount(ctx, connectionsKey(id), formatScore(now), "+inf")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, id := range userIDs {
+		online[id] = counts[i].Val() > 0
+	}
+	return online, nil
+}
+
+// touch writes last active at most once per LastActiveDebounce per user
+func (s *Service) touch(ctx context.Context, userID uuid.UUID) {
+	ok, err := s.redis.SetNX(ctx, touchedKeyPrefix+userID.String(), 1, LastActiveDebounce).Result()
+	if err != nil || !ok {
+		return
+	}
+	if err := s.profileRepo.UpdateLastActive(ctx, userID); err != nil {
+		log.Printf("[PRESENCE] Failed to update last active for user %s: %v", userID, err)
+	}
+}
+
+// announce sends a presence change to the user's matches unless they hide their online status
+func (s *Service) announce(ctx context.Context, userID uuid.UUID, payload PresencePayload) {
+	if s.hub == nil {
+		return
+	}
+
+	if s.privacyService != nil {
+		ps, err := s.privacyService.GetPrivacySettings(ctx, userID)
+		if err != nil || !ps.ShowOnlineStatus {
+			return
+		}
+	}
+
+	matchedIDs, err := s.matchRepo.GetMatchedUserIDs(ctx, userID)
+	if err != nil {
+		log.Printf("[PRESENCE] Failed to get matches for user %s: %v", userID, err)
+		return
+	}
+
+	for _, id := range matchedIDs {
+		s.hub.SendToUser(id, WSMessage{
+			Type:    EventPresence,
+			Payload: payload,
+		})
+	}
+}
+
+func connectionsKey(userID uuid.UUID) string {
+	return connectionsKeyPrefix + userID.String()
+}
+
+func formatScore(ms int64) string {
+	return strconv.FormatInt(ms, 10)
+}
@@ -2,14 +2,29 @@ package settings
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidQuietHours = errors.New("quiet hours must be HH:MM times and must differ")
+	ErrInvalidTimezone   = errors.New("unknown timezone")
+	ErrInvalidLocale     = errors.New("locale must be a language tag such as \"en\" or \"es-MX\"")
+	ErrUnknownEmailType  = errors.New("unknown email type")
+)
+
+// localePattern matches simple language tags: a language with optional region or script subtags
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
 type Repository interface {
 	GetNotificationSettings(ctx context.Context, userID uuid.UUID) (*NotificationSettings, error)
 	UpsertNotificationSettings(ctx context.Context, settings *NotificationSettings) error
 	GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*PrivacySettings, error)
+	GetPrivacySettingsForUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*PrivacySettings, error)
 	UpsertPrivacySettings(ctx context.Context, settings *PrivacySettings) error
 }
 
@@ -32,11 +47,73 @@ func (s *Service) GetNotificationSettings(ctx context.Context, userID uuid.UUID)
 }
 
 // UpdateNotificationSettings updates notification settings
+// Quiet hours fields left empty fall back to the defaults; a missing email section keeps the stored one
 func (s *Service) UpdateNotificationSettings(ctx context.Context, userID uuid.UUID, settings *NotificationSettings) error {
 	settings.UserID = userID
+	if err := normalizeQuietHours(settings); err != nil {
+		return err
+	}
+	if settings.Email == nil {
+		current, err := s.GetNotificationSettings(ctx, userID)
+		if err != nil {
+			return err
+		}
+		settings.Email = current.Email
+	}
+	if err := normalizeEmail(settings.Email); err != nil {
+		return err
+	}
 	return s.repo.UpsertNotificationSettings(ctx, settings)
 }
 
+// Unsubscribe turns off one email type, as requested by an email's unsubscribe link
+func (s *Service) Unsubscribe(ctx context.Context, userID uuid.UUID, emailType string) error {
+	settings, err := s.GetNotificationSettings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !settings.Email.Unsubscribe(emailType) {
+		return ErrUnknownEmailType
+	}
+	return s.repo.UpsertNotificationSettings(ctx, settings)
+}
+
+func normalizeEmail(email *EmailSettings) error {
+	if email.Locale == "" {
+		email.Locale = DefaultLocale
+	}
+	email.Locale = strings.ReplaceAll(strings.ToLower(email.Locale), "_", "-")
+	if !localePattern.MatchString(email.Locale) {
+		return ErrInvalidLocale
+	}
+	return nil
+}
+
+func normalizeQuietHours(settings *NotificationSettings) error {
+	if settings.QuietHoursStart == "" {
+		settings.QuietHoursStart = DefaultQuietHoursStart
+	}
+	if settings.QuietHoursEnd == "" {
+		settings.QuietHoursEnd = DefaultQuietHoursEnd
+	}
+	if settings.Timezone == "" {
+		settings.Timezone = DefaultTimezone
+	}
+
+	start, err := time.Parse("15:04", settings.QuietHoursStart)
+	if err != nil {
+		return ErrInvalidQuietHours
+	}
+	end, err := time.Parse("15:04", settings.QuietHoursEnd)
+	if err != nil || start.Equal(end) {
+		return ErrInvalidQuietHours
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return ErrInvalidTimezone
+	}
+	return nil
+}
+
 // GetPrivacySettings gets privacy settings, returning defaults if none exist
 func (s *Service) GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*PrivacySettings, error) {
 	settings, err := s.repo.GetPrivacySettings(ctx, userID)
@@ -47,6 +124,28 @@ func (s *Service) GetPrivacySettings(ctx context.Context, userID uuid.UUID) (*Pr
 	return settings, nil
 }
 
+// GetPrivacySettingsForUsers gets privacy settings for several users, filling in defaults
+func (s *Service) GetPrivacySettingsForUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*PrivacySettings, error) {
+	result := make(map[uuid.UUID]*PrivacySettings, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	stored, err := s.repo.GetPrivacySettingsForUsers(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range userIDs {
+		if ps, ok := stored[id]; ok {
+			result[id] = ps
+		} else {
+			result[id] = DefaultPrivacySettings(id)
+		}
+	}
+	return result, nil
+}
+
 // UpdatePrivacySettings updates privacy settings
 func (s *Service) UpdatePrivacySettings(ctx context.Context, userID uuid.UUID, settings *PrivacySettings) error {
 	settings.UserID = userID
@@ -6,6 +6,22 @@ import (
 	"github.com/google/uuid"
 )
 
+// Quiet hours defaults
+const (
+	DefaultQuietHoursStart = "22:00"
+	DefaultQuietHoursEnd   = "08:00"
+	DefaultTimezone        = "UTC"
+	DefaultLocale          = "en"
+)
+
+// Email types, each with its own unsubscribe link
+const (
+	EmailLikesDigest         = "likes_digest"
+	EmailSubscriptionReceipt = "subscription_receipt"
+	EmailRenewalReminder     = "renewal_reminder"
+	EmailAccountDeleted      = "account_deleted"
+)
+
 // NotificationSettings stores user notification preferences
 type NotificationSettings struct {
 	UserID        uuid.UUID `json:"user_id"`
@@ -15,7 +31,59 @@ type NotificationSettings struct {
 	LikesReceived bool      `json:"likes_received"`
 	SuperLikes    bool      `json:"super_likes"`
 	Promotions    bool      `json:"promotions"`
-	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Quiet hours hold back pushes, texts and emails between QuietHoursStart and
+	// QuietHoursEnd ("HH:MM", local to Timezone) until the quiet period ends
+	QuietHoursEnabled bool   `json:"quiet_hours_enabled"`
+	QuietHoursStart   string `json:"quiet_hours_start"`
+	QuietHoursEnd     string `json:"quiet_hours_end"`
+	Timezone          string `json:"timezone"` // IANA name, e.g. "Europe/London"
+
+	// Email is nil in an update that leaves the email section unchanged
+	Email *EmailSettings `json:"email,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EmailSettings controls which emails a user receives and in which language
+type EmailSettings struct {
+	LikesDigest          bool   `json:"likes_digest"`
+	SubscriptionReceipts bool   `json:"subscription_receipts"`
+	RenewalReminders     bool   `json:"renewal_reminders"`
+	AccountDeleted       bool   `json:"account_deleted"`
+	Locale               string `json:"locale"` // e.g. "en" or "es-MX"
+}
+
+// Allows reports whether the user receives emails of the given type
+func (e *EmailSettings) Allows(emailType string) bool {
+	if flag := e.flag(emailType); flag != nil {
+		return *flag
+	}
+	return false
+}
+
+// Unsubscribe turns off an email type, reporting false if the type is unknown
+func (e *EmailSettings) Unsubscribe(emailType string) bool {
+	flag := e.flag(emailType)
+	if flag == nil {
+		return false
+	}
+	*flag = false
+	return true
+}
+
+func (e *EmailSettings) flag(emailType string) *bool {
+	switch emailType {
+	case EmailLikesDigest:
+		return &e.LikesDigest
+	case EmailSubscriptionReceipt:
+		return &e.SubscriptionReceipts
+	case EmailRenewalReminder:
+		return &e.RenewalReminders
+	case EmailAccountDeleted:
+		return &e.AccountDeleted
+	}
+	return nil
 }
 
 // PrivacySettings stores user privacy preferences
@@ -39,7 +107,26 @@ func DefaultNotificationSettings(userID uuid.UUID) *NotificationSettings {
 		LikesReceived: true,
 		SuperLikes:    true,
 		Promotions:    false,
-		UpdatedAt:     time.Now(),
+
+		QuietHoursEnabled: false,
+		QuietHoursStart:   DefaultQuietHoursStart,
+		QuietHoursEnd:     DefaultQuietHoursEnd,
+		Timezone:          DefaultTimezone,
+
+		Email: DefaultEmailSettings(),
+
+		UpdatedAt: time.Now(),
+	}
+}
+
+// DefaultEmailSettings returns default email settings
+func DefaultEmailSettings() *EmailSettings {
+	return &EmailSettings{
+		LikesDigest:          true,
+		SubscriptionReceipts: true,
+		RenewalReminders:     true,
+		AccountDeleted:       true,
+		Locale:               DefaultLocale,
 	}
 }
 
@@ -0,0 +1,58 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailSettings_Allows(t *testing.T) {
+	e := &EmailSettings{LikesDigest: true, SubscriptionReceipts: false, RenewalReminders: true, AccountDeleted: false}
+
+	tests := []struct {
+		emailType string
+		want      bool
+	}{
+		{EmailLikesDigest, true},
+		{EmailSubscriptionReceipt, false},
+		{EmailRenewalReminder, true},
+		{EmailAccountDeleted, false},
+		{"promotions", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.emailType, func(t *testing.T) {
+			assert.Equal(t, tt.want, e.Allows(tt.emailType))
+		})
+	}
+}
+
+func TestEmailSettings_Unsubscribe(t *testing.T) {
+	types := []string{EmailLikesDigest, EmailSubscriptionReceipt, EmailRenewalReminder, EmailAccountDeleted}
+
+	for _, emailType := range types {
+		t.Run(emailType, func(t *testing.T) {
+			e := DefaultEmailSettings()
+			assert.True(t, e.Unsubscribe(emailType))
+			assert.False(t, e.Allows(emailType))
+
+			// Only that type is turned off
+			for _, other := range types {
+				if other != emailType {
+					assert.True(t, e.Allows(other), other)
+				}
+			}
+
+			// Unsubscribing twice is fine
+			assert.True(t, e.Unsubscribe(emailType))
+			assert.False(t, e.Allows(emailType))
+		})
+	}
+}
+
+func TestEmailSettings_UnsubscribeUnknownType(t *testing.T) {
+	e := DefaultEmailSettings()
+	assert.False(t, e.Unsubscribe("promotions"))
+	assert.Equal(t, DefaultEmailSettings(), e, "settings must be unchanged")
+}
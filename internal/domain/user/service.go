@@ -79,6 +79,17 @@ type Repository interface {
 	DeleteUser(ctx context.Context, userID uuid.UUID) error
 }
 
+// DeviceKeyRevoker removes a device from the E2E key directory
+type DeviceKeyRevoker interface {
+	RevokeDevice(ctx context.Context, userID uuid.UUID, deviceID string) error
+}
+
+// DeletionMailer confirms account deletion by email; the email is prepared while the
+// account exists and the returned func sends it once the account is gone
+type DeletionMailer interface {
+	PrepareAccountDeleted(ctx context.Context, userID uuid.UUID) (func(ctx context.Context) error, error)
+}
+
 // SMSService interface for sending SMS messages
 type SMSService interface {
 	SendVerificationCode(ctx context.Context, to, code string) error
@@ -90,6 +101,8 @@ type Service struct {
 	accessExpiry  time.Duration
 	refreshExpiry time.Duration
 	smsService    SMSService
+	keyRevoker    DeviceKeyRevoker
+	mailer        DeletionMailer
 }
 
 type Claims struct {
@@ -111,6 +124,16 @@ func (s *Service) SetSMSService(sms SMSService) {
 	s.smsService = sms
 }
 
+// SetDeviceKeyRevoker sets the key directory used to revoke a device's keys on logout
+func (s *Service) SetDeviceKeyRevoker(r DeviceKeyRevoker) {
+	s.keyRevoker = r
+}
+
+// SetDeletionMailer sets the mailer that confirms account deletion
+func (s *Service) SetDeletionMailer(m DeletionMailer) {
+	s.mailer = m
+}
+
 // GetByPhone returns a user by their phone number
 func (s *Service) GetByPhone(ctx context.Context, phone string) (*User, error) {
 	return s.repo.GetByPhone(ctx, phone)
@@ -205,7 +228,7 @@ func (s *Service) GenerateTokens(ctx context.Context, userID uuid.UUID, deviceID
 		}
 	}
 
-	return s.generateTokens(ctx, userID)
+	return s.generateTokens(ctx, userID, deviceID)
 }
 
 func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResponse, error) {
@@ -277,7 +300,7 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*AuthResp
 	}
 	_ = s.repo.UpsertDeviceSession(ctx, session)
 
-	return s.generateTokens(ctx, user.ID)
+	return s.generateTokens(ctx, user.ID, req.DeviceID)
 }
 
 func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, error) {
@@ -344,7 +367,7 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest) (*AuthResponse,
 	}
 	_ = s.repo.UpsertDeviceSession(ctx, session)
 
-	return s.generateTokens(ctx, user.ID)
+	return s.generateTokens(ctx, user.ID, req.DeviceID)
 }
 
 func (s *Service) Refresh(ctx context.Context, refreshToken string) (*AuthResponse, error) {
@@ -365,12 +388,31 @@ func (s *Service) Refresh(ctx context.Context, refreshToken string) (*AuthRespon
 		return nil, err
 	}
 
-	return s.generateTokens(ctx, stored.UserID)
+	// The device keeps its session across rotations
+	return s.generateTokens(ctx, stored.UserID, stored.DeviceID)
 }
 
 func (s *Service) Logout(ctx context.Context, refreshToken string) error {
 	tokenHash := hashToken(refreshToken)
-	return s.repo.DeleteRefreshToken(ctx, tokenHash)
+
+	stored, err := s.repo.GetRefreshToken(ctx, tokenHash)
+	if err != nil {
+		return s.repo.DeleteRefreshToken(ctx, tokenHash)
+	}
+	if err := s.repo.DeleteRefreshToken(ctx, tokenHash); err != nil {
+		return err
+	}
+
+	// Logging out ends the device session, which revokes that device's E2E keys
+	if stored.DeviceID != "" {
+		if s.keyRevoker != nil {
+			if err := s.keyRevoker.RevokeDevice(ctx, stored.UserID, stored.DeviceID); err != nil {
+				log.Printf("[Auth] Logout: failed to revoke keys for user=%s device=%s: %v", stored.UserID, stored.DeviceID, err)
+			}
+		}
+		return s.repo.DeleteDeviceSession(ctx, stored.UserID, stored.DeviceID)
+	}
+	return nil
 }
 
 func (s *Service) ValidateAccessToken(tokenString string) (*Claims, error) {
@@ -397,7 +439,7 @@ func (s *Service) GetUser(ctx context.Context, id uuid.UUID) (*User, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *Service) generateTokens(ctx context.Context, userID uuid.UUID) (*AuthResponse, error) {
+func (s *Service) generateTokens(ctx context.Context, userID uuid.UUID, deviceID string) (*AuthResponse, error) {
 	now := time.Now()
 
 	// Generate access token
@@ -424,6 +466,7 @@ func (s *Service) generateTokens(ctx context.Context, userID uuid.UUID) (*AuthRe
 	refreshToken := &RefreshToken{
 		ID:        uuid.New(),
 		UserID:    userID,
+		DeviceID:  deviceID,
 		TokenHash: refreshTokenHash,
 		ExpiresAt: now.Add(s.refreshExpiry),
 		CreatedAt: now,
@@ -780,7 +823,7 @@ func (s *Service) VerifyMagicLink(ctx context.Context, req *VerifyMagicLinkReque
 
 	// Generate tokens
 	log.Printf("[Auth] VerifyMagicLink: user=%s email=%s isNewUser=%v", user.ID, user.Email, isNewUser)
-	authResp, err := s.generateTokens(ctx, user.ID)
+	authResp, err := s.generateTokens(ctx, user.ID, req.DeviceID)
 	if err != nil {
 		log.Printf("[Auth] VerifyMagicLink: failed to generate tokens for user=%s: %v", user.ID, err)
 		return nil, err
@@ -866,7 +909,7 @@ func (s *Service) AuthenticateWithApple(ctx context.Context, req *AppleAuthReque
 	_ = s.repo.UpsertDeviceSession(ctx, session)
 
 	// Generate tokens
-	authResp, err := s.generateTokens(ctx, user.ID)
+	authResp, err := s.generateTokens(ctx, user.ID, req.DeviceID)
 	if err != nil {
 		return nil, err
 	}
@@ -940,6 +983,25 @@ func (s *Service) DeleteAccount(ctx context.Context, userID uuid.UUID) error {
 	if err := s.repo.DeleteUserRefreshTokens(ctx, userID); err != nil {
 		return err
 	}
+	// Address the confirmation email before the profile and settings it needs are deleted
+	var sendConfirmation func(ctx context.Context) error
+	if s.mailer != nil {
+		send, err := s.mailer.PrepareAccountDeleted(ctx, userID)
+		if err != nil {
+			log.Printf("[Auth] DeleteAccount: failed to prepare confirmation email for user=%s: %v", userID, err)
+		}
+		sendConfirmation = send
+	}
+
 	// Delete the user (cascades to profile, photos, matches, messages, etc.)
-	return s.repo.DeleteUser(ctx, userID)
+	if err := s.repo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if sendConfirmation != nil {
+		if err := sendConfirmation(ctx); err != nil {
+			log.Printf("[Auth] DeleteAccount: failed to send confirmation email for user=%s: %v", userID, err)
+		}
+	}
+	return nil
 }
@@ -51,6 +51,7 @@ type RefreshRequest struct {
 type RefreshToken struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
+	DeviceID  string
 	TokenHash string
 	ExpiresAt time.Time
 	CreatedAt time.Time
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 )
@@ -30,14 +31,16 @@ type Email struct {
 	Subject string
 	HTML    string
 	Text    string
+	Headers map[string]string
 }
 
 type resendRequest struct {
-	From    string   `json:"from"`
-	To      []string `json:"to"`
-	Subject string   `json:"subject"`
-	HTML    string   `json:"html,omitempty"`
-	Text    string   `json:"text,omitempty"`
+	From    string            `json:"from"`
+	To      []string          `json:"to"`
+	Subject string            `json:"subject"`
+	HTML    string            `json:"html,omitempty"`
+	Text    string            `json:"text,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 type resendResponse struct {
@@ -86,6 +89,7 @@ func (s *Service) Send(ctx context.Context, email *Email) error {
 		Subject: email.Subject,
 		HTML:    email.HTML,
 		Text:    email.Text,
+		Headers: email.Headers,
 	}
 
 	body, err := json.Marshal(req)
@@ -120,6 +124,26 @@ func (s *Service) Send(ctx context.Context, email *Email) error {
 	return nil
 }
 
+// SendTemplate renders a template in the closest available locale and sends it
+// The unsubscribe URL goes in the footer and in List-Unsubscribe for one-click unsubscribe
+func (s *Service) SendTemplate(ctx context.Context, toEmail, name, locale string, data interface{}, unsubscribeURL string) error {
+	msg, err := renderTemplate(name, locale, data, unsubscribeURL)
+	if err != nil {
+		return err
+	}
+
+	return s.Send(ctx, &Email{
+		To:      []string{toEmail},
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
 // SendMagicLink sends a magic link email
 func (s *Service) SendMagicLink(ctx context.Context, toEmail, token, appName string) error {
 	// Use HTTPS link that redirects to app - email clients block custom schemes
@@ -274,3 +298,126 @@ Open %s to send a message.
 		Text:    text,
 	})
 }
+
+// SendDatePlanShared tells a user's trusted contact about an upcoming date
+func (s *Service) SendDatePlanShared(ctx context.Context, toEmail, contactName, userName, when, place string) error {
+	html := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background-color: #0a0a0a; color: #ffffff; padding: 40px 20px;">
+  <div style="max-width: 400px; margin: 0 auto; text-align: center;">
+    <h1 style="color: #e85d75; margin-bottom: 30px;">Date plans shared with you</h1>
+    <p style="font-size: 18px; margin-bottom: 20px;">Hi %s,</p>
+    <p style="font-size: 16px; color: #ccc; margin-bottom: 20px;">%s added you as their trusted contact for a date on Feels.</p>
+    <p style="font-size: 16px; margin-bottom: 6px;"><strong>%s</strong></p>
+    <p style="font-size: 16px; color: #ccc; margin-bottom: 30px;">%s</p>
+    <p style="color: #888; font-size: 14px;">If they miss their safety check-in, we'll email you so you can reach out.</p>
+  </div>
+</body>
+</html>
+`, escapeHTML(contactName), escapeHTML(userName), escapeHTML(when), escapeHTML(place))
+
+	text := fmt.Sprintf(`Date plans shared with you
+
+Hi %s,
+
+%s added you as their trusted contact for a date on Feels.
+
+When: %s
+Where: %s
+
+If they miss their safety check-in, we'll email you so you can reach out.
+`, contactName, userName, when, place)
+
+	return s.Send(ctx, &Email{
+		To:      []string{toEmail},
+		Subject: fmt.Sprintf("%s shared their date plans with you", userName),
+		HTML:    html,
+		Text:    text,
+	})
+}
+
+// SendCheckInEscalation tells a trusted contact the user missed their safety check-in
+func (s *Service) SendCheckInEscalation(ctx context.Context, toEmail, contactName, userName, when, place string) error {
+	html := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background-color: #0a0a0a; color: #ffffff; padding: 40px 20px;">
+  <div style="max-width: 400px; margin: 0 auto; text-align: center;">
+    <h1 style="color: #e85d75; margin-bottom: 30px;">Missed safety check-in</h1>
+    <p style="font-size: 18px; margin-bottom: 20px;">Hi %s,</p>
+    <p style="font-size: 16px; color: #ccc; margin-bottom: 20px;">%s hasn't checked in after their date. Please try to reach them.</p>
+    <p style="font-size: 16px; margin-bottom: 6px;"><strong>%s</strong></p>
+    <p style="font-size: 16px; color: #ccc; margin-bottom: 30px;">%s</p>
+    <p style="color: #888; font-size: 14px;">If you believe they are in danger, contact local emergency services.</p>
+  </div>
+</body>
+</html>
+`, escapeHTML(contactName), escapeHTML(userName), escapeHTML(when), escapeHTML(place))
+
+	text := fmt.Sprintf(`Missed safety check-in
+
+Hi %s,
+
+%s hasn't checked in after their date. Please try to reach them.
+
+When: %s
+Where: %s
+
+If you believe they are in danger, contact local emergency services.
+`, contactName, userName, when, place)
+
+	return s.Send(ctx, &Email{
+		To:      []string{toEmail},
+		Subject: fmt.Sprintf("%s missed a safety check-in", userName),
+		HTML:    html,
+		Text:    text,
+	})
+}
+
+// SendNotificationEmail sends a dispatcher notification, e.g. a safety check-in reminder
+func (s *Service) SendNotificationEmail(ctx context.Context, toEmail, title, body string) error {
+	html := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background-color: #0a0a0a; color: #ffffff; padding: 40px 20px;">
+  <div style="max-width: 400px; margin: 0 auto; text-align: center;">
+    <h1 style="color: #e85d75; margin-bottom: 30px;">%s</h1>
+    <p style="font-size: 18px; margin-bottom: 30px;">%s</p>
+    <a href="feels://" style="display: inline-block; background-color: #e85d75; color: white; padding: 16px 32px; text-decoration: none; border-radius: 8px; font-weight: bold; font-size: 16px;">Open Feels</a>
+  </div>
+</body>
+</html>
+`, escapeHTML(title), escapeHTML(body))
+
+	text := fmt.Sprintf(`%s
+
+%s
+
+Open Feels to see more.
+`, title, body)
+
+	return s.Send(ctx, &Email{
+		To:      []string{toEmail},
+		Subject: title,
+		HTML:    html,
+		Text:    text,
+	})
+}
+
+// escapeHTML escapes user-supplied text placed in an HTML body
+func escapeHTML(s string) string {
+	return html.EscapeString(s)
+}
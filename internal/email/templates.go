@@ -0,0 +1,171 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// DefaultLocale is used when a template has no variant for the requested locale
+const DefaultLocale = "en"
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// Templates live in templates/<locale>/<name>.txt and <name>.html. The .txt file defines
+// "subject" and "content", the .html file defines "content", and both are wrapped in the
+// shared layout along with the locale's footer, which carries the unsubscribe link.
+//
+//go:embed templates
+var templateFS embed.FS
+
+var templates = mustLoadTemplates(templateFS)
+
+// templateData is what every template is executed with; Data is the per-email payload
+type templateData struct {
+	Data           interface{}
+	UnsubscribeURL string
+}
+
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// rendered is a template executed for one recipient
+type rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// monthNames localizes dates in templates; locales without an entry use English
+var monthNames = map[string][12]string{
+	"en": {"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	"es": {"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+}
+
+// mustLoadTemplates parses every locale's templates; they're embedded, so failing here is a bug
+func mustLoadTemplates(fsys fs.FS) map[string]*templateSet {
+	sets, err := loadTemplates(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return sets
+}
+
+// loadTemplates returns template sets keyed by "<locale>/<name>"
+func loadTemplates(fsys fs.FS) (map[string]*templateSet, error) {
+	locales, err := fs.ReadDir(fsys, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make(map[string]*templateSet)
+	for _, dir := range locales {
+		if !dir.IsDir() {
+			continue
+		}
+		locale := dir.Name()
+		files, err := fs.Glob(fsys, path.Join("templates", locale, "*.txt"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := strings.TrimSuffix(path.Base(file), ".txt")
+			if name == "footer" {
+				continue
+			}
+			set, err := parseTemplateSet(fsys, locale, name)
+			if err != nil {
+				return nil, fmt.Errorf("email template %s/%s: %w", locale, name, err)
+			}
+			sets[locale+"/"+name] = set
+		}
+	}
+	return sets, nil
+}
+
+func parseTemplateSet(fsys fs.FS, locale, name string) (*templateSet, error) {
+	dir := path.Join("templates", locale)
+	funcs := map[string]interface{}{
+		"date": func(t time.Time) string { return formatDate(t, locale) },
+	}
+
+	text, err := texttemplate.New("layout.txt").Funcs(funcs).ParseFS(fsys,
+		"templates/layout.txt", path.Join(dir, "common.tmpl"), path.Join(dir, "footer.txt"), path.Join(dir, name+".txt"))
+	if err != nil {
+		return nil, err
+	}
+	if text.Lookup("subject") == nil {
+		return nil, errors.New("missing subject")
+	}
+
+	html, err := htmltemplate.New("layout.html").Funcs(funcs).ParseFS(fsys,
+		"templates/layout.html", path.Join(dir, "common.tmpl"), path.Join(dir, "footer.html"), path.Join(dir, name+".html"))
+	if err != nil {
+		return nil, err
+	}
+	return &templateSet{text: text, html: html}, nil
+}
+
+// lookupTemplate finds the closest variant of a template: "es-mx", then "es", then DefaultLocale
+func lookupTemplate(name, locale string) (*templateSet, string, error) {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	candidates := []string{locale}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, DefaultLocale)
+
+	for _, l := range candidates {
+		if set, ok := templates[l+"/"+name]; ok {
+			return set, l, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+}
+
+// renderTemplate executes a template in the user's locale
+func renderTemplate(name, locale string, data interface{}, unsubscribeURL string) (*rendered, error) {
+	set, _, err := lookupTemplate(name, locale)
+	if err != nil {
+		return nil, err
+	}
+	td := templateData{Data: data, UnsubscribeURL: unsubscribeURL}
+
+	var subject, text, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", td); err != nil {
+		return nil, err
+	}
+	if err := set.text.Execute(&text, td); err != nil {
+		return nil, err
+	}
+	if err := set.html.Execute(&html, td); err != nil {
+		return nil, err
+	}
+	return &rendered{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// formatDate writes a long-form date in the locale's style
+func formatDate(t time.Time, locale string) string {
+	months, ok := monthNames[locale]
+	if !ok {
+		months = monthNames[DefaultLocale]
+	}
+	month := months[t.Month()-1]
+	if locale == "es" {
+		return fmt.Sprintf("%d de %s de %d", t.Day(), month, t.Year())
+	}
+	return fmt.Sprintf("%s %d, %d", month, t.Day(), t.Year())
+}
@@ -0,0 +1,117 @@
+package email
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feels/feels/internal/domain/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupTemplate_FallsBackToBaseThenDefaultLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"es", "es"},
+		{"es-mx", "es"},
+		{"es-MX", "es"},
+		{"es_MX", "es"},
+		{"ES", "es"},
+		{"en", "en"},
+		{"en-gb", "en"},
+		{"fr", DefaultLocale},
+		{"fr-ca", DefaultLocale},
+		{"", DefaultLocale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			set, got, err := lookupTemplate("likes_digest", tt.locale)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Same(t, templates[tt.want+"/likes_digest"], set)
+		})
+	}
+}
+
+func TestLookupTemplate_UnknownName(t *testing.T) {
+	_, _, err := lookupTemplate("newsletter", "es-mx")
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+}
+
+// templateSamples is data for each template, as the mailer sends it
+var templateSamples = map[string]interface{}{
+	"likes_digest": mailer.LikesDigestData{Name: "Sam", Likes: 3},
+	"subscription_receipt": mailer.ReceiptData{
+		Name:        "Sam",
+		Plan:        "quarterly",
+		Amount:      "24.99 USD",
+		PurchasedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		RenewsAt:    time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+	},
+	"renewal_reminder": mailer.RenewalReminderData{Name: "Sam", Plan: "annual", RenewsAt: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+	"account_deleted":  mailer.AccountDeletedData{Name: "Sam"},
+}
+
+func TestRenderTemplate_EveryTemplateInEveryLocale(t *testing.T) {
+	require.NotEmpty(t, templates)
+	keys := make([]string, 0, len(templates))
+	for key := range templates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	const unsubscribeURL = "https://api.example.com/email/unsubscribe?token=a.b&c"
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			locale, name, _ := strings.Cut(key, "/")
+			data, ok := templateSamples[name]
+			require.True(t, ok, "no sample data for template %s", name)
+
+			r, err := renderTemplate(name, locale, data, unsubscribeURL)
+			require.NoError(t, err)
+			assert.NotEmpty(t, r.Subject)
+			assert.NotContains(t, r.Subject, "\n")
+			for _, body := range []string{r.Subject, r.Text, r.HTML} {
+				assert.NotContains(t, body, "<no value>")
+				assert.NotContains(t, body, "%!")
+			}
+			assert.Contains(t, r.Text, "Sam")
+			assert.Contains(t, r.HTML, "Sam")
+			assert.Contains(t, r.Text, unsubscribeURL)
+			assert.Contains(t, r.HTML, "a.b&amp;c", "the unsubscribe link must be escaped in HTML")
+		})
+	}
+}
+
+func TestTemplates_EveryLocaleHasEveryTemplate(t *testing.T) {
+	locales := make(map[string]bool)
+	for key := range templates {
+		locale, name, _ := strings.Cut(key, "/")
+		locales[locale] = true
+		assert.Contains(t, templateSamples, name, "add sample data for %s", key)
+	}
+	require.Contains(t, locales, DefaultLocale)
+
+	for locale := range locales {
+		for name := range templateSamples {
+			assert.Contains(t, templates, locale+"/"+name)
+		}
+	}
+}
+
+func TestRenderTemplate_LocalizesDates(t *testing.T) {
+	data := templateSamples["renewal_reminder"]
+
+	en, err := renderTemplate("renewal_reminder", "en", data, "https://example.com/u")
+	require.NoError(t, err)
+	assert.Contains(t, en.Text, "March 8, 2026")
+
+	es, err := renderTemplate("renewal_reminder", "es-mx", data, "https://example.com/u")
+	require.NoError(t, err)
+	assert.Contains(t, es.Text, "8 de marzo de 2026")
+}
@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Email job names
+const (
+	JobWeeklyLikesDigest = "weekly_likes_digest"
+	JobRenewalReminders  = "renewal_reminders"
+)
+
+// RenewalReminderLead is how long before a subscription renews its reminder is sent
+const RenewalReminderLead = 3 * 24 * time.Hour
+
+// LikesDigestCandidate is a user with likes they haven't answered
+type LikesDigestCandidate struct {
+	UserID uuid.UUID
+	Likes  int
+}
+
+// RenewalDue is an auto-renewing subscription coming up for renewal
+type RenewalDue struct {
+	UserID   uuid.UUID
+	PlanType string
+	RenewsAt time.Time
+}
+
+// EmailRepository provides the queries behind the email jobs
+type EmailRepository interface {
+	GetLikesDigestCandidates(ctx context.Context, since time.Time) ([]LikesDigestCandidate, error)
+	GetRenewalsDue(ctx context.Context, from, to time.Time) ([]RenewalDue, error)
+}
+
+// Mailer sends the emails used by the email jobs, reporting false when a user
+// unsubscribed, has no address or was already sent the email
+type Mailer interface {
+	SendLikesDigest(ctx context.Context, userID uuid.UUID, likes int, weekOf time.Time) (bool, error)
+	SendRenewalReminder(ctx context.Context, userID uuid.UUID, plan string, renewsAt time.Time) (bool, error)
+}
+
+// RegisterEmailJobs registers the weekly likes digest and renewal reminders
+func RegisterEmailJobs(s *Scheduler, repo EmailRepository, mailer Mailer) {
+	s.Register(Job{
+		Name:     JobWeeklyLikesDigest,
+		Interval: 7 * 24 * time.Hour,
+		Timeout:  time.Hour,
+		Run:      WeeklyLikesDigestJob(repo, mailer),
+	})
+	s.Register(Job{
+		Name:     JobRenewalReminders,
+		Interval: 24 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run:      RenewalRemindersJob(repo, mailer),
+	})
+}
+
+// WeeklyLikesDigestJob emails everyone with unanswered likes from the past week
+func WeeklyLikesDigestJob(repo EmailRepository, mailer Mailer) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		now := time.Now()
+		candidates, err := repo.GetLikesDigestCandidates(ctx, now.Add(-7*24*time.Hour))
+		if err != nil {
+			return "", err
+		}
+
+		sent, skipped, failed := 0, 0, 0
+		for _, c := range candidates {
+			if ctx.Err() != nil {
+				return fmt.Sprintf("sent %d digests, %d skipped, %d failed (interrupted)", sent, skipped, failed), ctx.Err()
+			}
+			ok, err := mailer.SendLikesDigest(ctx, c.UserID, c.Likes, now)
+			switch {
+			case err != nil:
+				log.Printf("[JOBS] likes digest email for %s failed: %v", c.UserID, err)
+				failed++
+			case ok:
+				sent++
+			default:
+				skipped++
+			}
+		}
+		return fmt.Sprintf("sent %d digests, %d skipped, %d failed", sent, skipped, failed), nil
+	}
+}
+
+// RenewalRemindersJob emails users whose subscription renews within RenewalReminderLead
+// Each renewal is reminded once, so a missed day is caught up on the next run
+func RenewalRemindersJob(repo EmailRepository, mailer Mailer) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		now := time.Now()
+		due, err := repo.GetRenewalsDue(ctx, now, now.Add(RenewalReminderLead))
+		if err != nil {
+			return "", err
+		}
+
+		sent, skipped, failed := 0, 0, 0
+		for _, d := range due {
+			if ctx.Err() != nil {
+				return fmt.Sprintf("sent %d reminders, %d skipped, %d failed (interrupted)", sent, skipped, failed), ctx.Err()
+			}
+			ok, err := mailer.SendRenewalReminder(ctx, d.UserID, d.PlanType, d.RenewsAt)
+			switch {
+			case err != nil:
+				log.Printf("[JOBS] renewal reminder for %s failed: %v", d.UserID, err)
+				failed++
+			case ok:
+				sent++
+			default:
+				skipped++
+			}
+		}
+		return fmt.Sprintf("sent %d reminders, %d skipped, %d failed", sent, skipped, failed), nil
+	}
+}
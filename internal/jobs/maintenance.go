@@ -0,0 +1,159 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job names
+const (
+	JobDailyDigest         = "daily_digest"
+	JobInactivityReminders = "inactivity_reminders"
+	JobExpireTokens        = "expire_tokens"
+	JobCleanupPasses       = "cleanup_passes"
+)
+
+const (
+	// PassRetentionDays is how long a pass hides a profile before it can resurface
+	PassRetentionDays = 90
+)
+
+// InactivityReminderDays are the days-since-active on which a reminder is sent
+var InactivityReminderDays = []int{3, 7, 14}
+
+// DigestCandidate is a user with activity to report in the daily digest
+type DigestCandidate struct {
+	UserID     uuid.UUID
+	NewLikes   int
+	NewMatches int
+}
+
+// InactiveUser is a user due an inactivity reminder
+type InactiveUser struct {
+	UserID          uuid.UUID
+	DaysSinceActive int
+}
+
+// MaintenanceRepository provides the queries behind the built-in jobs
+type MaintenanceRepository interface {
+	GetDailyDigestCandidates(ctx context.Context, since time.Time) ([]DigestCandidate, error)
+	GetInactiveUsers(ctx context.Context, days []int) ([]InactiveUser, error)
+	DeleteExpiredMagicLinks(ctx context.Context) (int64, error)
+	DeleteExpiredOTPCodes(ctx context.Context) (int64, error)
+	DeleteExpiredRefreshTokens(ctx context.Context) (int64, error)
+	DeletePassesOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Notifier sends the push notifications used by the built-in jobs
+type Notifier interface {
+	SendDailyDigestNotification(ctx context.Context, userID uuid.UUID, newLikes, newMatches int) error
+	SendInactivityReminderNotification(ctx context.Context, userID uuid.UUID, daysSinceActive int) error
+}
+
+// RegisterDefaultJobs registers the digest, reminder and cleanup jobs
+func RegisterDefaultJobs(s *Scheduler, repo MaintenanceRepository, notifier Notifier) {
+	s.Register(Job{
+		Name:     JobDailyDigest,
+		Interval: 24 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run:      DailyDigestJob(repo, notifier),
+	})
+	s.Register(Job{
+		Name:     JobInactivityReminders,
+		Interval: 24 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run:      InactivityRemindersJob(repo, notifier),
+	})
+	s.Register(Job{
+		Name:     JobExpireTokens,
+		Interval: time.Hour,
+		Run:      ExpireTokensJob(repo),
+	})
+	s.Register(Job{
+		Name:     JobCleanupPasses,
+		Interval: 24 * time.Hour,
+		Run:      CleanupPassesJob(repo),
+	})
+}
+
+// DailyDigestJob pushes a summary of the last 24h of likes and matches
+func DailyDigestJob(repo MaintenanceRepository, notifier Notifier) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		candidates, err := repo.GetDailyDigestCandidates(ctx, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			return "", err
+		}
+
+		sent, failed := 0, 0
+		for _, c := range candidates {
+			if ctx.Err() != nil {
+				return fmt.Sprintf("sent %d digests, %d failed (interrupted)", sent, failed), ctx.Err()
+			}
+			if err := notifier.SendDailyDigestNotification(ctx, c.UserID, c.NewLikes, c.NewMatches); err != nil {
+				log.Printf("[JOBS] daily digest for %s failed: %v", c.UserID, err)
+				failed++
+				continue
+			}
+			sent++
+		}
+		return fmt.Sprintf("sent %d digests, %d failed", sent, failed), nil
+	}
+}
+
+// InactivityRemindersJob nudges users who have been away for InactivityReminderDays
+func InactivityRemindersJob(repo MaintenanceRepository, notifier Notifier) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		users, err := repo.GetInactiveUsers(ctx, InactivityReminderDays)
+		if err != nil {
+			return "", err
+		}
+
+		sent, failed := 0, 0
+		for _, u := range users {
+			if ctx.Err() != nil {
+				return fmt.Sprintf("sent %d reminders, %d failed (interrupted)", sent, failed), ctx.Err()
+			}
+			if err := notifier.SendInactivityReminderNotification(ctx, u.UserID, u.DaysSinceActive); err != nil {
+				log.Printf("[JOBS] inactivity reminder for %s failed: %v", u.UserID, err)
+				failed++
+				continue
+			}
+			sent++
+		}
+		return fmt.Sprintf("sent %d reminders, %d failed", sent, failed), nil
+	}
+}
+
+// ExpireTokensJob deletes expired magic links, OTP codes and refresh tokens
+func ExpireTokensJob(repo MaintenanceRepository) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		links, err := repo.DeleteExpiredMagicLinks(ctx)
+		if err != nil {
+			return "", fmt.Errorf("magic links: %w", err)
+		}
+		codes, err := repo.DeleteExpiredOTPCodes(ctx)
+		if err != nil {
+			return "", fmt.Errorf("otp codes: %w", err)
+		}
+		tokens, err := repo.DeleteExpiredRefreshTokens(ctx)
+		if err != nil {
+			return "", fmt.Errorf("refresh tokens: %w", err)
+		}
+		return fmt.Sprintf("deleted %d magic links, %d otp codes, %d refresh tokens", links, codes, tokens), nil
+	}
+}
+
+// CleanupPassesJob deletes passes older than PassRetentionDays so profiles can resurface
+func CleanupPassesJob(repo MaintenanceRepository) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		deleted, err := repo.DeletePassesOlderThan(ctx, time.Now().AddDate(0, 0, -PassRetentionDays))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("deleted %d passes", deleted), nil
+	}
+}
@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Notification dispatch job names
+const (
+	JobFlushNotifications = "flush_notifications"
+	JobPruneDeliveryLog   = "prune_notification_deliveries"
+	JobPruneInbox         = "prune_notification_inbox"
+	JobPushReceipts       = "push_receipts"
+)
+
+// NotificationFlusher delivers held notifications and trims the delivery log
+type NotificationFlusher interface {
+	FlushPending(ctx context.Context, now time.Time) (int, error)
+	PruneDeliveryLog(ctx context.Context, now time.Time) (int64, error)
+}
+
+// InboxPruner deletes old notifications from users' inboxes
+type InboxPruner interface {
+	PruneInbox(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ReceiptPoller checks Expo push receipts and prunes dead device tokens
+type ReceiptPoller interface {
+	PollReceipts(ctx context.Context, now time.Time) (int, int, error)
+}
+
+// RegisterNotificationDispatch registers the jobs behind notification batching and quiet hours
+func RegisterNotificationDispatch(s *Scheduler, f NotificationFlusher) {
+	s.Register(Job{
+		Name:     JobFlushNotifications,
+		Interval: time.Minute,
+		Timeout:  time.Minute,
+		Run:      FlushNotificationsJob(f),
+	})
+	s.Register(Job{
+		Name:     JobPruneDeliveryLog,
+		Interval: 24 * time.Hour,
+		Run:      PruneDeliveryLogJob(f),
+	})
+}
+
+// FlushNotificationsJob sends batched summaries and notifications held for quiet hours
+func FlushNotificationsJob(f NotificationFlusher) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		sent, err := f.FlushPending(ctx, time.Now())
+		return fmt.Sprintf("sent %d held notifications", sent), err
+	}
+}
+
+// PruneDeliveryLogJob deletes delivery log entries past their retention
+func PruneDeliveryLogJob(f NotificationFlusher) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		deleted, err := f.PruneDeliveryLog(ctx, time.Now())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("deleted %d deliveries", deleted), nil
+	}
+}
+
+// RegisterInboxCleanup registers the daily notification inbox cleanup
+func RegisterInboxCleanup(s *Scheduler, p InboxPruner) {
+	s.Register(Job{
+		Name:     JobPruneInbox,
+		Interval: 24 * time.Hour,
+		Run:      PruneInboxJob(p),
+	})
+}
+
+// PruneInboxJob deletes inbox notifications past their retention
+func PruneInboxJob(p InboxPruner) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		deleted, err := p.PruneInbox(ctx, time.Now())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("deleted %d notifications", deleted), nil
+	}
+}
+
+// RegisterPushReceipts registers the Expo receipt check that prunes dead push tokens
+func RegisterPushReceipts(s *Scheduler, p ReceiptPoller) {
+	s.Register(Job{
+		Name:     JobPushReceipts,
+		Interval: 5 * time.Minute,
+		Timeout:  2 * time.Minute,
+		Run:      PushReceiptsJob(p),
+	})
+}
+
+// PushReceiptsJob checks receipts for pushes sent at least 15 minutes ago
+func PushReceiptsJob(p ReceiptPoller) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		checked, pruned, err := p.PollReceipts(ctx, time.Now())
+		return fmt.Sprintf("checked %d receipts, pruned %d tokens", checked, pruned), err
+	}
+}
@@ -0,0 +1,172 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobConversationNudges nudges people in matches that never got going or stalled
+const JobConversationNudges = "conversation_nudges"
+
+// EventConversationNudge is the WebSocket event carrying a nudge and its icebreaker
+const EventConversationNudge = "conversation_nudge"
+
+// Nudge reasons
+const (
+	NudgeReasonNoMessages = "no_messages" // matched but nobody has said anything
+	NudgeReasonNoReply    = "no_reply"    // the other person is waiting on a reply
+)
+
+const (
+	// NudgeNoMessagesAfter is how long a new match can sit silent before both people are nudged
+	NudgeNoMessagesAfter = 24 * time.Hour
+
+	// NudgeNoReplyAfter is how long a message can go unanswered before its recipient is nudged
+	NudgeNoReplyAfter = 3 * 24 * time.Hour
+
+	// NudgeMatchCooldown is the minimum time between nudges in the same match
+	NudgeMatchCooldown = 3 * 24 * time.Hour
+
+	// NudgeMaxPerMatch caps the nudges each person gets over the lifetime of a match
+	NudgeMaxPerMatch = 3
+
+	// NudgeGiveUpAfter stops nudging conversations that have been quiet this long
+	NudgeGiveUpAfter = 30 * 24 * time.Hour
+
+	// nudgeBatch is how many matches are nudged per run
+	nudgeBatch = 1000
+)
+
+// NudgePrompt is a profile prompt offered as an icebreaker
+type NudgePrompt struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// NudgeCandidate is a person to nudge about a stalled match
+// Prompts are the other person's profile prompts
+type NudgeCandidate struct {
+	MatchID     uuid.UUID
+	UserID      uuid.UUID
+	OtherUserID uuid.UUID
+	OtherName   string
+	Reason      string
+	Prompts     []NudgePrompt
+}
+
+// NudgeQuery selects stalled matches that are due a nudge
+type NudgeQuery struct {
+	NoMessagesBefore time.Time // matched before this with no messages
+	NoReplyBefore    time.Time // last message sent before this
+	QuietSince       time.Time // ignore matches quiet since before this
+	CooldownSince    time.Time // skip matches nudged after this
+	MaxPerMatch      int       // nudges per person per match
+	Limit            int       // matches, not people, so a match's nudges are never split
+}
+
+// NudgeRepository finds stalled matches and records nudges
+// Candidates must exclude people with new-message notifications off and muted or archived chats
+type NudgeRepository interface {
+	GetNudgeCandidates(ctx context.Context, q NudgeQuery) ([]NudgeCandidate, error)
+	RecordNudge(ctx context.Context, matchID, userID uuid.UUID, reason string) error
+}
+
+// NudgeNotifier sends the nudge push notification
+type NudgeNotifier interface {
+	SendConversationNudgeNotification(ctx context.Context, userID uuid.UUID, otherName, reason, icebreaker string, matchID uuid.UUID) error
+}
+
+// EventSender delivers WebSocket events to a user's connections
+type EventSender interface {
+	SendToUser(userID uuid.UUID, msg interface{})
+}
+
+// ConversationNudgePayload is the payload of a conversation_nudge event
+type ConversationNudgePayload struct {
+	MatchID     uuid.UUID    `json:"match_id"`
+	OtherUserID uuid.UUID    `json:"other_user_id"`
+	Reason      string       `json:"reason"`
+	Icebreaker  *NudgePrompt `json:"icebreaker,omitempty"`
+}
+
+type nudgeEvent struct {
+	Type    string                   `json:"type"`
+	Payload ConversationNudgePayload `json:"payload"`
+}
+
+// RegisterConversationNudges registers the stalled conversation nudge job
+func RegisterConversationNudges(s *Scheduler, repo NudgeRepository, notifier NudgeNotifier, events EventSender) {
+	s.Register(Job{
+		Name:     JobConversationNudges,
+		Interval: time.Hour,
+		Timeout:  15 * time.Minute,
+		Run:      ConversationNudgesJob(repo, notifier, events),
+	})
+}
+
+// ConversationNudgesJob sends a push and a conversation_nudge event, with one of the
+// other person's prompts as an icebreaker, to whoever should speak next in a stalled match
+func ConversationNudgesJob(repo NudgeRepository, notifier NudgeNotifier, events EventSender) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		now := time.Now()
+		candidates, err := repo.GetNudgeCandidates(ctx, NudgeQuery{
+			NoMessagesBefore: now.Add(-NudgeNoMessagesAfter),
+			NoReplyBefore:    now.Add(-NudgeNoReplyAfter),
+			QuietSince:       now.Add(-NudgeGiveUpAfter),
+			CooldownSince:    now.Add(-NudgeMatchCooldown),
+			MaxPerMatch:      NudgeMaxPerMatch,
+			Limit:            nudgeBatch,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		sent, failed := 0, 0
+		for _, c := range candidates {
+			if ctx.Err() != nil {
+				return fmt.Sprintf("sent %d nudges, %d failed (interrupted)", sent, failed), ctx.Err()
+			}
+
+			// Record first so a failed push can't cause the same nudge to repeat next run
+			if err := repo.RecordNudge(ctx, c.MatchID, c.UserID, c.Reason); err != nil {
+				log.Printf("[JOBS] recording nudge for match %s failed: %v", c.MatchID, err)
+				failed++
+				continue
+			}
+
+			var icebreaker *NudgePrompt
+			if len(c.Prompts) > 0 {
+				icebreaker = &c.Prompts[rand.Intn(len(c.Prompts))]
+			}
+
+			if events != nil {
+				events.SendToUser(c.UserID, nudgeEvent{
+					Type: EventConversationNudge,
+					Payload: ConversationNudgePayload{
+						MatchID:     c.MatchID,
+						OtherUserID: c.OtherUserID,
+						Reason:      c.Reason,
+						Icebreaker:  icebreaker,
+					},
+				})
+			}
+
+			question := ""
+			if icebreaker != nil {
+				question = icebreaker.Question
+			}
+			if err := notifier.SendConversationNudgeNotification(ctx, c.UserID, c.OtherName, c.Reason, question, c.MatchID); err != nil {
+				log.Printf("[JOBS] nudge push for %s in match %s failed: %v", c.UserID, c.MatchID, err)
+				failed++
+				continue
+			}
+			sent++
+		}
+		return fmt.Sprintf("sent %d nudges, %d failed", sent, failed), nil
+	}
+}
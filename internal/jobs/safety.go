@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// JobSafetyCheckIns reminds and escalates date safety check-ins
+const JobSafetyCheckIns = "safety_check_ins"
+
+// CheckInProcessor handles check-ins that have come due
+type CheckInProcessor interface {
+	ProcessDueCheckIns(ctx context.Context, now time.Time) (reminded, escalated int, err error)
+}
+
+// RegisterSafetyCheckIns registers the safety check-in job; it runs every minute
+// so a missed check-in reaches the trusted contact promptly
+func RegisterSafetyCheckIns(s *Scheduler, p CheckInProcessor) {
+	s.Register(Job{
+		Name:     JobSafetyCheckIns,
+		Interval: time.Minute,
+		Timeout:  time.Minute,
+		Run:      SafetyCheckInsJob(p),
+	})
+}
+
+// SafetyCheckInsJob reminds users whose check-in time has arrived and escalates
+// check-ins missed by more than the grace period
+func SafetyCheckInsJob(p CheckInProcessor) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		reminded, escalated, err := p.ProcessDueCheckIns(ctx, time.Now())
+		return fmt.Sprintf("reminded %d, escalated %d", reminded, escalated), err
+	}
+}
@@ -0,0 +1,405 @@
+// Package jobs runs periodic background work (digests, reminders, cleanup)
+// inside the API process, using Redis locks so only one replica runs each job
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// Run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Run statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	lockKeyPrefix = "jobs:"
+	// runLockTTL bounds how long a crashed replica can block a job
+	runLockTTL = 30 * time.Minute
+	// recheckDelay is the shortest wait between schedule checks, so replicas that lose
+	// the window lock don't spin while the winner's run is being recorded
+	recheckDelay = time.Minute
+	// retryDelay is how soon a failed run is retried, or the schedule rechecked when
+	// the run history can't be read; jobs with shorter intervals just wait an interval
+	retryDelay = 5 * time.Minute
+)
+
+// JobFunc does the work and returns a short human-readable summary
+type JobFunc func(ctx context.Context) (string, error)
+
+// Job is a named unit of periodic work
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Run      JobFunc
+}
+
+// Run is a record of one job execution
+type Run struct {
+	ID         uuid.UUID  `json:"id"`
+	JobName    string     `json:"job_name"`
+	Trigger    string     `json:"trigger"`
+	Instance   string     `json:"instance"`
+	Status     string     `json:"status"`
+	Result     string     `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobInfo describes a registered job for the admin API
+type JobInfo struct {
+	Name     string `json:"name"`
+	Interval string `json:"interval"`
+	LastRun  *Run   `json:"last_run,omitempty"`
+}
+
+// Repository stores run history
+type Repository interface {
+	CreateJobRun(ctx context.Context, run *Run) error
+	FinishJobRun(ctx context.Context, run *Run) error
+	ListJobRuns(ctx context.Context, jobName string, limit int) ([]Run, error)
+}
+
+// locker holds expiring locks shared by every replica
+type locker interface {
+	// acquire takes key for owner unless someone else holds it
+	acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// release drops key if owner still holds it
+	release(ctx context.Context, key, owner string) error
+}
+
+// releaseScript deletes a lock only if we still own it
+var releaseScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+type redisLocker struct {
+	client *redis.Client
+}
+
+func (l *redisLocker) acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return l.client.SetNX(ctx, key, owner, ttl).Result()
+}
+
+func (l *redisLocker) release(ctx context.Context, key, owner string) error {
+	return releaseScript.Run(ctx, l.client, []string{key}, owner).Err()
+}
+
+type Scheduler struct {
+	locks    locker // nil on a single instance
+	repo     Repository
+	instance string
+
+	mu      sync.RWMutex
+	jobs    map[string]Job
+	running map[string]bool // local guard; Redis guards across replicas
+
+	ctx    context.Context // scheduler lifetime; manual runs outlive the request that triggered them
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler. redisClient may be nil (single instance, no locking).
+func NewScheduler(redisClient *redis.Client, repo Repository) *Scheduler {
+	host, _ := os.Hostname()
+	var locks locker
+	if redisClient != nil {
+		locks = &redisLocker{client: redisClient}
+	}
+	return &Scheduler{
+		locks:    locks,
+		repo:     repo,
+		instance: fmt.Sprintf("%s-%s", host, uuid.New().String()[:8]),
+		jobs:     make(map[string]Job),
+		running:  make(map[string]bool),
+		ctx:      context.Background(),
+	}
+}
+
+// Register adds a job. Must be called before Start.
+func (s *Scheduler) Register(job Job) {
+	if job.Timeout == 0 {
+		job.Timeout = 10 * time.Minute
+	}
+	s.mu.Lock()
+	s.jobs[job.Name] = job
+	s.mu.Unlock()
+}
+
+// Start runs every registered job on its interval until Stop is called
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.ctx = ctx
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+	log.Printf("[JOBS] Scheduler started with %d jobs (instance %s)", len(s.jobs), s.instance)
+}
+
+// Stop stops scheduling and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	// Resume from the last recorded run rather than the process start, so deploys
+	// don't keep pushing back daily and weekly jobs; overdue jobs run right away
+	timer := time.NewTimer(s.untilDue(ctx, job))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			s.runScheduled(ctx, job)
+			timer.Reset(max(s.untilDue(ctx, job), recheckDelay))
+		}
+	}
+}
+
+func (s *Scheduler) runScheduled(ctx context.Context, job Job) {
+	// Claim this interval's slot so other replicas skip it
+	ok, err := s.acquire(ctx, s.windowKey(job.Name), job.Interval-job.Interval/10)
+	if err != nil {
+		log.Printf("[JOBS] %s: failed to acquire schedule lock: %v", job.Name, err)
+		return
+	}
+	if !ok {
+		return
+	}
+	run, err := s.execute(ctx, job, TriggerSchedule)
+	if err != nil {
+		if !errors.Is(err, ErrJobRunning) {
+			log.Printf("[JOBS] %s: %v", job.Name, err)
+		}
+		return
+	}
+	// Free the slot so whichever replica is first can retry
+	if run.Status == StatusFailed {
+		s.release(s.windowKey(job.Name))
+	}
+}
+
+// untilDue returns how long until a job is next due: one interval after its last run, or
+// retryDelay after it if that run failed
+func (s *Scheduler) untilDue(ctx context.Context, job Job) time.Duration {
+	retry := min(retryDelay, job.Interval)
+	runs, err := s.repo.ListJobRuns(ctx, job.Name, 1)
+	if err != nil {
+		log.Printf("[JOBS] %s: failed to get last run: %v", job.Name, err)
+		return retry
+	}
+	if len(runs) == 0 {
+		return 0
+	}
+	last := runs[0]
+	if last.Status == StatusFailed {
+		return max(time.Until(last.StartedAt.Add(retry)), 0)
+	}
+	return max(time.Until(last.StartedAt.Add(job.Interval)), 0)
+}
+
+// Trigger starts a job in the background (admin API) and returns its run record, which
+// is still running. Returns ErrJobRunning if it's running anywhere.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*Run, error) {
+	s.mu.RLock()
+	job, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	run, err := s.begin(ctx, job, TriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	started := *run
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.finish(s.ctx, job, run)
+	}()
+	return &started, nil
+}
+
+// Jobs lists registered jobs with their most recent run
+func (s *Scheduler) Jobs(ctx context.Context) ([]JobInfo, error) {
+	s.mu.RLock()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		infos = append(infos, JobInfo{Name: job.Name, Interval: job.Interval.String()})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	for i := range infos {
+		runs, err := s.repo.ListJobRuns(ctx, infos[i].Name, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			infos[i].LastRun = &runs[0]
+		}
+	}
+	return infos, nil
+}
+
+// History returns the most recent runs of a job
+func (s *Scheduler) History(ctx context.Context, name string, limit int) ([]Run, error) {
+	s.mu.RLock()
+	_, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return s.repo.ListJobRuns(ctx, name, limit)
+}
+
+// execute runs a job under its run lock and records the run
+func (s *Scheduler) execute(ctx context.Context, job Job, trigger string) (*Run, error) {
+	run, err := s.begin(ctx, job, trigger)
+	if err != nil {
+		return nil, err
+	}
+	s.finish(ctx, job, run)
+	return run, nil
+}
+
+// begin takes a job's run lock and records the start of a run; finish must follow
+func (s *Scheduler) begin(ctx context.Context, job Job, trigger string) (*Run, error) {
+	s.mu.Lock()
+	if s.running[job.Name] {
+		s.mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	s.running[job.Name] = true
+	s.mu.Unlock()
+
+	ok, err := s.acquire(ctx, s.runKey(job.Name), runLockTTL)
+	if err != nil || !ok {
+		s.clearRunning(job.Name)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrJobRunning
+	}
+
+	run := &Run{
+		ID:        uuid.New(),
+		JobName:   job.Name,
+		Trigger:   trigger,
+		Instance:  s.instance,
+		Status:    StatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := s.repo.CreateJobRun(ctx, run); err != nil {
+		log.Printf("[JOBS] %s: failed to record run: %v", job.Name, err)
+	}
+	return run, nil
+}
+
+// finish runs a started job, records the outcome and releases its locks
+func (s *Scheduler) finish(ctx context.Context, job Job, run *Run) {
+	defer s.clearRunning(job.Name)
+	defer s.release(s.runKey(job.Name))
+
+	jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	result, jobErr := s.safeRun(jobCtx, job)
+	cancel()
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Result = result
+	if jobErr != nil {
+		run.Status = StatusFailed
+		run.Error = jobErr.Error()
+	} else {
+		run.Status = StatusSucceeded
+	}
+
+	// Record the outcome even if the scheduler is shutting down
+	if err := s.repo.FinishJobRun(context.Background(), run); err != nil {
+		log.Printf("[JOBS] %s: failed to record run result: %v", job.Name, err)
+	}
+
+	log.Printf("[JOBS] %s (%s) %s in %s: %s", job.Name, run.Trigger, run.Status, finished.Sub(run.StartedAt).Round(time.Millisecond), result)
+}
+
+func (s *Scheduler) clearRunning(name string) {
+	s.mu.Lock()
+	delete(s.running, name)
+	s.mu.Unlock()
+}
+
+// safeRun keeps a panicking job from taking down the process
+func (s *Scheduler) safeRun(ctx context.Context, job Job) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (s *Scheduler) windowKey(name string) string {
+	return lockKeyPrefix + name + ":window"
+}
+
+func (s *Scheduler) runKey(name string) string {
+	return lockKeyPrefix + name + ":running"
+}
+
+func (s *Scheduler) acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if s.locks == nil {
+		return true, nil
+	}
+	return s.locks.acquire(ctx, key, s.instance, ttl)
+}
+
+func (s *Scheduler) release(key string) {
+	if s.locks == nil {
+		return
+	}
+	if err := s.locks.release(context.Background(), key, s.instance); err != nil {
+		log.Printf("[JOBS] failed to release lock %s: %v", key, err)
+	}
+}
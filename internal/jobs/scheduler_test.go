@@ -0,0 +1,274 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRuns is an in-memory Repository
+type memoryRuns struct {
+	mu   sync.Mutex
+	runs []Run // oldest first
+	err  error // returned by ListJobRuns when set
+}
+
+func (r *memoryRuns) CreateJobRun(ctx context.Context, run *Run) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+func (r *memoryRuns) FinishJobRun(ctx context.Context, run *Run) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.runs {
+		if r.runs[i].ID == run.ID {
+			r.runs[i] = *run
+		}
+	}
+	return nil
+}
+
+func (r *memoryRuns) ListJobRuns(ctx context.Context, jobName string, limit int) ([]Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	var runs []Run
+	for i := len(r.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		if r.runs[i].JobName == jobName {
+			runs = append(runs, r.runs[i])
+		}
+	}
+	return runs, nil
+}
+
+// memoryLocks is a locker shared by the schedulers standing in for replicas
+type memoryLocks struct {
+	mu    sync.Mutex
+	owner map[string]string
+}
+
+func newMemoryLocks() *memoryLocks {
+	return &memoryLocks{owner: make(map[string]string)}
+}
+
+func (l *memoryLocks) acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, held := l.owner[key]; held {
+		return false, nil
+	}
+	l.owner[key] = owner
+	return true, nil
+}
+
+func (l *memoryLocks) release(ctx context.Context, key, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.owner[key] == owner {
+		delete(l.owner, key)
+	}
+	return nil
+}
+
+func (l *memoryLocks) held(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.owner[key]
+	return ok
+}
+
+// newReplica returns a scheduler sharing locks and run history with any others made from them
+func newReplica(locks *memoryLocks, repo *memoryRuns, jobs ...Job) *Scheduler {
+	s := NewScheduler(nil, repo)
+	s.locks = locks
+	for _, job := range jobs {
+		s.Register(job)
+	}
+	return s
+}
+
+// countingJob counts its runs and fails while fail is set
+func countingJob(name string, runs *atomic.Int32, fail *atomic.Bool) Job {
+	return Job{
+		Name:     name,
+		Interval: 24 * time.Hour,
+		Run: func(ctx context.Context) (string, error) {
+			runs.Add(1)
+			if fail != nil && fail.Load() {
+				return "", errors.New("boom")
+			}
+			return "done", nil
+		},
+	}
+}
+
+func TestUntilDue(t *testing.T) {
+	daily := Job{Name: "daily", Interval: 24 * time.Hour}
+	minutely := Job{Name: "minutely", Interval: time.Minute}
+	ago := func(d time.Duration) time.Time { return time.Now().Add(-d) }
+
+	tests := []struct {
+		name    string
+		job     Job
+		last    *Run
+		listErr error
+		want    time.Duration
+	}{
+		{"never run", daily, nil, nil, 0},
+		{"succeeded recently", daily, &Run{Status: StatusSucceeded, StartedAt: ago(time.Hour)}, nil, 23 * time.Hour},
+		{"succeeded over an interval ago", daily, &Run{Status: StatusSucceeded, StartedAt: ago(25 * time.Hour)}, nil, 0},
+		{"still running", daily, &Run{Status: StatusRunning, StartedAt: ago(time.Hour)}, nil, 23 * time.Hour},
+		{"failed recently", daily, &Run{Status: StatusFailed, StartedAt: ago(time.Minute)}, nil, retryDelay - time.Minute},
+		{"failed a while ago", daily, &Run{Status: StatusFailed, StartedAt: ago(time.Hour)}, nil, 0},
+		{"failed with a short interval", minutely, &Run{Status: StatusFailed, StartedAt: ago(30 * time.Second)}, nil, 30 * time.Second},
+		{"history unavailable", daily, nil, errors.New("db down"), retryDelay},
+		{"history unavailable with a short interval", minutely, nil, errors.New("db down"), time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryRuns{err: tt.listErr}
+			if tt.last != nil {
+				tt.last.JobName = tt.job.Name
+				repo.runs = []Run{*tt.last}
+			}
+			s := newReplica(newMemoryLocks(), repo)
+
+			got := s.untilDue(context.Background(), tt.job)
+			assert.InDelta(t, tt.want, got, float64(time.Second), "got %s, want %s", got, tt.want)
+		})
+	}
+}
+
+func TestUntilDue_UsesTheLatestRun(t *testing.T) {
+	job := Job{Name: "daily", Interval: 24 * time.Hour}
+	repo := &memoryRuns{runs: []Run{
+		{JobName: job.Name, Status: StatusSucceeded, StartedAt: time.Now().Add(-48 * time.Hour)},
+		{JobName: "other", Status: StatusFailed, StartedAt: time.Now()},
+		{JobName: job.Name, Status: StatusSucceeded, StartedAt: time.Now().Add(-2 * time.Hour)},
+	}}
+
+	got := newReplica(newMemoryLocks(), repo).untilDue(context.Background(), job)
+	assert.InDelta(t, 22*time.Hour, got, float64(time.Second))
+}
+
+func TestRunScheduled_WindowLockRunsOncePerInterval(t *testing.T) {
+	var runs atomic.Int32
+	job := countingJob("digest", &runs, nil)
+	locks, repo := newMemoryLocks(), &memoryRuns{}
+	a, b := newReplica(locks, repo, job), newReplica(locks, repo, job)
+
+	a.runScheduled(context.Background(), job)
+	b.runScheduled(context.Background(), job)
+	a.runScheduled(context.Background(), job)
+
+	assert.EqualValues(t, 1, runs.Load())
+	assert.True(t, locks.held(a.windowKey(job.Name)), "the window stays claimed after a successful run")
+	assert.False(t, locks.held(a.runKey(job.Name)), "the run lock is released")
+	require.Len(t, repo.runs, 1)
+	assert.Equal(t, StatusSucceeded, repo.runs[0].Status)
+	assert.Equal(t, TriggerSchedule, repo.runs[0].Trigger)
+}
+
+func TestRunScheduled_FailedRunIsRetried(t *testing.T) {
+	var runs atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	job := countingJob("digest", &runs, &fail)
+	locks, repo := newMemoryLocks(), &memoryRuns{}
+	a, b := newReplica(locks, repo, job), newReplica(locks, repo, job)
+
+	a.runScheduled(context.Background(), job)
+	require.Len(t, repo.runs, 1)
+	assert.Equal(t, StatusFailed, repo.runs[0].Status)
+	assert.Equal(t, "boom", repo.runs[0].Error)
+	assert.False(t, locks.held(a.windowKey(job.Name)), "a failed run frees the window")
+	assert.InDelta(t, retryDelay, b.untilDue(context.Background(), job), float64(time.Second))
+
+	// Any replica can pick up the retry
+	fail.Store(false)
+	b.runScheduled(context.Background(), job)
+	assert.EqualValues(t, 2, runs.Load())
+	assert.Equal(t, StatusSucceeded, repo.runs[1].Status)
+	assert.InDelta(t, job.Interval, b.untilDue(context.Background(), job), float64(time.Second))
+}
+
+func TestRunScheduled_PanicIsRecordedAsFailure(t *testing.T) {
+	job := Job{Name: "panics", Interval: time.Hour, Run: func(ctx context.Context) (string, error) {
+		panic("nil map")
+	}}
+	repo := &memoryRuns{}
+	s := newReplica(newMemoryLocks(), repo, job)
+
+	s.runScheduled(context.Background(), job)
+	require.Len(t, repo.runs, 1)
+	assert.Equal(t, StatusFailed, repo.runs[0].Status)
+	assert.Contains(t, repo.runs[0].Error, "nil map")
+}
+
+func TestTrigger(t *testing.T) {
+	started, unblock := make(chan struct{}), make(chan struct{})
+	job := Job{Name: "cleanup", Interval: time.Hour, Run: func(ctx context.Context) (string, error) {
+		close(started)
+		<-unblock
+		return "cleaned", nil
+	}}
+	locks, repo := newMemoryLocks(), &memoryRuns{}
+	a, b := newReplica(locks, repo, job), newReplica(locks, repo, job)
+	ctx := context.Background()
+
+	run, err := a.Trigger(ctx, job.Name)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, run.Status)
+	assert.Equal(t, TriggerManual, run.Trigger)
+	<-started
+
+	// Running here and, through the run lock, on every other replica
+	_, err = a.Trigger(ctx, job.Name)
+	assert.ErrorIs(t, err, ErrJobRunning)
+	_, err = b.Trigger(ctx, job.Name)
+	assert.ErrorIs(t, err, ErrJobRunning)
+	b.runScheduled(ctx, job)
+	assert.Len(t, repo.runs, 1, "a scheduled run can't start either")
+
+	close(unblock)
+	a.Stop()
+
+	runs, err := a.History(ctx, job.Name, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, run.ID, runs[0].ID)
+	assert.Equal(t, StatusSucceeded, runs[0].Status)
+	assert.Equal(t, "cleaned", runs[0].Result)
+	assert.False(t, locks.held(a.runKey(job.Name)))
+}
+
+func TestTrigger_UnknownJob(t *testing.T) {
+	s := newReplica(newMemoryLocks(), &memoryRuns{})
+	_, err := s.Trigger(context.Background(), "nope")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestTrigger_CanRunAgainOnceFinished(t *testing.T) {
+	var runs atomic.Int32
+	job := countingJob("digest", &runs, nil)
+	s := newReplica(newMemoryLocks(), &memoryRuns{}, job)
+
+	for i := 0; i < 2; i++ {
+		_, err := s.Trigger(context.Background(), job.Name)
+		require.NoError(t, err)
+		s.Stop()
+	}
+	assert.EqualValues(t, 2, runs.Load())
+}
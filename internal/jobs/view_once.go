@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobSweepViewOnce deletes view-once image objects once they can no longer be shown
+const JobSweepViewOnce = "sweep_view_once"
+
+const (
+	// ViewOnceViewGrace outlives the presigned link handed out on view, so the object
+	// isn't removed while the recipient is still loading it
+	ViewOnceViewGrace = 5 * time.Minute
+
+	// ViewOnceUnopenedTTL is how long an unopened view-once image stays available
+	ViewOnceUnopenedTTL = 7 * 24 * time.Hour
+
+	viewOnceSweepBatch = 500
+)
+
+// ViewOnceObject is a view-once image whose stored object should be deleted
+type ViewOnceObject struct {
+	MessageID  uuid.UUID
+	ObjectName string
+}
+
+// ViewOnceRepository finds view-once images due for deletion
+type ViewOnceRepository interface {
+	GetViewOnceObjectsToSweep(ctx context.Context, viewedBefore, sentBefore time.Time, limit int) ([]ViewOnceObject, error)
+	MarkViewOnceObjectDeleted(ctx context.Context, messageID uuid.UUID) error
+}
+
+// ObjectDeleter removes objects from private storage
+type ObjectDeleter interface {
+	DeletePrivate(ctx context.Context, objectName string) error
+}
+
+// RegisterViewOnceSweep registers the view-once image sweep
+func RegisterViewOnceSweep(s *Scheduler, repo ViewOnceRepository, store ObjectDeleter) {
+	s.Register(Job{
+		Name:     JobSweepViewOnce,
+		Interval: 10 * time.Minute,
+		Run:      SweepViewOnceJob(repo, store),
+	})
+}
+
+// SweepViewOnceJob deletes objects of view-once images that were viewed, unsent or left unopened too long
+func SweepViewOnceJob(repo ViewOnceRepository, store ObjectDeleter) JobFunc {
+	return func(ctx context.Context) (string, error) {
+		now := time.Now()
+		objects, err := repo.GetViewOnceObjectsToSweep(ctx, now.Add(-ViewOnceViewGrace), now.Add(-ViewOnceUnopenedTTL), viewOnceSweepBatch)
+		if err != nil {
+			return "", err
+		}
+
+		deleted, failed := 0, 0
+		for _, o := range objects {
+			if ctx.Err() != nil {
+				return fmt.Sprintf("deleted %d objects, %d failed (interrupted)", deleted, failed), ctx.Err()
+			}
+			if err := store.DeletePrivate(ctx, o.ObjectName); err != nil {
+				log.Printf("[JOBS] deleting view-once object for message %s failed: %v", o.MessageID, err)
+				failed++
+				continue
+			}
+			if err := repo.MarkViewOnceObjectDeleted(ctx, o.MessageID); err != nil {
+				log.Printf("[JOBS] marking view-once object deleted for message %s failed: %v", o.MessageID, err)
+				failed++
+				continue
+			}
+			deleted++
+		}
+		return fmt.Sprintf("deleted %d objects, %d failed", deleted, failed), nil
+	}
+}
@@ -393,3 +393,119 @@ func (r *CreditRepository) UsePremiumLikeAtomic(ctx context.Context, userID uuid
 	}
 	return nil
 }
+
+// Boost methods
+
+// GetActiveBoost returns the user's currently running boost, or nil if none
+func (r *CreditRepository) GetActiveBoost(ctx context.Context, userID uuid.UUID) (*credit.Boost, error) {
+	query := `
+		SELECT id, user_id, source, credits_spent, started_at, expires_at
+		FROM boosts
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY expires_at DESC
+		LIMIT 1
+	`
+	var b credit.Boost
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&b.ID, &b.UserID, &b.Source, &b.CreditsSpent, &b.StartedAt, &b.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ActivateBoost atomically consumes a weekly boost (or credits once the weekly allowance is used)
+// and starts a boost window. Returns credit.ErrBoostActive if a boost is already running.
+func (r *CreditRepository) ActivateBoost(ctx context.Context, userID uuid.UUID, weeklyLimit, creditCost int, duration time.Duration) (*credit.Boost, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Ensure a credits row exists, then lock it to serialize concurrent boost requests
+	_, err = tx.Exec(ctx, `
+		INSERT INTO credits (user_id, balance, bonus_likes)
+		VALUES ($1, 0, 0)
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance, boostsUsed int
+	var lastBoostReset *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT balance, COALESCE(boosts_used, 0), last_boost_reset
+		FROM credits
+		WHERE user_id = $1
+		FOR UPDATE
+	`, userID).Scan(&balance, &boostsUsed, &lastBoostReset)
+	if err != nil {
+		return nil, err
+	}
+
+	var active bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM boosts WHERE user_id = $1 AND expires_at > NOW())
+	`, userID).Scan(&active)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, credit.ErrBoostActive
+	}
+
+	// Weekly allowance resets 7 days after the last reset
+	now := time.Now().UTC()
+	if lastBoostReset == nil || now.Sub(lastBoostReset.UTC()) >= 7*24*time.Hour {
+		boostsUsed = 0
+		lastBoostReset = &now
+	}
+
+	b := &credit.Boost{
+		ID:        uuid.New(),
+		UserID:    userID,
+		StartedAt: now,
+		ExpiresAt: now.Add(duration),
+	}
+
+	if boostsUsed < weeklyLimit {
+		b.Source = credit.BoostSourceWeekly
+		boostsUsed++
+	} else {
+		if balance < creditCost {
+			return nil, credit.ErrInsufficientCredits
+		}
+		b.Source = credit.BoostSourceCredits
+		b.CreditsSpent = creditCost
+		balance -= creditCost
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE credits
+		SET balance = $2, boosts_used = $3, last_boost_reset = $4
+		WHERE user_id = $1
+	`, userID, balance, boostsUsed, lastBoostReset)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO boosts (id, user_id, source, credits_spent, started_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, b.ID, b.UserID, b.Source, b.CreditsSpent, b.StartedAt, b.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/feels/feels/internal/domain/dateplan"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DatePlanRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDatePlanRepository(db *pgxpool.Pool) *DatePlanRepository {
+	return &DatePlanRepository{db: db}
+}
+
+const datePlanColumns = `id, match_id, proposer_id, invitee_id, scheduled_at, place_name, place_address,
+	lat, lng, notes, status, responded_at, created_at, updated_at`
+
+const checkInColumns = `id, plan_id, user_id, contact_name, contact_email, contact_phone, check_in_at,
+	reminded_at, checked_in_at, escalated_at, cancelled_at, created_at, updated_at`
+
+func scanDatePlan(row pgx.Row) (*dateplan.DatePlan, error) {
+	var p dateplan.DatePlan
+	err := row.Scan(
+		&p.ID, &p.MatchID, &p.ProposerID, &p.InviteeID, &p.ScheduledAt, &p.PlaceName, &p.PlaceAddress,
+		&p.Lat, &p.Lng, &p.Notes, &p.Status, &p.RespondedAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanCheckIn(row pgx.Row) (*dateplan.CheckIn, error) {
+	var ci dateplan.CheckIn
+	var name, email, phone *string
+	err := row.Scan(
+		&ci.ID, &ci.PlanID, &ci.UserID, &name, &email, &phone, &ci.CheckInAt,
+		&ci.RemindedAt, &ci.CheckedInAt, &ci.EscalatedAt, &ci.CancelledAt, &ci.CreatedAt, &ci.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		ci.Contact = &dateplan.TrustedContact{Name: *name, Email: email, Phone: phone}
+	}
+	return &ci, nil
+}
+
+func (r *DatePlanRepository) CreatePlan(ctx context.Context, p *dateplan.DatePlan) error {
+	query := `
+		INSERT INTO date_plans (` + datePlanColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+	_, err := r.db.Exec(ctx, query,
+		p.ID, p.MatchID, p.ProposerID, p.InviteeID, p.ScheduledAt, p.PlaceName, p.PlaceAddress,
+		p.Lat, p.Lng, p.Notes, p.Status, p.RespondedAt, p.CreatedAt, p.UpdatedAt,
+	)
+	return err
+}
+
+func (r *DatePlanRepository) GetPlan(ctx context.Context, planID uuid.UUID) (*dateplan.DatePlan, error) {
+	query := `SELECT ` + datePlanColumns + ` FROM date_plans WHERE id = $1`
+
+	p, err := scanDatePlan(r.db.QueryRow(ctx, query, planID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, dateplan.ErrPlanNotFound
+	}
+	return p, err
+}
+
+// GetPlansByMatch returns a match's plans, soonest date first
+func (r *DatePlanRepository) GetPlansByMatch(ctx context.Context, matchID uuid.UUID) ([]dateplan.DatePlan, error) {
+	query := `SELECT ` + datePlanColumns + ` FROM date_plans WHERE match_id = $1 ORDER BY scheduled_at ASC`
+
+	rows, err := r.db.Query(ctx, query, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []dateplan.DatePlan
+	for rows.Next() {
+		p, err := scanDatePlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *p)
+	}
+	return plans, rows.Err()
+}
+
+// UpdatePlanStatus sets a plan's status, stamping responded_at when it is accepted or declined
+func (r *DatePlanRepository) UpdatePlanStatus(ctx context.Context, planID uuid.UUID, status string) error {
+	query := `
+		UPDATE date_plans SET
+			status = $2,
+			responded_at = CASE WHEN $2 IN ('accepted', 'declined') THEN NOW() ELSE responded_at END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(ctx, query, planID, status)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return dateplan.ErrPlanNotFound
+	}
+	return nil
+}
+
+// UpsertCheckIn creates or replaces the user's check-in, clearing any earlier progress
+func (r *DatePlanRepository) UpsertCheckIn(ctx context.Context, ci *dateplan.CheckIn) error {
+	var name, email, phone *string
+	if ci.Contact != nil {
+		name, email, phone = &ci.Contact.Name, ci.Contact.Email, ci.Contact.Phone
+	}
+
+	query := `
+		INSERT INTO date_plan_check_ins (id, plan_id, user_id, contact_name, contact_email, contact_phone, check_in_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (plan_id, user_id) DO UPDATE SET
+			contact_name = EXCLUDED.contact_name,
+			contact_email = EXCLUDED.contact_email,
+			contact_phone = EXCLUDED.contact_phone,
+			check_in_at = EXCLUDED.check_in_at,
+			reminded_at = NULL,
+			checked_in_at = NULL,
+			escalated_at = NULL,
+			cancelled_at = NULL,
+			updated_at = NOW()
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query,
+		ci.ID, ci.PlanID, ci.UserID, name, email, phone, ci.CheckInAt, ci.CreatedAt, ci.UpdatedAt,
+	).Scan(&ci.ID, &ci.CreatedAt)
+}
+
+func (r *DatePlanRepository) GetCheckIn(ctx context.Context, planID, userID uuid.UUID) (*dateplan.CheckIn, error) {
+	query := `SELECT ` + checkInColumns + ` FROM date_plan_check_ins WHERE plan_id = $1 AND user_id = $2`
+
+	ci, err := scanCheckIn(r.db.QueryRow(ctx, query, planID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, dateplan.ErrCheckInNotFound
+	}
+	return ci, err
+}
+
+func (r *DatePlanRepository) GetCheckInsByPlan(ctx context.Context, planID uuid.UUID) ([]dateplan.CheckIn, error) {
+	query := `SELECT ` + checkInColumns + ` FROM date_plan_check_ins WHERE plan_id = $1 ORDER BY created_at`
+	return r.queryCheckIns(ctx, query, planID)
+}
+
+func (r *DatePlanRepository) MarkCheckedIn(ctx context.Context, checkInID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE date_plan_check_ins SET checked_in_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND checked_in_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, checkInID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// CancelCheckIn stops a user's pending check-in on a plan, reporting whether there was one
+func (r *DatePlanRepository) CancelCheckIn(ctx context.Context, planID, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE date_plan_check_ins SET cancelled_at = NOW(), updated_at = NOW()
+		WHERE plan_id = $1 AND user_id = $2 AND checked_in_at IS NULL AND escalated_at IS NULL AND cancelled_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, planID, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetCheckInsToRemind returns pending check-ins whose time has come and haven't been reminded
+func (r *DatePlanRepository) GetCheckInsToRemind(ctx context.Context, now time.Time, limit int) ([]dateplan.CheckIn, error) {
+	query := `
+		SELECT ` + checkInColumns + ` FROM date_plan_check_ins
+		WHERE check_in_at <= $1 AND reminded_at IS NULL
+		  AND checked_in_at IS NULL AND escalated_at IS NULL AND cancelled_at IS NULL
+		ORDER BY check_in_at
+		LIMIT $2
+	`
+	return r.queryCheckIns(ctx, query, now, limit)
+}
+
+// GetCheckInsToEscalate returns pending check-ins that were due before dueBefore
+func (r *DatePlanRepository) GetCheckInsToEscalate(ctx context.Context, dueBefore time.Time, limit int) ([]dateplan.CheckIn, error) {
+	query := `
+		SELECT ` + checkInColumns + ` FROM date_plan_check_ins
+		WHERE check_in_at <= $1
+		  AND checked_in_at IS NULL AND escalated_at IS NULL AND cancelled_at IS NULL
+		ORDER BY check_in_at
+		LIMIT $2
+	`
+	return r.queryCheckIns(ctx, query, dueBefore, limit)
+}
+
+func (r *DatePlanRepository) MarkReminded(ctx context.Context, checkInID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE date_plan_check_ins SET reminded_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND reminded_at IS NULL AND checked_in_at IS NULL AND cancelled_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, checkInID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (r *DatePlanRepository) MarkEscalated(ctx context.Context, checkInID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE date_plan_check_ins SET escalated_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND escalated_at IS NULL AND checked_in_at IS NULL AND cancelled_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, checkInID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (r *DatePlanRepository) queryCheckIns(ctx context.Context, query string, args ...interface{}) ([]dateplan.CheckIn, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkIns []dateplan.CheckIn
+	for rows.Next() {
+		ci, err := scanCheckIn(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkIns = append(checkIns, *ci)
+	}
+	return checkIns, rows.Err()
+}
+
+func (r *DatePlanRepository) AddAudit(ctx context.Context, e *dateplan.AuditEntry) error {
+	query := `
+		INSERT INTO date_plan_audit (id, plan_id, actor_id, action, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	var details []byte
+	if len(e.Details) > 0 {
+		details = e.Details
+	}
+	_, err := r.db.Exec(ctx, query, e.ID, e.PlanID, e.ActorID, e.Action, details, e.CreatedAt)
+	return err
+}
+
+func (r *DatePlanRepository) GetAudit(ctx context.Context, planID uuid.UUID) ([]dateplan.AuditEntry, error) {
+	query := `
+		SELECT id, plan_id, actor_id, action, details, created_at
+		FROM date_plan_audit WHERE plan_id = $1
+		ORDER BY created_at ASC
+	`
+	return r.queryAudit(ctx, query, planID)
+}
+
+// ListAuditByAction returns the most recent audit entries with the given action
+func (r *DatePlanRepository) ListAuditByAction(ctx context.Context, action string, limit int) ([]dateplan.AuditEntry, error) {
+	query := `
+		SELECT id, plan_id, actor_id, action, details, created_at
+		FROM date_plan_audit WHERE action = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	return r.queryAudit(ctx, query, action, limit)
+}
+
+// CountAuditByActor counts the actor's entries with any of the given actions since a time
+func (r *DatePlanRepository) CountAuditByActor(ctx context.Context, actorID uuid.UUID, actions []string, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM date_plan_audit
+		WHERE actor_id = $1 AND action = ANY($2) AND created_at > $3
+	`
+	var count int
+	err := r.db.QueryRow(ctx, query, actorID, actions, since).Scan(&count)
+	return count, err
+}
+
+func (r *DatePlanRepository) queryAudit(ctx context.Context, query string, args ...interface{}) ([]dateplan.AuditEntry, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []dateplan.AuditEntry
+	for rows.Next() {
+		var e dateplan.AuditEntry
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.PlanID, &e.ActorID, &e.Action, &details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(details) > 0 {
+			e.Details = details
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
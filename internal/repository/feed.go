@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/feels/feels/internal/domain/feed"
 	"github.com/feels/feels/internal/domain/profile"
@@ -38,7 +39,8 @@ func (r *FeedRepository) GetFeedProfiles(ctx context.Context, userID uuid.UUID,
 	r.db.QueryRow(ctx, `SELECT COUNT(*) FROM (SELECT liked_id FROM likes WHERE liker_id = $1 UNION SELECT passed_id FROM passes WHERE passer_id = $1) x`, userID).Scan(&seen)
 	log.Printf("[FEED] total=%d already_seen=%d", total, seen)
 	// Complex query implementing the feed algorithm
-	// Priority: qualified_superlike > qualified_like > gap_superlike > browse
+	// Priority: qualified_superlike > qualified_like > decayed_like > gap_superlike > boosted > browse
+	// Likes older than feed.LikeDecayDays drop out of the qualified Top 10 but are not deleted
 	// Now also handles gender_presentations for per-gender visibility and bio addendum
 	query := `
 		WITH user_profile AS (
@@ -62,6 +64,9 @@ func (r *FeedRepository) GetFeedProfiles(ctx context.Context, userID uuid.UUID,
 		shadowbanned_users AS (
 			SELECT id FROM users WHERE moderation_status = 'shadowbanned'
 		),
+		boosted_users AS (
+			SELECT DISTINCT user_id FROM boosts WHERE expires_at > NOW()
+		),
 		candidates AS (
 			SELECT
 				p.*,
@@ -76,16 +81,24 @@ func (r *FeedRepository) GetFeedProfiles(ctx context.Context, userID uuid.UUID,
 				END AS distance,
 				CASE
 					WHEN l.is_superlike = true AND
+						 l.created_at > NOW() - make_interval(days => $7) AND
 						 p.gender = ANY($2) AND
 						 EXTRACT(YEAR FROM AGE(p.dob)) BETWEEN $3 AND $4
 					THEN 1  -- qualified_superlike
 					WHEN l.id IS NOT NULL AND
+						 l.created_at > NOW() - make_interval(days => $7) AND
 						 p.gender = ANY($2) AND
 						 EXTRACT(YEAR FROM AGE(p.dob)) BETWEEN $3 AND $4
 					THEN 2  -- qualified_like
+					WHEN l.id IS NOT NULL AND
+						 p.gender = ANY($2) AND
+						 EXTRACT(YEAR FROM AGE(p.dob)) BETWEEN $3 AND $4
+					THEN 3  -- decayed_like (unprocessed past decay window, moves to position 11+)
 					WHEN l.is_superlike = true
-					THEN 3  -- gap_superlike
-					ELSE 4  -- browse
+					THEN 4  -- gap_superlike
+					WHEN p.user_id IN (SELECT user_id FROM boosted_users)
+					THEN 5  -- boosted
+					ELSE 6  -- browse
 				END AS priority,
 				l.is_superlike,
 				l.created_at AS liked_at,
@@ -119,11 +132,14 @@ func (r *FeedRepository) GetFeedProfiles(ctx context.Context, userID uuid.UUID,
 			user_id, name, dob, gender, gender_identity, zip_code, neighborhood, bio,
 			kink_level, COALESCE(looking_for, ARRAY[]::TEXT[]) as looking_for, zodiac, religion, has_kids, wants_kids,
 			alcohol, weed, work_for_money, work_for_passion, lat, lng, is_verified, last_active, created_at,
-			age, distance, priority, gender_presentations, viewer_gender
+			age, distance,
+			-- Boosts only rank up for nearby viewers (known distance within range)
+			CASE WHEN priority = 5 AND distance IS NULL THEN 6 ELSE priority END AS priority,
+			gender_presentations, viewer_gender
 		FROM candidates
-		WHERE (priority <= 3) OR (
-			-- For browse, apply all search criteria
-			priority = 4 AND
+		WHERE (priority <= 4) OR (
+			-- For boosted and browse, apply all search criteria
+			priority >= 5 AND
 			gender = ANY($2) AND
 			age BETWEEN $3 AND $4 AND
 			(distance IS NULL OR distance <= $5)
@@ -139,6 +155,7 @@ func (r *FeedRepository) GetFeedProfiles(ctx context.Context, userID uuid.UUID,
 		prefs.AgeMax,
 		prefs.DistanceMiles,
 		limit,
+		feed.LikeDecayDays,
 	)
 	if err != nil {
 		return nil, err
@@ -167,7 +184,11 @@ func (r *FeedRepository) GetFeedProfiles(ctx context.Context, userID uuid.UUID,
 		case 2:
 			fp.Priority = feed.PriorityQualifiedLike
 		case 3:
+			fp.Priority = feed.PriorityDecayedLike
+		case 4:
 			fp.Priority = feed.PriorityGapSuperlike
+		case 5:
+			fp.Priority = feed.PriorityBoosted
 		default:
 			fp.Priority = feed.PriorityBrowse
 		}
@@ -260,6 +281,7 @@ func (r *FeedRepository) getPhotos(ctx context.Context, userID uuid.UUID) ([]pro
 }
 
 // CountQueuedLikes returns the number of qualified likes waiting to be processed
+// Likes older than feed.LikeDecayDays have decayed out of the queue and are not counted
 func (r *FeedRepository) CountQueuedLikes(ctx context.Context, userID uuid.UUID, prefs *profile.Preferences) (int, error) {
 	query := `
 		SELECT COUNT(*)
@@ -270,14 +292,16 @@ func (r *FeedRepository) CountQueuedLikes(ctx context.Context, userID uuid.UUID,
 			AND l.liker_id NOT IN (SELECT passed_id FROM passes WHERE passer_id = $1)
 			AND p.gender = ANY($2)
 			AND EXTRACT(YEAR FROM AGE(p.dob)) BETWEEN $3 AND $4
+			AND l.created_at > NOW() - make_interval(days => $5)
 	`
 	var count int
-	err := r.db.QueryRow(ctx, query, userID, prefs.GendersSeeking, prefs.AgeMin, prefs.AgeMax).Scan(&count)
+	err := r.db.QueryRow(ctx, query, userID, prefs.GendersSeeking, prefs.AgeMin, prefs.AgeMax, feed.LikeDecayDays).Scan(&count)
 	return count, err
 }
 
 // CountPendingLikesForUser returns the number of pending (unprocessed) likes for a user
 // This is used for the queue slot system - counts likes not yet processed by the target
+// Decayed likes (older than feed.LikeDecayDays) no longer occupy a slot
 func (r *FeedRepository) CountPendingLikesForUser(ctx context.Context, targetUserID uuid.UUID) (int, error) {
 	query := `
 		SELECT COUNT(*)
@@ -285,14 +309,16 @@ func (r *FeedRepository) CountPendingLikesForUser(ctx context.Context, targetUse
 		WHERE l.liked_id = $1
 			AND l.liker_id NOT IN (SELECT liked_id FROM likes WHERE liker_id = $1)
 			AND l.liker_id NOT IN (SELECT passed_id FROM passes WHERE passer_id = $1)
+			AND l.created_at > NOW() - make_interval(days => $2)
 	`
 	var count int
-	err := r.db.QueryRow(ctx, query, targetUserID).Scan(&count)
+	err := r.db.QueryRow(ctx, query, targetUserID, feed.LikeDecayDays).Scan(&count)
 	return count, err
 }
 
 // CheckPremiumRequired checks if a premium like is required for liker to like target
 // Returns reason: "queue_full" (target has 10+ pending likes) or "age_range" (liker outside target's age prefs)
+// Only likes inside the decay window count toward a full queue
 func (r *FeedRepository) CheckPremiumRequired(ctx context.Context, likerID, targetID uuid.UUID, freeSlots int) (*feed.PremiumCheckResult, error) {
 	// First, get liker's profile (age and gender)
 	var likerAge int
@@ -363,6 +389,95 @@ func (r *FeedRepository) CheckPremiumRequired(ctx context.Context, likerID, targ
 	return &feed.PremiumCheckResult{RequiresPremium: false}, nil
 }
 
+// ArmQueueClearReward opens a queue-clear reward for a user whose queue is full
+// No-op if the user already has an open reward
+func (r *FeedRepository) ArmQueueClearReward(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		INSERT INTO queue_clear_rewards (user_id, armed_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (user_id) WHERE cleared_at IS NULL DO NOTHING
+	`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+// ClaimQueueClearReward closes the user's open queue-clear reward and grants bonus likes atomically
+// Returns false if there was no open reward (never armed, or already claimed by a concurrent request)
+// A queue that decay helped empty doesn't count as cleared: if a qualified like that was queued
+// when the reward was armed has since decayed without the user acting on it, the reward is
+// withdrawn instead, so the next full queue arms a fresh one
+func (r *FeedRepository) ClaimQueueClearReward(ctx context.Context, userID uuid.UUID, prefs *profile.Preferences, bonusLikes int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Locking the open ledger row serializes concurrent claims, so only the first
+	// one sees it open
+	var rewardID uuid.UUID
+	var armedAt time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT id, armed_at FROM queue_clear_rewards
+		WHERE user_id = $1 AND cleared_at IS NULL
+		FOR UPDATE
+	`, userID).Scan(&rewardID, &armedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var decayed bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM likes l
+			JOIN profiles p ON p.user_id = l.liker_id
+			WHERE l.liked_id = $1
+				AND l.liker_id NOT IN (SELECT liked_id FROM likes WHERE liker_id = $1)
+				AND l.liker_id NOT IN (SELECT passed_id FROM passes WHERE passer_id = $1)
+				AND p.gender = ANY($2)
+				AND EXTRACT(YEAR FROM AGE(p.dob)) BETWEEN $3 AND $4
+				AND l.created_at > $6 - make_interval(days => $5)
+				AND l.created_at <= NOW() - make_interval(days => $5)
+		)
+	`, userID, prefs.GendersSeeking, prefs.AgeMin, prefs.AgeMax, feed.LikeDecayDays, armedAt).Scan(&decayed)
+	if err != nil {
+		return false, err
+	}
+	if decayed {
+		if _, err := tx.Exec(ctx, `DELETE FROM queue_clear_rewards WHERE id = $1`, rewardID); err != nil {
+			return false, err
+		}
+		return false, tx.Commit(ctx)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE queue_clear_rewards SET cleared_at = NOW(), bonus_likes = $2
+		WHERE id = $1
+	`, rewardID, bonusLikes)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO credits (user_id, balance, bonus_likes)
+		VALUES ($1, 0, $2)
+		ON CONFLICT (user_id) DO UPDATE SET bonus_likes = credits.bonus_likes + $2
+	`, userID, bonusLikes)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // CreateLike creates a like record
 func (r *FeedRepository) CreateLike(ctx context.Context, like *feed.Like) error {
 	query := `
@@ -1000,3 +1115,165 @@ func (r *FeedRepository) DebugFeedFilters(ctx context.Context, userID uuid.UUID,
 
 	return result, nil
 }
+
+// Daily picks
+
+// GetDailyPicks returns the stored picks for a user on a date, in pick order
+func (r *FeedRepository) GetDailyPicks(ctx context.Context, userID uuid.UUID, date time.Time) ([]feed.DailyPick, error) {
+	query := `
+		SELECT id, user_id, pick_user_id, pick_date, position, COALESCE(priority, ''), created_at
+		FROM daily_picks
+		WHERE user_id = $1 AND pick_date = $2
+		ORDER BY position
+	`
+	rows, err := r.db.Query(ctx, query, userID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var picks []feed.DailyPick
+	for rows.Next() {
+		var p feed.DailyPick
+		if err := rows.Scan(&p.ID, &p.UserID, &p.PickUserID, &p.PickDate, &p.Position, &p.Priority, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		picks = append(picks, p)
+	}
+	return picks, rows.Err()
+}
+
+// SaveDailyPicks stores a user's picks for a date
+// If picks already exist for that date (e.g. a concurrent request saved first) this is a no-op,
+// so a day's set is never mixed or replaced
+func (r *FeedRepository) SaveDailyPicks(ctx context.Context, userID uuid.UUID, picks []feed.DailyPick, date time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize pick generation per user and day
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1::text || $2::date::text))`, userID, date)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM daily_picks WHERE user_id = $1 AND pick_date = $2)
+	`, userID, date).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	for _, p := range picks {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO daily_picks (id, user_id, pick_user_id, pick_date, position, priority, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW())
+			ON CONFLICT (user_id, pick_date, pick_user_id) DO NOTHING
+		`, p.ID, userID, p.PickUserID, date, p.Position, p.Priority)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CountPicksToday returns the number of picks stored for a user today (UTC)
+func (r *FeedRepository) CountPicksToday(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM daily_picks WHERE user_id = $1 AND pick_date = (NOW() AT TIME ZONE 'UTC')::date
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// GetDailyPickProfiles returns the stored picks for a date with their profiles
+// and whether the user has since liked, passed or matched them
+// Picks that are now blocked or shadowbanned are left out
+func (r *FeedRepository) GetDailyPickProfiles(ctx context.Context, userID uuid.UUID, date time.Time) ([]feed.DailyPickProfile, error) {
+	query := `
+		WITH user_profile AS (
+			SELECT lat, lng FROM profiles WHERE user_id = $1
+		)
+		SELECT
+			p.user_id, p.name, p.dob, p.gender, p.gender_identity, p.zip_code, p.neighborhood, p.bio,
+			p.kink_level, COALESCE(p.looking_for, ARRAY[]::TEXT[]), p.zodiac, p.religion, p.has_kids, p.wants_kids,
+			p.alcohol, p.weed, p.work_for_money, p.work_for_passion, p.lat, p.lng, p.is_verified, p.last_active, p.created_at,
+			EXTRACT(YEAR FROM AGE(p.dob))::int AS age,
+			CASE
+				WHEN up.lat IS NOT NULL AND up.lng IS NOT NULL
+					AND up.lat BETWEEN 24 AND 50 AND up.lng BETWEEN -125 AND -66
+					AND p.lat IS NOT NULL AND p.lng IS NOT NULL
+					AND p.lat BETWEEN 24 AND 50 AND p.lng BETWEEN -125 AND -66
+				THEN ROUND((3959 * acos(cos(radians(up.lat)) * cos(radians(p.lat)) * cos(radians(p.lng) - radians(up.lng)) + sin(radians(up.lat)) * sin(radians(p.lat))))::numeric, 0)::int
+				ELSE NULL
+			END AS distance,
+			COALESCE(dp.priority, ''),
+			CASE
+				WHEN EXISTS(
+					SELECT 1 FROM matches m
+					WHERE (m.user1_id = $1 AND m.user2_id = p.user_id) OR (m.user1_id = p.user_id AND m.user2_id = $1)
+				) THEN 'matched'
+				WHEN EXISTS(SELECT 1 FROM likes WHERE liker_id = $1 AND liked_id = p.user_id) THEN 'liked'
+				WHEN EXISTS(SELECT 1 FROM passes WHERE passer_id = $1 AND passed_id = p.user_id) THEN 'passed'
+				ELSE ''
+			END AS action
+		FROM daily_picks dp
+		JOIN profiles p ON p.user_id = dp.pick_user_id
+		JOIN users u ON u.id = p.user_id
+		CROSS JOIN user_profile up
+		WHERE dp.user_id = $1 AND dp.pick_date = $2
+			AND COALESCE(u.moderation_status, 'active') != 'shadowbanned'
+			AND NOT EXISTS(
+				SELECT 1 FROM blocks
+				WHERE (blocker_id = $1 AND blocked_id = p.user_id) OR (blocker_id = p.user_id AND blocked_id = $1)
+			)
+		ORDER BY dp.position
+	`
+	rows, err := r.db.Query(ctx, query, userID, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var picks []feed.DailyPickProfile
+	for rows.Next() {
+		var dp feed.DailyPickProfile
+		err := rows.Scan(
+			&dp.UserID, &dp.Name, &dp.DOB, &dp.Gender, &dp.GenderIdentity, &dp.ZipCode, &dp.Neighborhood, &dp.Bio,
+			&dp.KinkLevel, &dp.LookingFor, &dp.Zodiac, &dp.Religion, &dp.HasKids, &dp.WantsKids,
+			&dp.Alcohol, &dp.Weed, &dp.WorkForMoney, &dp.WorkForPassion, &dp.Lat, &dp.Lng, &dp.IsVerified, &dp.LastActive, &dp.CreatedAt,
+			&dp.Age, &dp.Distance, &dp.Priority, &dp.Action,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dp.Acted = dp.Action != ""
+		picks = append(picks, dp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(picks) > 0 {
+		userIDs := make([]uuid.UUID, len(picks))
+		for i, p := range picks {
+			userIDs[i] = p.UserID
+		}
+		photosMap, err := r.getPhotosForUsers(ctx, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range picks {
+			picks[i].Photos = photosMap[picks[i].UserID]
+		}
+	}
+
+	return picks, nil
+}
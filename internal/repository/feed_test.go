@@ -596,6 +596,376 @@ func TestFeedRepository_CountQueuedLikes(t *testing.T) {
 	}
 }
 
+func TestFeedRepository_GetFeedProfiles_DecayedLikesRankAfterFreshLikes(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	defer db.CleanupAll(t)
+
+	repo := repository.NewFeedRepository(db.Pool)
+	ctx := context.Background()
+
+	alice := db.CreateTestUserWithPrefs(t, "Alice", "woman", 25, []string{"man"}, 20, 40)
+
+	// Old superlike past the decay window
+	staleAt := time.Now().AddDate(0, 0, -(feed.LikeDecayDays + 1))
+	staleSuperliker := db.CreateTestUserWithPrefs(t, "StaleSuperLiker", "man", 30, []string{"woman"}, 20, 40)
+	db.CreateLikeAt(t, staleSuperliker.ID, alice.ID, true, staleAt)
+
+	// Fresh like from yesterday
+	freshLiker := db.CreateTestUserWithPrefs(t, "FreshLiker", "man", 27, []string{"woman"}, 20, 40)
+	db.CreateLikeAt(t, freshLiker.ID, alice.ID, false, time.Now().AddDate(0, 0, -1))
+
+	// Browse candidate
+	db.CreateTestUserWithPrefs(t, "BrowseMan", "man", 28, []string{"woman"}, 20, 40)
+
+	prefs := &profile.Preferences{
+		GendersSeeking: []string{"man"},
+		AgeMin:         20,
+		AgeMax:         40,
+		DistanceMiles:  100,
+	}
+	profiles, err := repo.GetFeedProfiles(ctx, alice.ID, prefs, 20)
+	if err != nil {
+		t.Fatalf("GetFeedProfiles failed: %v", err)
+	}
+	if len(profiles) < 3 {
+		t.Fatalf("Expected at least 3 profiles, got %d", len(profiles))
+	}
+
+	if profiles[0].UserID != freshLiker.ID || profiles[0].Priority != feed.PriorityQualifiedLike {
+		t.Errorf("First profile should be the fresh liker (qualified_like), got %v (%s)", profiles[0].UserID, profiles[0].Priority)
+	}
+
+	// Decayed like is not deleted, it just moves behind the qualified likes
+	if profiles[1].UserID != staleSuperliker.ID {
+		t.Errorf("Second profile should be the decayed superliker, got %v", profiles[1].UserID)
+	}
+	if profiles[1].Priority != feed.PriorityDecayedLike {
+		t.Errorf("Decayed like priority should be decayed_like, got %v", profiles[1].Priority)
+	}
+
+	for i := 2; i < len(profiles); i++ {
+		if profiles[i].Priority != feed.PriorityBrowse {
+			t.Errorf("Profile %d priority should be browse, got %v", i, profiles[i].Priority)
+		}
+	}
+}
+
+func TestFeedRepository_CountQueuedLikes_ExcludesDecayedLikes(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	defer db.CleanupAll(t)
+
+	repo := repository.NewFeedRepository(db.Pool)
+	ctx := context.Background()
+
+	alice := db.CreateTestUserWithPrefs(t, "Alice", "woman", 25, []string{"man"}, 20, 40)
+
+	// 3 fresh likes
+	for i := 0; i < 3; i++ {
+		liker := db.CreateTestUserWithPrefs(t, "FreshLiker", "man", 25+i, []string{"woman"}, 20, 40)
+		db.CreateLike(t, liker.ID, alice.ID, false)
+	}
+
+	// 4 likes older than the decay window
+	staleAt := time.Now().AddDate(0, 0, -(feed.LikeDecayDays + 5))
+	for i := 0; i < 4; i++ {
+		liker := db.CreateTestUserWithPrefs(t, "StaleLiker", "man", 25+i, []string{"woman"}, 20, 40)
+		db.CreateLikeAt(t, liker.ID, alice.ID, false, staleAt)
+	}
+
+	prefs := &profile.Preferences{
+		GendersSeeking: []string{"man"},
+		AgeMin:         20,
+		AgeMax:         40,
+		DistanceMiles:  100,
+	}
+
+	count, err := repo.CountQueuedLikes(ctx, alice.ID, prefs)
+	if err != nil {
+		t.Fatalf("CountQueuedLikes failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 queued likes (decayed excluded), got %d", count)
+	}
+
+	pending, err := repo.CountPendingLikesForUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("CountPendingLikesForUser failed: %v", err)
+	}
+	if pending != 3 {
+		t.Errorf("Expected 3 pending likes (decayed excluded), got %d", pending)
+	}
+}
+
+func TestFeedRepository_CheckPremiumRequired_DecayedLikesFreeQueueSlots(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	defer db.CleanupAll(t)
+
+	repo := repository.NewFeedRepository(db.Pool)
+	ctx := context.Background()
+
+	alice := db.CreateTestUserWithPrefs(t, "Alice", "woman", 25, []string{"man"}, 20, 40)
+
+	// Fill Alice's queue with stale likes
+	staleAt := time.Now().AddDate(0, 0, -(feed.LikeDecayDays + 1))
+	for i := 0; i < feed.FreeLikeSlots; i++ {
+		liker := db.CreateTestUserWithPrefs(t, "StaleLiker", "man", 25+i%10, []string{"woman"}, 20, 40)
+		db.CreateLikeAt(t, liker.ID, alice.ID, false, staleAt)
+	}
+
+	bob := db.CreateTestUserWithPrefs(t, "Bob", "man", 28, []string{"woman"}, 20, 40)
+
+	result, err := repo.CheckPremiumRequired(ctx, bob.ID, alice.ID, feed.FreeLikeSlots)
+	if err != nil {
+		t.Fatalf("CheckPremiumRequired failed: %v", err)
+	}
+	if result.RequiresPremium {
+		t.Errorf("Decayed likes should not fill the queue, got reason %q", result.Reason)
+	}
+
+	// Fresh likes still fill it
+	for i := 0; i < feed.FreeLikeSlots; i++ {
+		liker := db.CreateTestUserWithPrefs(t, "FreshLiker", "man", 25+i%10, []string{"woman"}, 20, 40)
+		db.CreateLike(t, liker.ID, alice.ID, false)
+	}
+
+	result, err = repo.CheckPremiumRequired(ctx, bob.ID, alice.ID, feed.FreeLikeSlots)
+	if err != nil {
+		t.Fatalf("CheckPremiumRequired failed: %v", err)
+	}
+	if !result.RequiresPremium || result.Reason != feed.PremiumReasonQueueFull {
+		t.Errorf("Expected queue_full with %d fresh likes, got %+v", feed.FreeLikeSlots, result)
+	}
+}
+
+func TestFeedRepository_GetFeedProfiles_BoostedRankAboveBrowse(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	defer db.CleanupAll(t)
+
+	repo := repository.NewFeedRepository(db.Pool)
+	ctx := context.Background()
+
+	alice := db.CreateTestUserWithPrefs(t, "Alice", "woman", 25, []string{"man"}, 20, 40)
+	for i := 0; i < 3; i++ {
+		db.CreateTestUserWithPrefs(t, "BrowseMan", "man", 25+i, []string{"woman"}, 20, 40)
+	}
+	boosted := db.CreateTestUserWithPrefs(t, "BoostedMan", "man", 29, []string{"woman"}, 20, 40)
+	expired := db.CreateTestUserWithPrefs(t, "ExpiredBoostMan", "man", 30, []string{"woman"}, 20, 40)
+
+	// Boosted profiles are ranked below likes but above browse; only running boosts count
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO boosts (user_id, source, started_at, expires_at)
+		VALUES ($1, 'weekly', NOW(), NOW() + INTERVAL '30 minutes'),
+		       ($2, 'weekly', NOW() - INTERVAL '2 hours', NOW() - INTERVAL '90 minutes')
+	`, boosted.ID, expired.ID)
+	if err != nil {
+		t.Fatalf("Failed to create boosts: %v", err)
+	}
+
+	prefs := &profile.Preferences{
+		GendersSeeking: []string{"man"},
+		AgeMin:         20,
+		AgeMax:         40,
+		DistanceMiles:  100,
+	}
+	profiles, err := repo.GetFeedProfiles(ctx, alice.ID, prefs, 20)
+	if err != nil {
+		t.Fatalf("GetFeedProfiles failed: %v", err)
+	}
+	if len(profiles) == 0 {
+		t.Fatal("Expected profiles in feed")
+	}
+
+	if profiles[0].UserID != boosted.ID {
+		t.Errorf("First profile should be the boosted user, got %v", profiles[0].UserID)
+	}
+	if profiles[0].Priority != feed.PriorityBoosted {
+		t.Errorf("First profile priority should be boosted, got %v", profiles[0].Priority)
+	}
+	for i := 1; i < len(profiles); i++ {
+		if profiles[i].Priority != feed.PriorityBrowse {
+			t.Errorf("Profile %d priority should be browse, got %v", i, profiles[i].Priority)
+		}
+	}
+}
+
+func TestFeedRepository_ClaimQueueClearReward_GrantsOncePerClear(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	defer db.CleanupAll(t)
+
+	repo := repository.NewFeedRepository(db.Pool)
+	ctx := context.Background()
+
+	alice := db.CreateTestUser(t, "Alice", "woman", 25)
+	_, initialBonus := db.GetCredits(t, alice.ID)
+	prefs := &profile.Preferences{GendersSeeking: []string{"man"}, AgeMin: 20, AgeMax: 40}
+
+	// Not armed yet: nothing to claim
+	granted, err := repo.ClaimQueueClearReward(ctx, alice.ID, prefs, feed.QueueClearBonusLikes)
+	if err != nil {
+		t.Fatalf("ClaimQueueClearReward failed: %v", err)
+	}
+	if granted {
+		t.Error("Should not grant a reward that was never armed")
+	}
+
+	// Arming twice keeps a single open reward
+	if err := repo.ArmQueueClearReward(ctx, alice.ID); err != nil {
+		t.Fatalf("ArmQueueClearReward failed: %v", err)
+	}
+	if err := repo.ArmQueueClearReward(ctx, alice.ID); err != nil {
+		t.Fatalf("ArmQueueClearReward (second) failed: %v", err)
+	}
+
+	// Concurrent claims: exactly one wins
+	const workers = 5
+	results := make(chan bool, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			ok, err := repo.ClaimQueueClearReward(ctx, alice.ID, prefs, feed.QueueClearBonusLikes)
+			if err != nil {
+				t.Errorf("ClaimQueueClearReward failed: %v", err)
+			}
+			results <- ok
+		}()
+	}
+	grants := 0
+	for i := 0; i < workers; i++ {
+		if <-results {
+			grants++
+		}
+	}
+	if grants != 1 {
+		t.Errorf("Expected exactly 1 grant, got %d", grants)
+	}
+
+	_, newBonus := db.GetCredits(t, alice.ID)
+	if newBonus != initialBonus+feed.QueueClearBonusLikes {
+		t.Errorf("Bonus likes should increase by %d: expected %d, got %d", feed.QueueClearBonusLikes, initialBonus+feed.QueueClearBonusLikes, newBonus)
+	}
+
+	// A new full queue arms a new reward
+	if err := repo.ArmQueueClearReward(ctx, alice.ID); err != nil {
+		t.Fatalf("ArmQueueClearReward failed: %v", err)
+	}
+	granted, err = repo.ClaimQueueClearReward(ctx, alice.ID, prefs, feed.QueueClearBonusLikes)
+	if err != nil {
+		t.Fatalf("ClaimQueueClearReward failed: %v", err)
+	}
+	if !granted {
+		t.Error("Should grant reward for the second clear")
+	}
+}
+
+func TestFeedRepository_ClaimQueueClearReward_NotForDecayedQueue(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	defer db.CleanupAll(t)
+
+	repo := repository.NewFeedRepository(db.Pool)
+	ctx := context.Background()
+
+	alice := db.CreateTestUserWithPrefs(t, "Alice", "woman", 25, []string{"man"}, 20, 40)
+	_, initialBonus := db.GetCredits(t, alice.ID)
+	prefs := &profile.Preferences{GendersSeeking: []string{"man"}, AgeMin: 20, AgeMax: 40}
+
+	// Armed 10 days ago with a like that has since decayed out of the queue unprocessed
+	if err := repo.ArmQueueClearReward(ctx, alice.ID); err != nil {
+		t.Fatalf("ArmQueueClearReward failed: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx, `UPDATE queue_clear_rewards SET armed_at = NOW() - INTERVAL '10 days' WHERE user_id = $1`, alice.ID); err != nil {
+		t.Fatalf("Failed to backdate reward: %v", err)
+	}
+	liker := db.CreateTestUserWithPrefs(t, "DecayedLiker", "man", 30, []string{"woman"}, 20, 40)
+	db.CreateLikeAt(t, liker.ID, alice.ID, false, time.Now().AddDate(0, 0, -(feed.LikeDecayDays+5)))
+
+	granted, err := repo.ClaimQueueClearReward(ctx, alice.ID, prefs, feed.QueueClearBonusLikes)
+	if err != nil {
+		t.Fatalf("ClaimQueueClearReward failed: %v", err)
+	}
+	if granted {
+		t.Error("Should not grant a reward for a queue emptied by decay")
+	}
+	if _, bonus := db.GetCredits(t, alice.ID); bonus != initialBonus {
+		t.Errorf("Bonus likes should be unchanged: expected %d, got %d", initialBonus, bonus)
+	}
+
+	// The reward was withdrawn, so the next full queue arms a fresh one that can be earned
+	if err := repo.ArmQueueClearReward(ctx, alice.ID); err != nil {
+		t.Fatalf("ArmQueueClearReward failed: %v", err)
+	}
+	granted, err = repo.ClaimQueueClearReward(ctx, alice.ID, prefs, feed.QueueClearBonusLikes)
+	if err != nil {
+		t.Fatalf("ClaimQueueClearReward failed: %v", err)
+	}
+	if !granted {
+		t.Error("Should grant a reward armed after the decayed like")
+	}
+}
+
+func TestFeedRepository_DailyPicks_StableAndMarksActions(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	defer db.CleanupAll(t)
+
+	repo := repository.NewFeedRepository(db.Pool)
+	ctx := context.Background()
+
+	alice := db.CreateTestUserWithPrefs(t, "Alice", "woman", 25, []string{"man"}, 20, 40)
+	bob := db.CreateTestUserWithPrefs(t, "Bob", "man", 28, []string{"woman"}, 20, 40)
+	charlie := db.CreateTestUserWithPrefs(t, "Charlie", "man", 30, []string{"woman"}, 20, 40)
+	dave := db.CreateTestUserWithPrefs(t, "Dave", "man", 31, []string{"woman"}, 20, 40)
+
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	first := []feed.DailyPick{
+		{ID: uuid.New(), PickUserID: bob.ID, Position: 0, Priority: feed.PriorityBrowse},
+		{ID: uuid.New(), PickUserID: charlie.ID, Position: 1, Priority: feed.PriorityBrowse},
+	}
+	if err := repo.SaveDailyPicks(ctx, alice.ID, first, date); err != nil {
+		t.Fatalf("SaveDailyPicks failed: %v", err)
+	}
+
+	// A second save for the same day must not replace or extend the set
+	second := []feed.DailyPick{
+		{ID: uuid.New(), PickUserID: dave.ID, Position: 0, Priority: feed.PriorityBrowse},
+	}
+	if err := repo.SaveDailyPicks(ctx, alice.ID, second, date); err != nil {
+		t.Fatalf("SaveDailyPicks (second) failed: %v", err)
+	}
+
+	stored, err := repo.GetDailyPicks(ctx, alice.ID, date)
+	if err != nil {
+		t.Fatalf("GetDailyPicks failed: %v", err)
+	}
+	if len(stored) != 2 || stored[0].PickUserID != bob.ID || stored[1].PickUserID != charlie.ID {
+		t.Fatalf("Expected original picks [Bob, Charlie], got %+v", stored)
+	}
+
+	// Alice passes on Bob: he stays in her picks, marked as passed
+	_, err = db.Pool.Exec(ctx, `INSERT INTO passes (passer_id, passed_id, created_at) VALUES ($1, $2, NOW())`, alice.ID, bob.ID)
+	if err != nil {
+		t.Fatalf("Failed to create pass: %v", err)
+	}
+
+	picks, err := repo.GetDailyPickProfiles(ctx, alice.ID, date)
+	if err != nil {
+		t.Fatalf("GetDailyPickProfiles failed: %v", err)
+	}
+	if len(picks) != 2 {
+		t.Fatalf("Expected 2 picks, got %d", len(picks))
+	}
+	if picks[0].UserID != bob.ID || !picks[0].Acted || picks[0].Action != feed.PickActionPassed {
+		t.Errorf("Bob should be marked passed, got acted=%v action=%q", picks[0].Acted, picks[0].Action)
+	}
+	if picks[1].UserID != charlie.ID || picks[1].Acted {
+		t.Errorf("Charlie should be unacted, got acted=%v action=%q", picks[1].Acted, picks[1].Action)
+	}
+}
+
 // Helper function to order user IDs consistently
 func orderedUserIDs(a, b uuid.UUID) (uuid.UUID, uuid.UUID) {
 	if a.String() < b.String() {
@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/feels/feels/internal/domain/profile"
+	"github.com/feels/feels/internal/jobs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewJobRepository(db *pgxpool.Pool) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// CreateJobRun records the start of a job run
+func (r *JobRepository) CreateJobRun(ctx context.Context, run *jobs.Run) error {
+	query := `
+		INSERT INTO job_runs (id, job_name, trigger, instance, status, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(ctx, query, run.ID, run.JobName, run.Trigger, run.Instance, run.Status, run.StartedAt)
+	return err
+}
+
+// FinishJobRun records the outcome of a job run
+func (r *JobRepository) FinishJobRun(ctx context.Context, run *jobs.Run) error {
+	query := `
+		UPDATE job_runs
+		SET status = $2, result = NULLIF($3, ''), error = NULLIF($4, ''), finished_at = $5
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, query, run.ID, run.Status, run.Result, run.Error, run.FinishedAt)
+	return err
+}
+
+// ListJobRuns returns the most recent runs of a job, newest first
+func (r *JobRepository) ListJobRuns(ctx context.Context, jobName string, limit int) ([]jobs.Run, error) {
+	query := `
+		SELECT id, job_name, trigger, instance, status, COALESCE(result, ''), COALESCE(error, ''), started_at, finished_at
+		FROM job_runs
+		WHERE job_name = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, jobName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []jobs.Run
+	for rows.Next() {
+		var run jobs.Run
+		if err := rows.Scan(
+			&run.ID, &run.JobName, &run.Trigger, &run.Instance, &run.Status,
+			&run.Result, &run.Error, &run.StartedAt, &run.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetDailyDigestCandidates returns users with pending likes or new matches since the given time
+func (r *JobRepository) GetDailyDigestCandidates(ctx context.Context, since time.Time) ([]jobs.DigestCandidate, error) {
+	query := `
+		WITH new_likes AS (
+			SELECT l.liked_id AS user_id, COUNT(*) AS cnt
+			FROM likes l
+			WHERE l.created_at >= $1
+				AND NOT EXISTS (SELECT 1 FROM likes back WHERE back.liker_id = l.liked_id AND back.liked_id = l.liker_id)
+				AND NOT EXISTS (SELECT 1 FROM passes p WHERE p.passer_id = l.liked_id AND p.passed_id = l.liker_id)
+			GROUP BY l.liked_id
+		),
+		new_matches AS (
+			SELECT user_id, COUNT(*) AS cnt FROM (
+				SELECT user1_id AS user_id FROM matches WHERE created_at >= $1
+				UNION ALL
+				SELECT user2_id AS user_id FROM matches WHERE created_at >= $1
+			) m
+			GROUP BY user_id
+		)
+		SELECT u.id, COALESCE(nl.cnt, 0), COALESCE(nm.cnt, 0)
+		FROM users u
+		LEFT JOIN new_likes nl ON nl.user_id = u.id
+		LEFT JOIN new_matches nm ON nm.user_id = u.id
+		WHERE (nl.cnt IS NOT NULL OR nm.cnt IS NOT NULL)
+			AND COALESCE(u.moderation_status, 'active') != 'suspended'
+	`
+	rows, err := r.db.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []jobs.DigestCandidate
+	for rows.Next() {
+		var c jobs.DigestCandidate
+		if err := rows.Scan(&c.UserID, &c.NewLikes, &c.NewMatches); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// GetLikesDigestCandidates returns users with likes since the given time that they
+// haven't liked back or passed on
+func (r *JobRepository) GetLikesDigestCandidates(ctx context.Context, since time.Time) ([]jobs.LikesDigestCandidate, error) {
+	query := `
+		SELECT l.liked_id, COUNT(*)
+		FROM likes l
+		JOIN users u ON u.id = l.liked_id
+		WHERE l.created_at >= $1
+			AND COALESCE(u.moderation_status, 'active') != 'suspended'
+			AND NOT EXISTS (SELECT 1 FROM likes back WHERE back.liker_id = l.liked_id AND back.liked_id = l.liker_id)
+			AND NOT EXISTS (SELECT 1 FROM passes p WHERE p.passer_id = l.liked_id AND p.passed_id = l.liker_id)
+		GROUP BY l.liked_id
+	`
+	rows, err := r.db.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []jobs.LikesDigestCandidate
+	for rows.Next() {
+		var c jobs.LikesDigestCandidate
+		if err := rows.Scan(&c.UserID, &c.Likes); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// GetRenewalsDue returns active subscriptions whose current period ends between from and to
+// Subscriptions canceled at period end won't renew, so they're left out
+func (r *JobRepository) GetRenewalsDue(ctx context.Context, from, to time.Time) ([]jobs.RenewalDue, error) {
+	query := `
+		SELECT user_id, plan_type, current_period_end
+		FROM subscriptions
+		WHERE status = 'active' AND canceled_at IS NULL
+		  AND current_period_end > $1 AND current_period_end <= $2
+	`
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []jobs.RenewalDue
+	for rows.Next() {
+		var d jobs.RenewalDue
+		if err := rows.Scan(&d.UserID, &d.PlanType, &d.RenewsAt); err != nil {
+			return nil, err
+		}
+		due = append(due, d)
+	}
+	return due, rows.Err()
+}
+
+// GetInactiveUsers returns users whose last activity was exactly one of the given numbers of days ago
+// Matching exact days means each user gets at most one reminder per threshold
+func (r *JobRepository) GetInactiveUsers(ctx context.Context, days []int) ([]jobs.InactiveUser, error) {
+	query := `
+		SELECT p.user_id, (CURRENT_DATE - p.last_active::date) AS days_inactive
+		FROM profiles p
+		JOIN users u ON u.id = p.user_id
+		WHERE COALESCE(u.moderation_status, 'active') != 'suspended'
+			AND (CURRENT_DATE - p.last_active::date) = ANY($1)
+	`
+	rows, err := r.db.Query(ctx, query, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []jobs.InactiveUser
+	for rows.Next() {
+		var u jobs.InactiveUser
+		if err := rows.Scan(&u.UserID, &u.DaysSinceActive); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// DeleteExpiredMagicLinks deletes magic links past their expiry
+func (r *JobRepository) DeleteExpiredMagicLinks(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM magic_links WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpiredOTPCodes deletes OTP codes past their expiry
+func (r *JobRepository) DeleteExpiredOTPCodes(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM otp_codes WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeleteExpiredRefreshTokens deletes refresh tokens past their expiry
+func (r *JobRepository) DeleteExpiredRefreshTokens(ctx context.Context) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// DeletePassesOlderThan deletes passes created before the given time
+func (r *JobRepository) DeletePassesOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM passes WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// GetViewOnceObjectsToSweep returns view-once images that were viewed before viewedBefore,
+// unsent, or never opened and sent before sentBefore, and whose objects still exist
+func (r *JobRepository) GetViewOnceObjectsToSweep(ctx context.Context, viewedBefore, sentBefore time.Time, limit int) ([]jobs.ViewOnceObject, error) {
+	query := `
+		SELECT id, private_object
+		FROM messages
+		WHERE view_once AND object_deleted_at IS NULL AND private_object IS NOT NULL
+			AND (viewed_at < $1 OR unsent_at IS NOT NULL OR created_at < $2)
+		ORDER BY created_at
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, query, viewedBefore, sentBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objects []jobs.ViewOnceObject
+	for rows.Next() {
+		var o jobs.ViewOnceObject
+		if err := rows.Scan(&o.MessageID, &o.ObjectName); err != nil {
+			return nil, err
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// MarkViewOnceObjectDeleted records that a view-once image's object is gone
+func (r *JobRepository) MarkViewOnceObjectDeleted(ctx context.Context, messageID uuid.UUID) error {
+	query := `UPDATE messages SET object_deleted_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, messageID)
+	return err
+}
+
+// GetNudgeCandidates returns people to nudge in stalled matches: both people when a
+// match has no messages, otherwise whoever received the last message. Matches nudged
+// within the cooldown are skipped, as are people already nudged MaxPerMatch times in the
+// match and recipients with new-message notifications off or the chat muted or archived.
+// The limit counts matches, so both people in a match are always returned together.
+func (r *JobRepository) GetNudgeCandidates(ctx context.Context, q jobs.NudgeQuery) ([]jobs.NudgeCandidate, error) {
+	query := `
+		WITH stalled AS (
+			SELECT m.id AS match_id, m.user1_id, m.user2_id, lm.sender_id AS last_sender, lm.created_at AS last_at
+			FROM matches m
+			LEFT JOIN LATERAL (
+				SELECT sender_id, created_at FROM messages
+				WHERE match_id = m.id AND unsent_at IS NULL
+				ORDER BY created_at DESC, id DESC
+				LIMIT 1
+			) lm ON true
+			WHERE COALESCE(lm.created_at, m.created_at) > $3
+				AND ((lm.created_at IS NULL AND m.created_at < $1) OR lm.created_at < $2)
+				AND NOT EXISTS (SELECT 1 FROM conversation_nudges n WHERE n.match_id = m.id AND n.created_at > $4)
+		),
+		targets AS (
+			SELECT match_id, user1_id AS user_id, user2_id AS other_id, 'no_messages' AS reason FROM stalled WHERE last_at IS NULL
+			UNION ALL
+			SELECT match_id, user2_id, user1_id, 'no_messages' FROM stalled WHERE last_at IS NULL
+			UNION ALL
+			SELECT match_id, CASE WHEN last_sender = user1_id THEN user2_id ELSE user1_id END, last_sender, 'no_reply'
+			FROM stalled WHERE last_at IS NOT NULL
+		),
+		eligible AS (
+			SELECT t.match_id, t.user_id, t.other_id, t.reason, p.name, p.prompts
+			FROM targets t
+			JOIN profiles p ON p.user_id = t.other_id
+			JOIN users u ON u.id = t.user_id
+			LEFT JOIN notification_settings ns ON ns.user_id = t.user_id
+			LEFT JOIN conversation_settings cs ON cs.match_id = t.match_id AND cs.user_id = t.user_id
+			WHERE COALESCE(ns.new_messages, true)
+				AND COALESCE(u.moderation_status, 'active') != 'suspended'
+				AND NOT COALESCE(cs.muted AND (cs.muted_until IS NULL OR cs.muted_until > NOW()), false)
+				AND cs.archived_at IS NULL
+				AND (SELECT COUNT(*) FROM conversation_nudges n WHERE n.match_id = t.match_id AND n.user_id = t.user_id) < $5
+		),
+		batch AS (
+			SELECT DISTINCT match_id FROM eligible ORDER BY match_id LIMIT $6
+		)
+		SELECT e.match_id, e.user_id, e.other_id, e.reason, e.name, e.prompts
+		FROM eligible e
+		JOIN batch b ON b.match_id = e.match_id
+		ORDER BY e.match_id, e.user_id
+	`
+	rows, err := r.db.Query(ctx, query,
+		q.NoMessagesBefore, q.NoReplyBefore, q.QuietSince, q.CooldownSince, q.MaxPerMatch, q.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []jobs.NudgeCandidate
+	for rows.Next() {
+		var c jobs.NudgeCandidate
+		var prompts profile.Prompts
+		if err := rows.Scan(&c.MatchID, &c.UserID, &c.OtherUserID, &c.Reason, &c.OtherName, &prompts); err != nil {
+			return nil, err
+		}
+		for _, p := range prompts {
+			if p.Question != "" && p.Answer != "" {
+				c.Prompts = append(c.Prompts, jobs.NudgePrompt{Question: p.Question, Answer: p.Answer})
+			}
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// RecordNudge records a nudge sent to a user in a match
+func (r *JobRepository) RecordNudge(ctx context.Context, matchID, userID uuid.UUID, reason string) error {
+	query := `INSERT INTO conversation_nudges (match_id, user_id, reason) VALUES ($1, $2, $3)`
+	_, err := r.db.Exec(ctx, query, matchID, userID, reason)
+	return err
+}
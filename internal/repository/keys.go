@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/feels/feels/internal/domain/keys"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrDeviceSessionNotFound = errors.New("device session not found")
+
+type KeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewKeyRepository(db *pgxpool.Pool) *KeyRepository {
+	return &KeyRepository{db: db}
+}
+
+// GetDeviceSessionID resolves a client device ID to the user's device session
+func (r *KeyRepository) GetDeviceSessionID(ctx context.Context, userID uuid.UUID, deviceID string) (uuid.UUID, error) {
+	query := `SELECT id FROM device_sessions WHERE user_id = $1 AND device_id = $2`
+
+	var id uuid.UUID
+	err := r.db.QueryRow(ctx, query, userID, deviceID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrDeviceSessionNotFound
+	}
+	return id, err
+}
+
+// UpsertDeviceKeys stores a device's keys and replaces its one-time prekeys
+func (r *KeyRepository) UpsertDeviceKeys(ctx context.Context, k *keys.DeviceKeys, preKeys []keys.OneTimePreKey) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO device_keys (device_session_id, user_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (device_session_id) DO UPDATE SET
+			identity_key = EXCLUDED.identity_key,
+			signed_prekey_id = EXCLUDED.signed_prekey_id,
+			signed_prekey = EXCLUDED.signed_prekey,
+			signed_prekey_signature = EXCLUDED.signed_prekey_signature,
+			updated_at = NOW()
+	`, k.DeviceSessionID, k.UserID, k.IdentityKey, k.SignedPreKey.KeyID, k.SignedPreKey.PublicKey, k.SignedPreKey.Signature)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM one_time_prekeys WHERE device_session_id = $1`, k.DeviceSessionID); err != nil {
+		return err
+	}
+	// New keys mean new sessions, so this device and its peers can claim from each other again
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM prekey_claims WHERE requester_session_id = $1 OR target_session_id = $1
+	`, k.DeviceSessionID); err != nil {
+		return err
+	}
+	if err := insertPreKeys(ctx, tx, k.DeviceSessionID, preKeys); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// AddOneTimePreKeys appends prekeys (ignoring IDs already present) and returns the new total
+func (r *KeyRepository) AddOneTimePreKeys(ctx context.Context, deviceSessionID uuid.UUID, preKeys []keys.OneTimePreKey) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var registered bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM device_keys WHERE device_session_id = $1)`, deviceSessionID).Scan(&registered); err != nil {
+		return 0, err
+	}
+	if !registered {
+		return 0, keys.ErrNoDeviceKeys
+	}
+
+	if err := insertPreKeys(ctx, tx, deviceSessionID, preKeys); err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM one_time_prekeys WHERE device_session_id = $1`, deviceSessionID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit(ctx)
+}
+
+func insertPreKeys(ctx context.Context, tx pgx.Tx, deviceSessionID uuid.UUID, preKeys []keys.OneTimePreKey) error {
+	for _, pk := range preKeys {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO one_time_prekeys (device_session_id, key_id, public_key)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (device_session_id, key_id) DO NOTHING
+		`, deviceSessionID, pk.KeyID, pk.PublicKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountOneTimePreKeys returns how many unclaimed one-time prekeys a device has
+func (r *KeyRepository) CountOneTimePreKeys(ctx context.Context, deviceSessionID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM one_time_prekeys WHERE device_session_id = $1`, deviceSessionID).Scan(&count)
+	return count, err
+}
+
+// ClaimPreKeyBundles builds a bundle for every keyed device of the given users except the
+// requester, consuming one one-time prekey from each device the requester hasn't claimed
+// from before. Concurrent claims never get the same prekey.
+func (r *KeyRepository) ClaimPreKeyBundles(ctx context.Context, userIDs []uuid.UUID, requesterSessionID uuid.UUID) ([]keys.PreKeyBundle, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT dk.device_session_id, dk.user_id, ds.device_id, dk.identity_key,
+			dk.signed_prekey_id, dk.signed_prekey, dk.signed_prekey_signature
+		FROM device_keys dk
+		JOIN device_sessions ds ON ds.id = dk.device_session_id
+		WHERE dk.user_id = ANY($1) AND dk.device_session_id <> $2
+		ORDER BY dk.user_id, ds.created_at
+	`, userIDs, requesterSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessionIDs []uuid.UUID
+	var bundles []keys.PreKeyBundle
+	for rows.Next() {
+		var sessionID uuid.UUID
+		var b keys.PreKeyBundle
+		if err := rows.Scan(&sessionID, &b.UserID, &b.DeviceID, &b.IdentityKey,
+			&b.SignedPreKey.KeyID, &b.SignedPreKey.PublicKey, &b.SignedPreKey.Signature); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+		bundles = append(bundles, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, sessionID := range sessionIDs {
+		claim, err := tx.Exec(ctx, `
+			INSERT INTO prekey_claims (requester_session_id, target_session_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, requesterSessionID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if claim.RowsAffected() == 0 {
+			continue
+		}
+
+		var pk keys.OneTimePreKey
+		err = tx.QueryRow(ctx, `
+			DELETE FROM one_time_prekeys
+			WHERE (device_session_id, key_id) = (
+				SELECT device_session_id, key_id FROM one_time_prekeys
+				WHERE device_session_id = $1
+				ORDER BY key_id
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING key_id, public_key
+		`, sessionID).Scan(&pk.KeyID, &pk.PublicKey)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		bundles[i].OneTimePreKey = &pk
+	}
+
+	return bundles, tx.Commit(ctx)
+}
+
+// DeleteDeviceKeys removes a device's keys and prekeys, reporting whether any existed
+func (r *KeyRepository) DeleteDeviceKeys(ctx context.Context, userID uuid.UUID, deviceID string) (bool, error) {
+	query := `
+		DELETE FROM device_keys
+		WHERE device_session_id = (SELECT id FROM device_sessions WHERE user_id = $1 AND device_id = $2)
+	`
+	tag, err := r.db.Exec(ctx, query, userID, deviceID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetKeyedDeviceIDs returns the device IDs that have registered keys, per user
+func (r *KeyRepository) GetKeyedDeviceIDs(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]string, error) {
+	query := `
+		SELECT dk.user_id, ds.device_id
+		FROM device_keys dk
+		JOIN device_sessions ds ON ds.id = dk.device_session_id
+		WHERE dk.user_id = ANY($1)
+	`
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make(map[uuid.UUID][]string)
+	for rows.Next() {
+		var userID uuid.UUID
+		var deviceID string
+		if err := rows.Scan(&userID, &deviceID); err != nil {
+			return nil, err
+		}
+		devices[userID] = append(devices[userID], deviceID)
+	}
+	return devices, rows.Err()
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/feels/feels/internal/domain/mailer"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MailerRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMailerRepository(db *pgxpool.Pool) *MailerRepository {
+	return &MailerRepository{db: db}
+}
+
+// GetRecipient returns the user's email and display name
+// Placeholder addresses of phone-only accounts come back empty
+func (r *MailerRepository) GetRecipient(ctx context.Context, userID uuid.UUID) (*mailer.Recipient, error) {
+	query := `SELECT u.id,
+			CASE WHEN u.email LIKE '%@phone.feels.local' THEN '' ELSE u.email END,
+			COALESCE(p.name, '')
+		FROM users u
+		LEFT JOIN profiles p ON p.user_id = u.id
+		WHERE u.id = $1`
+
+	var rec mailer.Recipient
+	err := r.db.QueryRow(ctx, query, userID).Scan(&rec.UserID, &rec.Email, &rec.Name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, mailer.ErrRecipientNotFound
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (r *MailerRepository) ClaimEmail(ctx context.Context, userID uuid.UUID, emailType, key string) (bool, error) {
+	query := `INSERT INTO email_sends (user_id, type, dedupe_key) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, type, dedupe_key) DO NOTHING`
+	result, err := r.db.Exec(ctx, query, userID, emailType, key)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *MailerRepository) ReleaseEmail(ctx context.Context, userID uuid.UUID, emailType, key string) error {
+	query := `DELETE FROM email_sends WHERE user_id = $1 AND type = $2 AND dedupe_key = $3`
+	_, err := r.db.Exec(ctx, query, userID, emailType, key)
+	return err
+}
@@ -278,3 +278,27 @@ func (r *MatchRepository) GetOtherUserID(ctx context.Context, matchID, userID uu
 	return otherID, nil
 }
 
+// GetMatchedUserIDs returns the IDs of everyone the user is matched with
+func (r *MatchRepository) GetMatchedUserIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT CASE WHEN user1_id = $1 THEN user2_id ELSE user1_id END
+		FROM matches
+		WHERE user1_id = $1 OR user2_id = $1
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"html"
+	"strings"
 	"time"
 
 	"github.com/feels/feels/internal/domain/message"
@@ -15,6 +18,39 @@ var (
 	ErrMessageNotFound = errors.New("message not found")
 )
 
+// messageColumns is the column list scanned by scanMessage
+const messageColumns = `id, match_id, sender_id, content, encrypted_content, image_url, created_at, read_at, edited_at, unsent_at, reply_to_id,
+	kind, audio_url, audio_duration_ms, audio_waveform, view_once, private_object, viewed_at, sender_device_id, device_encrypted`
+
+// rowScanner is satisfied by pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanMessage scans messageColumns into msg, followed by any extra selected columns
+func scanMessage(row rowScanner, msg *message.Message, extra ...any) error {
+	var audioURL *string
+	var audioDurationMs *int
+	var audioWaveform []int16
+	dest := []any{
+		&msg.ID, &msg.MatchID, &msg.SenderID, &msg.Content, &msg.EncryptedContent, &msg.ImageURL,
+		&msg.CreatedAt, &msg.ReadAt, &msg.EditedAt, &msg.UnsentAt, &msg.ReplyToID,
+		&msg.Kind, &audioURL, &audioDurationMs, &audioWaveform, &msg.ViewOnce, &msg.PrivateObject, &msg.ViewedAt,
+		&msg.SenderDeviceID, &msg.DeviceEncrypted,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if audioURL != nil {
+		msg.Audio = &message.VoiceNote{URL: *audioURL, Waveform: audioWaveform}
+		if audioDurationMs != nil {
+			msg.Audio.DurationMs = *audioDurationMs
+		}
+	}
+	return nil
+}
+
 type MessageRepository struct {
 	db *pgxpool.Pool
 }
@@ -23,28 +59,83 @@ func NewMessageRepository(db *pgxpool.Pool) *MessageRepository {
 	return &MessageRepository{db: db}
 }
 
-// Create creates a new message
+// Create creates a new message along with its per-device ciphertexts
 func (r *MessageRepository) Create(ctx context.Context, msg *message.Message) error {
+	var audioURL *string
+	var audioDurationMs *int
+	var audioWaveform []int16
+	if msg.Audio != nil {
+		audioURL, audioDurationMs, audioWaveform = &msg.Audio.URL, &msg.Audio.DurationMs, msg.Audio.Waveform
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO messages (id, match_id, sender_id, content, encrypted_content, image_url, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO messages (id, match_id, sender_id, content, encrypted_content, image_url, created_at, reply_to_id,
+			kind, audio_url, audio_duration_ms, audio_waveform, view_once, private_object, sender_device_id, device_encrypted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 	`
-	_, err := r.db.Exec(ctx, query,
-		msg.ID, msg.MatchID, msg.SenderID, msg.Content, msg.EncryptedContent, msg.ImageURL, msg.CreatedAt,
+	_, err = tx.Exec(ctx, query,
+		msg.ID, msg.MatchID, msg.SenderID, msg.Content, msg.EncryptedContent, msg.ImageURL, msg.CreatedAt, msg.ReplyToID,
+		msg.Kind, audioURL, audioDurationMs, audioWaveform, msg.ViewOnce, msg.PrivateObject, msg.SenderDeviceID, msg.DeviceEncrypted,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	for _, c := range msg.DeviceCiphertexts {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO message_device_ciphertexts (message_id, recipient_id, device_id, ciphertext)
+			VALUES ($1, $2, $3, $4)
+		`, msg.ID, c.UserID, c.DeviceID, c.Ciphertext)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetCiphertexts returns the ciphertexts addressed to a user's devices, keyed by message then device
+func (r *MessageRepository) GetCiphertexts(ctx context.Context, msgIDs []uuid.UUID, recipientID uuid.UUID) (map[uuid.UUID]map[string]string, error) {
+	query := `
+		SELECT message_id, device_id, ciphertext
+		FROM message_device_ciphertexts
+		WHERE message_id = ANY($1) AND recipient_id = $2
+	`
+	rows, err := r.db.Query(ctx, query, msgIDs, recipientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ciphertexts := make(map[uuid.UUID]map[string]string)
+	for rows.Next() {
+		var msgID uuid.UUID
+		var deviceID, ciphertext string
+		if err := rows.Scan(&msgID, &deviceID, &ciphertext); err != nil {
+			return nil, err
+		}
+		if ciphertexts[msgID] == nil {
+			ciphertexts[msgID] = make(map[string]string)
+		}
+		ciphertexts[msgID][deviceID] = ciphertext
+	}
+	return ciphertexts, rows.Err()
 }
 
 // GetByID gets a message by ID
 func (r *MessageRepository) GetByID(ctx context.Context, msgID uuid.UUID) (*message.Message, error) {
 	query := `
-		SELECT id, match_id, sender_id, content, encrypted_content, image_url, created_at, read_at
+		SELECT ` + messageColumns + `
 		FROM messages WHERE id = $1
 	`
 	var msg message.Message
-	err := r.db.QueryRow(ctx, query, msgID).Scan(
-		&msg.ID, &msg.MatchID, &msg.SenderID, &msg.Content, &msg.EncryptedContent, &msg.ImageURL, &msg.CreatedAt, &msg.ReadAt,
-	)
+	err := scanMessage(r.db.QueryRow(ctx, query, msgID), &msg)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrMessageNotFound
@@ -54,16 +145,59 @@ func (r *MessageRepository) GetByID(ctx context.Context, msgID uuid.UUID) (*mess
 	return &msg, nil
 }
 
-// GetByMatch gets messages for a match with pagination
-func (r *MessageRepository) GetByMatch(ctx context.Context, matchID uuid.UUID, limit, offset int) ([]message.Message, error) {
+// GetByIDs gets a set of messages keyed by ID (missing IDs are omitted)
+func (r *MessageRepository) GetByIDs(ctx context.Context, msgIDs []uuid.UUID) (map[uuid.UUID]*message.Message, error) {
 	query := `
-		SELECT id, match_id, sender_id, content, encrypted_content, image_url, created_at, read_at
-		FROM messages
-		WHERE match_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		SELECT ` + messageColumns + `
+		FROM messages WHERE id = ANY($1)
 	`
-	rows, err := r.db.Query(ctx, query, matchID, limit, offset)
+	rows, err := r.db.Query(ctx, query, msgIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make(map[uuid.UUID]*message.Message)
+	for rows.Next() {
+		var msg message.Message
+		if err := scanMessage(rows, &msg); err != nil {
+			return nil, err
+		}
+		messages[msg.ID] = &msg
+	}
+	return messages, rows.Err()
+}
+
+// GetByMatch gets a page of messages for a match in chronological order
+// Cursor pages are keyed on (created_at, id) so new messages don't shift them
+func (r *MessageRepository) GetByMatch(ctx context.Context, matchID uuid.UUID, page message.PageQuery) ([]message.Message, error) {
+	args := []any{matchID}
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE match_id = $1`
+	if page.Before != nil {
+		query += " AND " + cursorCondition(page.Before, "<", &args)
+	}
+	if page.After != nil {
+		query += " AND " + cursorCondition(page.After, ">", &args)
+	}
+
+	// Walk forward from an after cursor, otherwise back from the newest (or before) message
+	ascending := page.After != nil && page.Before == nil
+	if ascending {
+		query += " ORDER BY created_at ASC, id ASC"
+	} else {
+		query += " ORDER BY created_at DESC, id DESC"
+	}
+	args = append(args, page.Limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+	if page.Before == nil && page.After == nil && page.Offset > 0 {
+		args = append(args, page.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -72,33 +206,95 @@ func (r *MessageRepository) GetByMatch(ctx context.Context, matchID uuid.UUID, l
 	var messages []message.Message
 	for rows.Next() {
 		var msg message.Message
-		if err := rows.Scan(&msg.ID, &msg.MatchID, &msg.SenderID, &msg.Content, &msg.EncryptedContent, &msg.ImageURL, &msg.CreatedAt, &msg.ReadAt); err != nil {
+		if err := scanMessage(rows, &msg); err != nil {
 			return nil, err
 		}
 		messages = append(messages, msg)
 	}
 
 	// Reverse to get chronological order
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	if !ascending {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
 	}
 
 	return messages, rows.Err()
 }
 
+// cursorCondition compares a message row with a cursor, appending the cursor's arguments
+// A message cursor is resolved within the same match ($1), so IDs from other chats match nothing
+func cursorCondition(c *message.Cursor, op string, args *[]any) string {
+	if c.MessageID != nil {
+		*args = append(*args, *c.MessageID)
+		return fmt.Sprintf("(created_at, id) %s (SELECT created_at, id FROM messages WHERE id = $%d AND match_id = $1)", op, len(*args))
+	}
+	*args = append(*args, *c.Time)
+	return fmt.Sprintf("created_at %s $%d", op, len(*args))
+}
+
+// Search highlights are delimited with private-use characters, which are stripped from
+// the content first, so the fragment can be HTML-escaped before the <mark> tags go in
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var headlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `", MaxFragments=2, MaxWords=20, MinWords=5`
+
+// markHighlights escapes a search fragment and wraps its matched terms in <mark></mark>
+func markHighlights(fragment string) string {
+	fragment = html.EscapeString(fragment)
+	fragment = strings.ReplaceAll(fragment, highlightStart, "<mark>")
+	return strings.ReplaceAll(fragment, highlightStop, "</mark>")
+}
+
+// SearchMessages finds plaintext messages in a match matching a web-style search query, newest first
+// The highlight is HTML-escaped, with matched terms wrapped in <mark></mark>
+func (r *MessageRepository) SearchMessages(ctx context.Context, matchID uuid.UUID, search string, before *message.Cursor, limit int) ([]message.SearchResult, error) {
+	args := []any{matchID, search, highlightStart + highlightStop, headlineOptions}
+	query := `
+		SELECT ` + messageColumns + `,
+			ts_headline('simple', translate(content, $3, ''), q, $4)
+		FROM messages, websearch_to_tsquery('simple', $2) q
+		WHERE match_id = $1
+			AND unsent_at IS NULL
+			AND to_tsvector('simple', COALESCE(content, '')) @@ q`
+	if before != nil {
+		query += " AND " + cursorCondition(before, "<", &args)
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []message.SearchResult
+	for rows.Next() {
+		var res message.SearchResult
+		if err := scanMessage(rows, &res.Message, &res.Highlight); err != nil {
+			return nil, err
+		}
+		res.Highlight = markHighlights(res.Highlight)
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
 // GetLastMessage gets the last message in a match
 func (r *MessageRepository) GetLastMessage(ctx context.Context, matchID uuid.UUID) (*message.Message, error) {
 	query := `
-		SELECT id, match_id, sender_id, content, encrypted_content, image_url, created_at, read_at
+		SELECT ` + messageColumns + `
 		FROM messages
 		WHERE match_id = $1
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
 	var msg message.Message
-	err := r.db.QueryRow(ctx, query, matchID).Scan(
-		&msg.ID, &msg.MatchID, &msg.SenderID, &msg.Content, &msg.EncryptedContent, &msg.ImageURL, &msg.CreatedAt, &msg.ReadAt,
-	)
+	err := scanMessage(r.db.QueryRow(ctx, query, matchID), &msg)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -116,6 +312,55 @@ func (r *MessageRepository) CountMessages(ctx context.Context, matchID uuid.UUID
 	return count, err
 }
 
+// CountTurns counts each sender's turns in a match, where a turn is a run of
+// consecutive messages from the same person (unsent messages are skipped)
+func (r *MessageRepository) CountTurns(ctx context.Context, matchID uuid.UUID) (map[uuid.UUID]int, error) {
+	query := `
+		SELECT sender_id, COUNT(*)
+		FROM (
+			SELECT sender_id, LAG(sender_id) OVER (ORDER BY created_at, id) AS prev_sender_id
+			FROM messages
+			WHERE match_id = $1 AND unsent_at IS NULL
+		) t
+		WHERE prev_sender_id IS DISTINCT FROM sender_id
+		GROUP BY sender_id
+	`
+	rows, err := r.db.Query(ctx, query, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	turns := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var senderID uuid.UUID
+		var count int
+		if err := rows.Scan(&senderID, &count); err != nil {
+			return nil, err
+		}
+		turns[senderID] = count
+	}
+	return turns, rows.Err()
+}
+
+// ImagePromptSent reports whether the image unlock prompt has been sent for a match
+func (r *MessageRepository) ImagePromptSent(ctx context.Context, matchID uuid.UUID) (bool, error) {
+	query := `SELECT image_prompt_sent_at IS NOT NULL FROM matches WHERE id = $1`
+	var sent bool
+	err := r.db.QueryRow(ctx, query, matchID).Scan(&sent)
+	return sent, err
+}
+
+// MarkImagePromptSent records the image unlock prompt, returning false if it was already sent
+func (r *MessageRepository) MarkImagePromptSent(ctx context.Context, matchID uuid.UUID) (bool, error) {
+	query := `UPDATE matches SET image_prompt_sent_at = NOW() WHERE id = $1 AND image_prompt_sent_at IS NULL`
+	result, err := r.db.Exec(ctx, query, matchID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
 // HasMessages returns true if a match has any messages
 func (r *MessageRepository) HasMessages(ctx context.Context, matchID uuid.UUID) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM messages WHERE match_id = $1)`
@@ -198,6 +443,21 @@ func (r *MessageRepository) SetImagePermission(ctx context.Context, matchID, use
 	return err
 }
 
+// MarkImagesBothEnabled records that both users have had images enabled in a match (first time only)
+func (r *MessageRepository) MarkImagesBothEnabled(ctx context.Context, matchID uuid.UUID) error {
+	query := `UPDATE matches SET images_both_enabled_at = COALESCE(images_both_enabled_at, NOW()) WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, matchID)
+	return err
+}
+
+// ImagesEverBothEnabled reports whether both users have ever had images enabled in a match
+func (r *MessageRepository) ImagesEverBothEnabled(ctx context.Context, matchID uuid.UUID) (bool, error) {
+	query := `SELECT images_both_enabled_at IS NOT NULL FROM matches WHERE id = $1`
+	var enabled bool
+	err := r.db.QueryRow(ctx, query, matchID).Scan(&enabled)
+	return enabled, err
+}
+
 // MarkMessagesRead marks all messages from a sender as read in a match
 func (r *MessageRepository) MarkMessagesRead(ctx context.Context, matchID, readerID uuid.UUID) (int, error) {
 	// Mark messages as read where the reader is NOT the sender (i.e., messages sent TO them)
@@ -254,9 +514,268 @@ func (r *MessageRepository) GetUnreadCountsForUser(ctx context.Context, userID u
 	return counts, rows.Err()
 }
 
+// Edits, unsend and reactions
+
+// EditMessage saves the current text to the edit history and replaces it
+func (r *MessageRepository) EditMessage(ctx context.Context, msgID, editorID uuid.UUID, content, encryptedContent *string) (*message.Message, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO message_edits (message_id, match_id, editor_id, action, content, encrypted_content, image_url)
+		SELECT id, match_id, $2, $3, content, encrypted_content, image_url
+		FROM messages WHERE id = $1 AND unsent_at IS NULL
+	`, msgID, editorID, message.EditActionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE messages
+		SET content = $2, encrypted_content = $3, edited_at = NOW()
+		WHERE id = $1 AND unsent_at IS NULL
+		RETURNING ` + messageColumns
+	var msg message.Message
+	if err := scanMessage(tx.QueryRow(ctx, query, msgID, content, encryptedContent), &msg); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// UnsendMessage archives a message to the edit history, clears its content and drops its reactions
+func (r *MessageRepository) UnsendMessage(ctx context.Context, msgID, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO message_edits (message_id, match_id, editor_id, action, content, encrypted_content, image_url, audio_url)
+		SELECT id, match_id, $2, $3, content, encrypted_content, image_url, audio_url
+		FROM messages WHERE id = $1 AND unsent_at IS NULL
+	`, msgID, userID, message.EditActionUnsend)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE messages
+		SET content = NULL, encrypted_content = NULL, image_url = NULL,
+			audio_url = NULL, audio_duration_ms = NULL, audio_waveform = NULL, unsent_at = NOW()
+		WHERE id = $1 AND unsent_at IS NULL
+	`, msgID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM message_reactions WHERE message_id = $1`, msgID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM message_device_ciphertexts WHERE message_id = $1`, msgID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetReaction adds or replaces a user's reaction to a message
+func (r *MessageRepository) SetReaction(ctx context.Context, msgID, userID uuid.UUID, emoji string) error {
+	query := `
+		INSERT INTO message_reactions (message_id, user_id, emoji)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (message_id, user_id) DO UPDATE SET emoji = $3, created_at = NOW()
+	`
+	_, err := r.db.Exec(ctx, query, msgID, userID, emoji)
+	return err
+}
+
+// DeleteReaction removes a user's reaction, reporting whether there was one
+func (r *MessageRepository) DeleteReaction(ctx context.Context, msgID, userID uuid.UUID) (bool, error) {
+	query := `DELETE FROM message_reactions WHERE message_id = $1 AND user_id = $2`
+	result, err := r.db.Exec(ctx, query, msgID, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetReactions gets reactions for a set of messages, keyed by message ID
+func (r *MessageRepository) GetReactions(ctx context.Context, msgIDs []uuid.UUID) (map[uuid.UUID][]message.Reaction, error) {
+	query := `
+		SELECT message_id, user_id, emoji, created_at
+		FROM message_reactions
+		WHERE message_id = ANY($1)
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(ctx, query, msgIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := make(map[uuid.UUID][]message.Reaction)
+	for rows.Next() {
+		var msgID uuid.UUID
+		var reaction message.Reaction
+		if err := rows.Scan(&msgID, &reaction.UserID, &reaction.Emoji, &reaction.CreatedAt); err != nil {
+			return nil, err
+		}
+		reactions[msgID] = append(reactions[msgID], reaction)
+	}
+	return reactions, rows.Err()
+}
+
+// MarkViewed consumes a view-once image
+// Only the first call for a message succeeds; later ones get message.ErrAlreadyViewed
+func (r *MessageRepository) MarkViewed(ctx context.Context, msgID uuid.UUID) error {
+	query := `
+		UPDATE messages
+		SET viewed_at = NOW()
+		WHERE id = $1 AND view_once AND viewed_at IS NULL AND unsent_at IS NULL AND object_deleted_at IS NULL
+	`
+	result, err := r.db.Exec(ctx, query, msgID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return message.ErrAlreadyViewed
+	}
+	return nil
+}
+
 // DeleteByMatch deletes all messages for a match (used when unmatching)
 func (r *MessageRepository) DeleteByMatch(ctx context.Context, matchID uuid.UUID) error {
 	query := `DELETE FROM messages WHERE match_id = $1`
 	_, err := r.db.Exec(ctx, query, matchID)
 	return err
 }
+
+// Conversations
+
+// GetConversations lists a user's conversations, pinned first then by last activity
+// Last messages are loaded in one batch after the list query
+func (r *MessageRepository) GetConversations(ctx context.Context, userID uuid.UUID, archived bool) ([]message.Conversation, error) {
+	query := `
+		SELECT
+			m.id,
+			p.user_id,
+			p.name,
+			(SELECT url FROM photos WHERE user_id = p.user_id ORDER BY position LIMIT 1),
+			last.id,
+			COALESCE(last.created_at, m.created_at) AS last_activity_at,
+			(SELECT COUNT(*) FROM messages WHERE match_id = m.id AND sender_id != $1 AND read_at IS NULL),
+			COALESCE(ip.enabled, false),
+			COALESCE(cs.muted AND (cs.muted_until IS NULL OR cs.muted_until > NOW()), false),
+			CASE WHEN cs.muted AND cs.muted_until > NOW() THEN cs.muted_until END,
+			cs.archived_at IS NOT NULL,
+			cs.pinned_at IS NOT NULL
+		FROM matches m
+		JOIN profiles p ON p.user_id = CASE WHEN m.user1_id = $1 THEN m.user2_id ELSE m.user1_id END
+		LEFT JOIN LATERAL (
+			SELECT id, created_at FROM messages
+			WHERE match_id = m.id
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) last ON true
+		LEFT JOIN image_permissions ip ON ip.match_id = m.id AND ip.user_id = $1
+		LEFT JOIN conversation_settings cs ON cs.match_id = m.id AND cs.user_id = $1
+		WHERE (m.user1_id = $1 OR m.user2_id = $1)
+			AND (cs.archived_at IS NOT NULL) = $2
+		ORDER BY cs.pinned_at DESC NULLS LAST, last_activity_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID, archived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []message.Conversation
+	var lastIDs []uuid.UUID
+	for rows.Next() {
+		var c message.Conversation
+		var lastID *uuid.UUID
+		err := rows.Scan(
+			&c.MatchID, &c.OtherUserID, &c.OtherUserName, &c.OtherUserPhoto,
+			&lastID, &c.LastActivityAt, &c.UnreadCount, &c.ImageEnabled,
+			&c.Muted, &c.MutedUntil, &c.Archived, &c.Pinned,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if lastID != nil {
+			c.LastMessage = &message.Message{ID: *lastID}
+			lastIDs = append(lastIDs, *lastID)
+		}
+		conversations = append(conversations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lastIDs) > 0 {
+		last, err := r.GetByIDs(ctx, lastIDs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range conversations {
+			if conversations[i].LastMessage != nil {
+				conversations[i].LastMessage = last[conversations[i].LastMessage.ID]
+			}
+		}
+	}
+	return conversations, nil
+}
+
+// CountArchivedConversations counts the user's archived conversations
+func (r *MessageRepository) CountArchivedConversations(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM conversation_settings WHERE user_id = $1 AND archived_at IS NOT NULL`
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	return count, err
+}
+
+// GetConversationSettings gets a user's settings for a match (defaults when never set)
+func (r *MessageRepository) GetConversationSettings(ctx context.Context, matchID, userID uuid.UUID) (*message.ConversationSettings, error) {
+	query := `
+		SELECT muted, muted_until, archived_at IS NOT NULL, pinned_at IS NOT NULL
+		FROM conversation_settings
+		WHERE match_id = $1 AND user_id = $2
+	`
+	var cs message.ConversationSettings
+	err := r.db.QueryRow(ctx, query, matchID, userID).Scan(&cs.Muted, &cs.MutedUntil, &cs.Archived, &cs.Pinned)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &message.ConversationSettings{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cs, nil
+}
+
+// SaveConversationSettings stores a user's settings for a match
+// Archive and pin keep their original timestamps while they stay set
+func (r *MessageRepository) SaveConversationSettings(ctx context.Context, matchID, userID uuid.UUID, cs *message.ConversationSettings) error {
+	query := `
+		INSERT INTO conversation_settings (match_id, user_id, muted, muted_until, archived_at, pinned_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN NOW() END, CASE WHEN $6 THEN NOW() END)
+		ON CONFLICT (match_id, user_id) DO UPDATE SET
+			muted = EXCLUDED.muted,
+			muted_until = EXCLUDED.muted_until,
+			archived_at = CASE WHEN $5 THEN COALESCE(conversation_settings.archived_at, NOW()) END,
+			pinned_at = CASE WHEN $6 THEN COALESCE(conversation_settings.pinned_at, NOW()) END,
+			updated_at = NOW()
+	`
+	_, err := r.db.Exec(ctx, query, matchID, userID, cs.Muted, cs.MutedUntil, cs.Archived, cs.Pinned)
+	return err
+}
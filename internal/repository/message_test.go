@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkHighlights(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"plain", "see you at " + highlightStart + "dinner" + highlightStop, "see you at <mark>dinner</mark>"},
+		{"markup is escaped", `<img src=x onerror="alert(1)"> ` + highlightStart + "hi" + highlightStop, `&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>hi</mark>`},
+		{"user-typed mark tags stay text", "<mark>" + highlightStart + "a&b" + highlightStop + "</mark>", "&lt;mark&gt;<mark>a&amp;b</mark>&lt;/mark&gt;"},
+		{"no match", "nothing here", "nothing here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, markHighlights(tt.fragment))
+		})
+	}
+}
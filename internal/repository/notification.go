@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/feels/feels/internal/domain/notification"
 	"github.com/google/uuid"
@@ -36,6 +38,11 @@ func (r *NotificationRepository) EnsureTables(ctx context.Context) error {
 
 	// Create index on user_id for faster lookups
 	_, err = r.db.Exec(ctx, `CREATE INDEX IF NOT EXISTS idx_push_tokens_user_id ON push_tokens(user_id)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, `ALTER TABLE push_tokens ADD COLUMN IF NOT EXISTS throttled_until TIMESTAMPTZ`)
 	return err
 }
 
@@ -55,7 +62,8 @@ func (r *NotificationRepository) SaveToken(ctx context.Context, token *notificat
 
 func (r *NotificationRepository) GetTokensByUserID(ctx context.Context, userID uuid.UUID) ([]notification.PushToken, error) {
 	query := `SELECT id, user_id, token, platform, created_at, updated_at
-		FROM push_tokens WHERE user_id = $1`
+		FROM push_tokens
+		WHERE user_id = $1 AND (throttled_until IS NULL OR throttled_until <= NOW())`
 
 	rows, err := r.db.Query(ctx, query, userID)
 	if err != nil {
@@ -87,6 +95,12 @@ func (r *NotificationRepository) DeleteUserTokens(ctx context.Context, userID uu
 	return err
 }
 
+func (r *NotificationRepository) ThrottleToken(ctx context.Context, token string, until time.Time) error {
+	query := `UPDATE push_tokens SET throttled_until = $2 WHERE token = $1`
+	_, err := r.db.Exec(ctx, query, token, until)
+	return err
+}
+
 // NotificationSettingsRepository wraps the settings repo to provide notification-specific checks
 type NotificationSettingsRepository struct {
 	db *pgxpool.Pool
@@ -96,17 +110,24 @@ func NewNotificationSettingsRepository(db *pgxpool.Pool) *NotificationSettingsRe
 	return &NotificationSettingsRepository{db: db}
 }
 
-func (r *NotificationSettingsRepository) GetNotificationSettings(ctx context.Context, userID uuid.UUID) (enabled bool, err error) {
-	query := `SELECT push_enabled FROM notification_settings WHERE user_id = $1`
+// GetPreferences returns the user's notification settings, or the defaults if none are saved
+func (r *NotificationSettingsRepository) GetPreferences(ctx context.Context, userID uuid.UUID) (*notification.Preferences, error) {
+	query := `SELECT push_enabled, new_matches, new_messages, likes_received, super_likes,
+			quiet_hours_enabled, quiet_hours_start, quiet_hours_end, timezone
+		FROM notification_settings WHERE user_id = $1`
 
-	err = r.db.QueryRow(ctx, query, userID).Scan(&enabled)
+	var p notification.Preferences
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&p.PushEnabled, &p.NewMatches, &p.NewMessages, &p.LikesReceived, &p.SuperLikes,
+		&p.QuietHoursEnabled, &p.QuietHoursStart, &p.QuietHoursEnd, &p.Timezone,
+	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return true, nil // Default to enabled if no settings exist
+			return notification.DefaultPreferences(), nil
 		}
-		return false, err
+		return nil, err
 	}
-	return enabled, nil
+	return &p, nil
 }
 
 // UpdateLastNotificationTime updates the last notification time for a user (for rate limiting)
@@ -114,3 +135,299 @@ func (r *NotificationRepository) UpdateLastNotificationTime(ctx context.Context,
 	// Could be used for rate limiting notifications
 	return nil
 }
+
+// GetContact returns the user's email and, if verified, phone number
+func (r *NotificationRepository) GetContact(ctx context.Context, userID uuid.UUID) (string, string, error) {
+	query := `SELECT email, CASE WHEN phone_verified THEN COALESCE(phone, '') ELSE '' END FROM users WHERE id = $1`
+
+	var email, phone string
+	err := r.db.QueryRow(ctx, query, userID).Scan(&email, &phone)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", nil
+	}
+	return email, phone, err
+}
+
+func (r *NotificationRepository) LogDeliveries(ctx context.Context, deliveries []notification.Delivery) error {
+	batch := &pgx.Batch{}
+	for _, d := range deliveries {
+		batch.Queue(`
+			INSERT INTO notification_deliveries (id, user_id, type, channel, status, collapse_key, error, created_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8)
+		`, d.ID, d.UserID, string(d.Type), d.Channel, d.Status, d.CollapseKey, d.Error, d.CreatedAt)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+// GetDeliveries returns a user's most recent deliveries, newest first
+func (r *NotificationRepository) GetDeliveries(ctx context.Context, userID uuid.UUID, limit int) ([]notification.Delivery, error) {
+	query := `
+		SELECT id, user_id, type, channel, status, COALESCE(collapse_key, ''), COALESCE(error, ''), created_at
+		FROM notification_deliveries WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []notification.Delivery
+	for rows.Next() {
+		var d notification.Delivery
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Type, &d.Channel, &d.Status, &d.CollapseKey, &d.Error, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *NotificationRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM notification_deliveries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// QueuePending creates or adds to the user's pending notification for the collapse key
+// A row inserted with count 0 stands for a notification already delivered, so adding to
+// it always counts at least one
+func (r *NotificationRepository) QueuePending(ctx context.Context, p *notification.PendingNotification) (bool, error) {
+	data, err := json.Marshal(p.Data)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO notification_pending (id, user_id, type, collapse_key, title, body, data, count, deliver_after, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, collapse_key) DO UPDATE SET
+			type = EXCLUDED.type,
+			title = EXCLUDED.title,
+			body = EXCLUDED.body,
+			data = EXCLUDED.data,
+			count = notification_pending.count + GREATEST(EXCLUDED.count, 1)
+		RETURNING (xmax = 0)
+	`
+	var created bool
+	err = r.db.QueryRow(ctx, query,
+		p.ID, p.UserID, string(p.Type), p.CollapseKey, p.Title, p.Body, data, p.Count, p.DeliverAfter, p.CreatedAt,
+	).Scan(&created)
+	return created, err
+}
+
+// ClaimDuePending leases and returns pending notifications due by now; SKIP LOCKED
+// keeps concurrent flushes from claiming the same rows, and the lease keeps later ones
+// from claiming them until it runs out
+func (r *NotificationRepository) ClaimDuePending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]notification.PendingNotification, error) {
+	query := `
+		UPDATE notification_pending SET claimed_until = $2
+		WHERE id IN (
+			SELECT id FROM notification_pending
+			WHERE deliver_after <= $1
+			  AND (claimed_until IS NULL OR claimed_until <= $1)
+			ORDER BY deliver_after
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, type, collapse_key, title, body, data, count, deliver_after, created_at
+	`
+	rows, err := r.db.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []notification.PendingNotification
+	for rows.Next() {
+		var p notification.PendingNotification
+		var data []byte
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Type, &p.CollapseKey, &p.Title, &p.Body, &data, &p.Count, &p.DeliverAfter, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &p.Data); err != nil {
+				return nil, err
+			}
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// CompletePending deletes a flushed notification, unless more were queued onto it
+// while it was claimed; then the ones sent are taken off its count and it's released
+func (r *NotificationRepository) CompletePending(ctx context.Context, p *notification.PendingNotification) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM notification_pending WHERE id = $1 AND count = $2`, p.ID, p.Count)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() > 0 {
+		return nil
+	}
+
+	_, err = r.db.Exec(ctx, `
+		UPDATE notification_pending SET count = count - $2, claimed_until = NULL
+		WHERE id = $1
+	`, p.ID, p.Count)
+	return err
+}
+
+// DeferPending releases a claimed notification to be flushed again at deliverAfter
+func (r *NotificationRepository) DeferPending(ctx context.Context, id uuid.UUID, deliverAfter time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE notification_pending SET deliver_after = $2, claimed_until = NULL
+		WHERE id = $1
+	`, id, deliverAfter)
+	return err
+}
+
+func (r *NotificationRepository) CreateNotification(ctx context.Context, n *notification.Notification) error {
+	var data []byte
+	if len(n.Data) > 0 {
+		var err error
+		if data, err = json.Marshal(n.Data); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO notifications (id, user_id, type, title, body, data, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.Exec(ctx, query, n.ID, n.UserID, string(n.Type), n.Title, n.Body, data, n.CreatedAt)
+	return err
+}
+
+// GetNotifications returns a user's notifications newest first, older than before when set
+// A before ID that isn't the user's returns notification.ErrUnknownCursor
+func (r *NotificationRepository) GetNotifications(ctx context.Context, userID uuid.UUID, before *uuid.UUID, limit int) ([]notification.Notification, error) {
+	args := []any{userID, limit}
+	query := `
+		SELECT id, user_id, type, title, body, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1`
+	if before != nil {
+		var cursorAt time.Time
+		err := r.db.QueryRow(ctx, `SELECT created_at FROM notifications WHERE id = $1 AND user_id = $2`, *before, userID).Scan(&cursorAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, notification.ErrUnknownCursor
+		}
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, cursorAt, *before)
+		query += `
+		  AND (created_at, id) < ($3, $4)`
+	}
+	query += `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []notification.Notification
+	for rows.Next() {
+		var n notification.Notification
+		var data []byte
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &data, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &n.Data); err != nil {
+				return nil, err
+			}
+		}
+		items = append(items, n)
+	}
+	return items, rows.Err()
+}
+
+func (r *NotificationRepository) CountUnreadNotifications(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+func (r *NotificationRepository) MarkNotificationsRead(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND id = ANY($2) AND read_at IS NULL`
+	result, err := r.db.Exec(ctx, query, userID, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *NotificationRepository) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *NotificationRepository) DeleteNotificationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM notifications WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *NotificationRepository) SaveTickets(ctx context.Context, tickets []notification.PushTicket) error {
+	batch := &pgx.Batch{}
+	for _, t := range tickets {
+		batch.Queue(`
+			INSERT INTO push_tickets (id, user_id, token, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id) DO NOTHING
+		`, t.ID, t.UserID, t.Token, t.CreatedAt)
+	}
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+// GetTicketsForReceipts returns the oldest tickets sent before sentBefore
+func (r *NotificationRepository) GetTicketsForReceipts(ctx context.Context, sentBefore time.Time, limit int) ([]notification.PushTicket, error) {
+	query := `SELECT id, user_id, token, created_at FROM push_tickets
+		WHERE created_at <= $1
+		ORDER BY created_at
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, sentBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickets []notification.PushTicket
+	for rows.Next() {
+		var t notification.PushTicket
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Token, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+
+	return tickets, rows.Err()
+}
+
+func (r *NotificationRepository) DeleteTickets(ctx context.Context, ids []string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM push_tickets WHERE id = ANY($1)`, ids)
+	return err
+}
+
+func (r *NotificationRepository) DeleteTicketsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM push_tickets WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -34,6 +34,17 @@ func (r *SettingsRepository) EnsureTables(ctx context.Context) error {
 			promotions BOOLEAN NOT NULL DEFAULT false,
 			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 		)`,
+		`ALTER TABLE notification_settings
+			ADD COLUMN IF NOT EXISTS quiet_hours_enabled BOOLEAN NOT NULL DEFAULT false,
+			ADD COLUMN IF NOT EXISTS quiet_hours_start TEXT NOT NULL DEFAULT '22:00',
+			ADD COLUMN IF NOT EXISTS quiet_hours_end TEXT NOT NULL DEFAULT '08:00',
+			ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC'`,
+		`ALTER TABLE notification_settings
+			ADD COLUMN IF NOT EXISTS email_likes_digest BOOLEAN NOT NULL DEFAULT true,
+			ADD COLUMN IF NOT EXISTS email_subscription_receipts BOOLEAN NOT NULL DEFAULT true,
+			ADD COLUMN IF NOT EXISTS email_renewal_reminders BOOLEAN NOT NULL DEFAULT true,
+			ADD COLUMN IF NOT EXISTS email_account_deleted BOOLEAN NOT NULL DEFAULT true,
+			ADD COLUMN IF NOT EXISTS email_locale TEXT NOT NULL DEFAULT 'en'`,
 		`CREATE TABLE IF NOT EXISTS privacy_settings (
 			user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
 			show_online_status BOOLEAN NOT NULL DEFAULT true,
@@ -54,13 +65,19 @@ func (r *SettingsRepository) EnsureTables(ctx context.Context) error {
 }
 
 func (r *SettingsRepository) GetNotificationSettings(ctx context.Context, userID uuid.UUID) (*settings.NotificationSettings, error) {
-	query := `SELECT user_id, push_enabled, new_matches, new_messages, likes_received, super_likes, promotions, updated_at
+	query := `SELECT user_id, push_enabled, new_matches, new_messages, likes_received, super_likes, promotions,
+			quiet_hours_enabled, quiet_hours_start, quiet_hours_end, timezone,
+			email_likes_digest, email_subscription_receipts, email_renewal_reminders, email_account_deleted, email_locale,
+			updated_at
 		FROM notification_settings WHERE user_id = $1`
 
-	var s settings.NotificationSettings
+	s := settings.NotificationSettings{Email: &settings.EmailSettings{}}
 	err := r.db.QueryRow(ctx, query, userID).Scan(
 		&s.UserID, &s.PushEnabled, &s.NewMatches, &s.NewMessages,
-		&s.LikesReceived, &s.SuperLikes, &s.Promotions, &s.UpdatedAt,
+		&s.LikesReceived, &s.SuperLikes, &s.Promotions,
+		&s.QuietHoursEnabled, &s.QuietHoursStart, &s.QuietHoursEnd, &s.Timezone,
+		&s.Email.LikesDigest, &s.Email.SubscriptionReceipts, &s.Email.RenewalReminders, &s.Email.AccountDeleted, &s.Email.Locale,
+		&s.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -73,8 +90,14 @@ func (r *SettingsRepository) GetNotificationSettings(ctx context.Context, userID
 
 func (r *SettingsRepository) UpsertNotificationSettings(ctx context.Context, s *settings.NotificationSettings) error {
 	s.UpdatedAt = time.Now()
-	query := `INSERT INTO notification_settings (user_id, push_enabled, new_matches, new_messages, likes_received, super_likes, promotions, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	if s.Email == nil {
+		s.Email = settings.DefaultEmailSettings()
+	}
+	query := `INSERT INTO notification_settings (user_id, push_enabled, new_matches, new_messages, likes_received, super_likes, promotions,
+			quiet_hours_enabled, quiet_hours_start, quiet_hours_end, timezone,
+			email_likes_digest, email_subscription_receipts, email_renewal_reminders, email_account_deleted, email_locale,
+			updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		ON CONFLICT (user_id) DO UPDATE SET
 			push_enabled = EXCLUDED.push_enabled,
 			new_matches = EXCLUDED.new_matches,
@@ -82,9 +105,21 @@ func (r *SettingsRepository) UpsertNotificationSettings(ctx context.Context, s *
 			likes_received = EXCLUDED.likes_received,
 			super_likes = EXCLUDED.super_likes,
 			promotions = EXCLUDED.promotions,
+			quiet_hours_enabled = EXCLUDED.quiet_hours_enabled,
+			quiet_hours_start = EXCLUDED.quiet_hours_start,
+			quiet_hours_end = EXCLUDED.quiet_hours_end,
+			timezone = EXCLUDED.timezone,
+			email_likes_digest = EXCLUDED.email_likes_digest,
+			email_subscription_receipts = EXCLUDED.email_subscription_receipts,
+			email_renewal_reminders = EXCLUDED.email_renewal_reminders,
+			email_account_deleted = EXCLUDED.email_account_deleted,
+			email_locale = EXCLUDED.email_locale,
 			updated_at = EXCLUDED.updated_at`
 
-	_, err := r.db.Exec(ctx, query, s.UserID, s.PushEnabled, s.NewMatches, s.NewMessages, s.LikesReceived, s.SuperLikes, s.Promotions, s.UpdatedAt)
+	_, err := r.db.Exec(ctx, query, s.UserID, s.PushEnabled, s.NewMatches, s.NewMessages, s.LikesReceived, s.SuperLikes, s.Promotions,
+		s.QuietHoursEnabled, s.QuietHoursStart, s.QuietHoursEnd, s.Timezone,
+		s.Email.LikesDigest, s.Email.SubscriptionReceipts, s.Email.RenewalReminders, s.Email.AccountDeleted, s.Email.Locale,
+		s.UpdatedAt)
 	return err
 }
 
@@ -106,6 +141,31 @@ func (r *SettingsRepository) GetPrivacySettings(ctx context.Context, userID uuid
 	return &s, nil
 }
 
+// GetPrivacySettingsForUsers returns stored privacy settings keyed by user; users without a row are omitted
+func (r *SettingsRepository) GetPrivacySettingsForUsers(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]*settings.PrivacySettings, error) {
+	query := `SELECT user_id, show_online_status, show_read_receipts, show_distance, hide_age, incognito_mode, updated_at
+		FROM privacy_settings WHERE user_id = ANY($1)`
+
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[uuid.UUID]*settings.PrivacySettings)
+	for rows.Next() {
+		var s settings.PrivacySettings
+		if err := rows.Scan(
+			&s.UserID, &s.ShowOnlineStatus, &s.ShowReadReceipts, &s.ShowDistance,
+			&s.HideAge, &s.IncognitoMode, &s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		result[s.UserID] = &s
+	}
+	return result, rows.Err()
+}
+
 func (r *SettingsRepository) UpsertPrivacySettings(ctx context.Context, s *settings.PrivacySettings) error {
 	s.UpdatedAt = time.Now()
 	query := `INSERT INTO privacy_settings (user_id, show_online_status, show_read_receipts, show_distance, hide_age, incognito_mode, updated_at)
@@ -100,21 +100,21 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*user.Us
 
 func (r *UserRepository) CreateRefreshToken(ctx context.Context, token *user.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO refresh_tokens (id, user_id, device_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
 	`
-	_, err := r.db.Exec(ctx, query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	_, err := r.db.Exec(ctx, query, token.ID, token.UserID, token.DeviceID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
 	return err
 }
 
 func (r *UserRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*user.RefreshToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, expires_at, created_at
+		SELECT id, user_id, COALESCE(device_id, ''), token_hash, expires_at, created_at
 		FROM refresh_tokens WHERE token_hash = $1
 	`
 	var token user.RefreshToken
 	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
-		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt,
+		&token.ID, &token.UserID, &token.DeviceID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -14,20 +14,22 @@ import (
 )
 
 type S3Client struct {
-	client    *minio.Client
-	bucket    string
-	endpoint  string
-	useSSL    bool
-	publicURL string // Custom domain for public URLs
+	client        *minio.Client
+	bucket        string
+	endpoint      string
+	useSSL        bool
+	publicURL     string // Custom domain for public URLs
+	privateBucket string // Objects only reachable through presigned links
 }
 
 type S3Config struct {
-	Endpoint  string
-	AccessKey string
-	SecretKey string
-	Bucket    string
-	UseSSL    bool
-	PublicURL string // Custom domain for public URLs (e.g., photos.feelsfun.app)
+	Endpoint      string
+	AccessKey     string
+	SecretKey     string
+	Bucket        string
+	UseSSL        bool
+	PublicURL     string // Custom domain for public URLs (e.g., photos.feelsfun.app)
+	PrivateBucket string // Bucket without public read access (e.g., view-once images)
 }
 
 func NewS3Client(cfg S3Config) (*S3Client, error) {
@@ -48,23 +50,30 @@ func NewS3Client(cfg S3Config) (*S3Client, error) {
 	}
 
 	return &S3Client{
-		client:    client,
-		bucket:    cfg.Bucket,
-		endpoint:  endpoint,
-		useSSL:    cfg.UseSSL,
-		publicURL: cfg.PublicURL,
+		client:        client,
+		bucket:        cfg.Bucket,
+		endpoint:      endpoint,
+		useSSL:        cfg.UseSSL,
+		publicURL:     cfg.PublicURL,
+		privateBucket: cfg.PrivateBucket,
 	}, nil
 }
 
 func (s *S3Client) EnsureBucket(ctx context.Context) error {
-	exists, err := s.client.BucketExists(ctx, s.bucket)
-	if err != nil {
-		return fmt.Errorf("failed to check bucket: %w", err)
+	buckets := []string{s.bucket}
+	if s.privateBucket != "" {
+		buckets = append(buckets, s.privateBucket)
 	}
-	if !exists {
-		err = s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{})
+	for _, bucket := range buckets {
+		exists, err := s.client.BucketExists(ctx, bucket)
 		if err != nil {
-			return fmt.Errorf("failed to create bucket: %w", err)
+			return fmt.Errorf("failed to check bucket: %w", err)
+		}
+		if !exists {
+			err = s.client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to create bucket: %w", err)
+			}
 		}
 	}
 	return nil
@@ -83,8 +92,22 @@ func (s *S3Client) UploadPhoto(ctx context.Context, userID uuid.UUID, reader io.
 	return s.GetPublicURL(filename), nil
 }
 
+// UploadAudio stores a chat voice note under prefix and returns its public URL
+func (s *S3Client) UploadAudio(ctx context.Context, prefix string, reader io.Reader, size int64, contentType string) (string, error) {
+	filename := fmt.Sprintf("%s%s%s", prefix, uuid.New().String(), getExtension(contentType))
+
+	_, err := s.client.PutObject(ctx, s.bucket, filename, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload audio to bucket %s at %s: %w", s.bucket, s.endpoint, err)
+	}
+
+	return s.GetPublicURL(filename), nil
+}
+
 func (s *S3Client) DeletePhoto(ctx context.Context, url string) error {
-	objectName := s.urlToObjectName(url)
+	objectName := s.ObjectName(url)
 	if objectName == "" {
 		return nil
 	}
@@ -107,18 +130,44 @@ func (s *S3Client) GetPublicURL(objectName string) string {
 	return fmt.Sprintf("%s://%s/%s/%s", scheme, s.endpoint, s.bucket, objectName)
 }
 
+// UploadPrivate stores an object in the private bucket under prefix and returns its object name
+func (s *S3Client) UploadPrivate(ctx context.Context, prefix string, reader io.Reader, size int64, contentType string) (string, error) {
+	if s.privateBucket == "" {
+		return "", fmt.Errorf("private bucket not configured")
+	}
+	objectName := fmt.Sprintf("%s%s%s", prefix, uuid.New().String(), getExtension(contentType))
+
+	_, err := s.client.PutObject(ctx, s.privateBucket, objectName, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload object to bucket %s at %s: %w", s.privateBucket, s.endpoint, err)
+	}
+
+	return objectName, nil
+}
+
+// DeletePrivate removes an object from the private bucket
+func (s *S3Client) DeletePrivate(ctx context.Context, objectName string) error {
+	return s.client.RemoveObject(ctx, s.privateBucket, objectName, minio.RemoveObjectOptions{})
+}
+
+// GetPresignedURL returns a link to a private bucket object that stops working after expiry
 func (s *S3Client) GetPresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
-	url, err := s.client.PresignedGetObject(ctx, s.bucket, objectName, expiry, nil)
+	url, err := s.client.PresignedGetObject(ctx, s.privateBucket, objectName, expiry, nil)
 	if err != nil {
 		return "", err
 	}
 	return url.String(), nil
 }
 
-func (s *S3Client) urlToObjectName(url string) string {
+// ObjectName returns the public bucket object a URL from GetPublicURL points at, or ""
+// if the URL is not one of ours
+func (s *S3Client) ObjectName(url string) string {
 	// Check custom public URL first
 	if s.publicURL != "" {
-		prefix := fmt.Sprintf("https://%s/", s.publicURL)
+		publicURL := strings.TrimPrefix(strings.TrimPrefix(s.publicURL, "https://"), "http://")
+		prefix := fmt.Sprintf("https://%s/", publicURL)
 		if len(url) > len(prefix) && url[:len(prefix)] == prefix {
 			return url[len(prefix):]
 		}
@@ -144,6 +193,16 @@ func getExtension(contentType string) string {
 		return ".gif"
 	case "image/webp":
 		return ".webp"
+	case "audio/mp4", "audio/x-m4a":
+		return ".m4a"
+	case "audio/aac":
+		return ".aac"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/webm":
+		return ".webm"
 	default:
 		return path.Ext(contentType)
 	}
@@ -161,3 +220,18 @@ func IsAllowedContentType(contentType string) bool {
 }
 
 const MaxPhotoSize = 10 * 1024 * 1024 // 10MB
+
+var allowedAudioContentTypes = map[string]bool{
+	"audio/mp4":   true,
+	"audio/x-m4a": true,
+	"audio/aac":   true,
+	"audio/mpeg":  true,
+	"audio/ogg":   true,
+	"audio/webm":  true,
+}
+
+func IsAllowedAudioContentType(contentType string) bool {
+	return allowedAudioContentTypes[contentType]
+}
+
+const MaxAudioSize = 5 * 1024 * 1024 // 5MB, about 5 minutes of AAC voice
@@ -0,0 +1,45 @@
+package testutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewTestRedis creates a new test Redis client
+// Uses TEST_REDIS_URL env var, falls back to REDIS_URL, or defaults to a local test database
+// Set SKIP_REDIS_TESTS=true to skip tests that require Redis
+func NewTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	if os.Getenv("SKIP_REDIS_TESTS") == "true" {
+		t.Skip("Skipping Redis test (SKIP_REDIS_TESTS=true)")
+	}
+
+	redisURL := os.Getenv("TEST_REDIS_URL")
+	if redisURL == "" {
+		redisURL = os.Getenv("REDIS_URL")
+	}
+	if redisURL == "" {
+		redisURL = "redis://localhost:6379/15"
+	}
+
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		t.Fatalf("Failed to parse test Redis URL: %v", err)
+	}
+	client := redis.NewClient(opt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		t.Fatalf("Failed to ping test Redis: %v", err)
+	}
+
+	return client
+}
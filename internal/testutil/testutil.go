@@ -201,6 +201,23 @@ func (db *TestDB) CreateLike(t *testing.T, likerID, likedID uuid.UUID, isSuperli
 	return likeID
 }
 
+// CreateLikeAt creates a like from liker to liked with a specific created_at (for decay tests)
+func (db *TestDB) CreateLikeAt(t *testing.T, likerID, likedID uuid.UUID, isSuperlike bool, createdAt time.Time) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+
+	likeID := uuid.New()
+	_, err := db.Pool.Exec(ctx, `
+		INSERT INTO likes (id, liker_id, liked_id, is_superlike, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, likeID, likerID, likedID, isSuperlike, createdAt)
+	if err != nil {
+		t.Fatalf("Failed to create test like: %v", err)
+	}
+
+	return likeID
+}
+
 // GetLikeCount returns the number of likes for a user
 func (db *TestDB) GetLikeCount(t *testing.T, likerID uuid.UUID) int {
 	t.Helper()
@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// EventRetention is how long sequenced events are kept for replay
+	EventRetention = 24 * time.Hour
+
+	// MaxStoredEvents caps the replay buffer per user (below the client send buffer)
+	MaxStoredEvents = 200
+
+	// EventResync tells a client its ?since= is older than the replay buffer, so it should refetch state
+	EventResync = "resync"
+
+	eventSeqKeyPrefix = "ws:seq:"
+	eventLogKeyPrefix = "ws:events:"
+)
+
+// sequencedMessage is an event envelope with the user's sequence number
+type sequencedMessage struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	Seq     int64           `json:"seq"`
+}
+
+// ResyncPayload is the payload for resync events
+type ResyncPayload struct {
+	Since     int64 `json:"since"`
+	LatestSeq int64 `json:"latest_seq"`
+}
+
+// EventLog numbers a user's events and keeps recent ones in Redis so a
+// reconnecting client can replay what it missed
+type EventLog struct {
+	redis *redis.Client
+}
+
+func NewEventLog(redisClient *redis.Client) *EventLog {
+	return &EventLog{redis: redisClient}
+}
+
+// Append assigns the next sequence number for the user, stores the event and returns it encoded
+func (l *EventLog) Append(ctx context.Context, userID uuid.UUID, eventType string, payload json.RawMessage) ([]byte, error) {
+	seq, err := l.redis.Incr(ctx, eventSeqKeyPrefix+userID.String()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(sequencedMessage{Type: eventType, Payload: payload, Seq: seq})
+	if err != nil {
+		return nil, err
+	}
+
+	key := eventLogKeyPrefix + userID.String()
+	pipe := l.redis.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(seq), Member: data})
+	pipe.ZRemRangeByRank(ctx, key, 0, -MaxStoredEvents-1)
+	pipe.Expire(ctx, key, EventRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Since returns the stored events after seq, oldest first
+// complete is false when events after seq have already been discarded
+func (l *EventLog) Since(ctx context.Context, userID uuid.UUID, since int64) (events [][]byte, latest int64, complete bool, err error) {
+	latest, err = l.redis.Get(ctx, eventSeqKeyPrefix+userID.String()).Int64()
+	if err == redis.Nil {
+		latest, err = 0, nil
+	}
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if since >= latest {
+		// Nothing missed, unless the client is ahead of us (sequence was reset)
+		return nil, latest, since == latest, nil
+	}
+
+	stored, err := l.redis.ZRangeByScoreWithScores(ctx, eventLogKeyPrefix+userID.String(), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(since, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	events = make([][]byte, len(stored))
+	for i, z := range stored {
+		events[i] = []byte(z.Member.(string))
+	}
+
+	complete = len(stored) > 0 && int64(stored[0].Score) == since+1
+	return events, latest, complete, nil
+}
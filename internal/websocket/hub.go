@@ -1,17 +1,23 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/feels/feels/internal/domain/message"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
 )
 
+// userChannelPrefix is the Redis pub/sub channel for events addressed to one user
+const userChannelPrefix = "ws:user:"
+
 var (
 	upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
@@ -23,20 +29,42 @@ var (
 	}
 )
 
+// PresenceTracker is told when connections open, stay alive and close
+type PresenceTracker interface {
+	Connected(userID uuid.UUID, connID string)
+	Heartbeat(userID uuid.UUID, connID string)
+	Disconnected(userID uuid.UUID, connID string)
+}
+
+// FrameHandler processes frames sent by clients and returns a reply for the sending connection (or nil)
+type FrameHandler interface {
+	HandleFrame(ctx context.Context, userID uuid.UUID, data []byte) interface{}
+}
+
 // Client represents a connected WebSocket client
 type Client struct {
 	hub    *Hub
 	conn   *websocket.Conn
+	id     string
 	userID uuid.UUID
 	send   chan []byte
 }
 
 // Hub maintains active WebSocket connections
+// With a Redis backend, SendToUser publishes to the user's channel and each hub
+// subscribes to the channels of users connected to it, so events reach sockets on any replica
 type Hub struct {
-	clients    map[uuid.UUID]map[*Client]bool // userID -> clients
+	clients    map[uuid.UUID]map[*Client]bool // userID -> clients on this process
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan userMessage
+	direct     chan clientMessage
+	presence   PresenceTracker
+	frames     FrameHandler
+	events     *EventLog
+	sequenced  map[string]bool // event types that get a seq and are kept for replay
+	redis      *redis.Client
+	pubsub     *redis.PubSub
 	mu         sync.RWMutex
 }
 
@@ -45,6 +73,12 @@ type userMessage struct {
 	data   []byte
 }
 
+// clientMessage is a reply for a single connection
+type clientMessage struct {
+	client *Client
+	data   []byte
+}
+
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
 	return &Hub{
@@ -52,46 +86,111 @@ func NewHub() *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan userMessage, 256),
+		direct:     make(chan clientMessage, 256),
+	}
+}
+
+// NewRedisHub creates a hub that fans events out through Redis pub/sub
+func NewRedisHub(redisClient *redis.Client) *Hub {
+	h := NewHub()
+	h.redis = redisClient
+	h.pubsub = redisClient.Subscribe(context.Background())
+	go h.receive()
+	return h
+}
+
+// receive delivers events published by any replica to this process's sockets
+func (h *Hub) receive() {
+	for msg := range h.pubsub.Channel() {
+		userID, err := uuid.Parse(strings.TrimPrefix(msg.Channel, userChannelPrefix))
+		if err != nil {
+			continue
+		}
+		h.broadcast <- userMessage{
+			userID: userID,
+			data:   []byte(msg.Payload),
+		}
 	}
 }
 
+// Close stops receiving events from Redis
+func (h *Hub) Close() error {
+	if h.pubsub == nil {
+		return nil
+	}
+	return h.pubsub.Close()
+}
+
+func userChannel(userID uuid.UUID) string {
+	return userChannelPrefix + userID.String()
+}
+
+// SetPresenceTracker sets the tracker notified of connection lifecycle (optional)
+func (h *Hub) SetPresenceTracker(t PresenceTracker) {
+	h.presence = t
+}
+
+// SetEventLog enables sequence numbers and replay for the given event types (optional)
+func (h *Hub) SetEventLog(events *EventLog, eventTypes ...string) {
+	h.events = events
+	h.sequenced = make(map[string]bool, len(eventTypes))
+	for _, t := range eventTypes {
+		h.sequenced[t] = true
+	}
+}
+
+// SetFrameHandler sets the handler for client -> server frames (optional)
+func (h *Hub) SetFrameHandler(f FrameHandler) {
+	h.frames = f
+}
+
 // Run starts the hub's main loop
 func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
-			if h.clients[client.userID] == nil {
+			firstConn := h.clients[client.userID] == nil
+			if firstConn {
 				h.clients[client.userID] = make(map[*Client]bool)
 			}
 			h.clients[client.userID][client] = true
 			h.mu.Unlock()
+			if firstConn && h.pubsub != nil {
+				if err := h.pubsub.Subscribe(context.Background(), userChannel(client.userID)); err != nil {
+					log.Printf("Error subscribing to events for user %s: %v", client.userID, err)
+				}
+			}
 			log.Printf("Client connected: user %s", client.userID)
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if clients, ok := h.clients[client.userID]; ok {
-				if _, ok := clients[client]; ok {
-					delete(clients, client)
-					close(client.send)
-					if len(clients) == 0 {
-						delete(h.clients, client.userID)
-					}
-				}
-			}
-			h.mu.Unlock()
+			h.removeClient(client)
 			log.Printf("Client disconnected: user %s", client.userID)
 
 		case msg := <-h.broadcast:
+			// A client that can't keep up is disconnected; it reconnects with ?since= to catch up
+			var slow []*Client
+			h.mu.RLock()
+			for client := range h.clients[msg.userID] {
+				select {
+				case client.send <- msg.data:
+				default:
+					slow = append(slow, client)
+				}
+			}
+			h.mu.RUnlock()
+			for _, client := range slow {
+				h.removeClient(client)
+				log.Printf("Client too slow, disconnected: user %s", client.userID)
+			}
+
+		case msg := <-h.direct:
+			// Only reply if the connection hasn't been unregistered in the meantime
 			h.mu.RLock()
-			if clients, ok := h.clients[msg.userID]; ok {
-				for client := range clients {
-					select {
-					case client.send <- msg.data:
-					default:
-						close(client.send)
-						delete(clients, client)
-					}
+			if h.clients[msg.client.userID][msg.client] {
+				select {
+				case msg.client.send <- msg.data:
+				default:
 				}
 			}
 			h.mu.RUnlock()
@@ -99,6 +198,29 @@ func (h *Hub) Run() {
 	}
 }
 
+// removeClient drops a client and stops listening for its user once their last connection is gone
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	lastConn := false
+	if clients, ok := h.clients[client.userID]; ok {
+		if _, ok := clients[client]; ok {
+			delete(clients, client)
+			close(client.send)
+			if len(clients) == 0 {
+				delete(h.clients, client.userID)
+				lastConn = true
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	if lastConn && h.pubsub != nil {
+		if err := h.pubsub.Unsubscribe(context.Background(), userChannel(client.userID)); err != nil {
+			log.Printf("Error unsubscribing from events for user %s: %v", client.userID, err)
+		}
+	}
+}
+
 // SendToUser sends a message to all connections for a user
 // Accepts any message type that can be marshaled to JSON
 func (h *Hub) SendToUser(userID uuid.UUID, msg interface{}) {
@@ -108,14 +230,58 @@ func (h *Hub) SendToUser(userID uuid.UUID, msg interface{}) {
 		return
 	}
 
+	if h.events != nil {
+		data = h.sequence(userID, data)
+	}
+
+	if h.redis != nil {
+		err := h.redis.Publish(context.Background(), userChannel(userID), data).Err()
+		if err == nil {
+			return
+		}
+		// Redis is down: still reach sockets on this replica
+		log.Printf("Error publishing message for user %s: %v", userID, err)
+	}
+
 	h.broadcast <- userMessage{
 		userID: userID,
 		data:   data,
 	}
 }
 
+// sequence numbers and stores durable events; other events pass through unchanged
+func (h *Hub) sequence(userID uuid.UUID, data []byte) []byte {
+	var envelope struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || !h.sequenced[envelope.Type] {
+		return data
+	}
+
+	stored, err := h.events.Append(context.Background(), userID, envelope.Type, envelope.Payload)
+	if err != nil {
+		// Still deliver live; the client just can't replay this one
+		log.Printf("Error storing %s event for user %s: %v", envelope.Type, userID, err)
+		return data
+	}
+	return stored
+}
+
 // HandleWebSocket handles a new WebSocket connection
+// With ?since=<seq>, sequenced events after seq are replayed before live events;
+// replayed and live events may overlap, so clients should ignore seqs they've already seen
 func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	var since int64 = -1
+	if v := r.URL.Query().Get("since"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -125,27 +291,69 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, userID uui
 	client := &Client{
 		hub:    h,
 		conn:   conn,
+		id:     uuid.New().String(),
 		userID: userID,
 		send:   make(chan []byte, 256),
 	}
 
 	h.register <- client
+	if h.presence != nil {
+		h.presence.Connected(userID, client.id)
+	}
+
+	// Registered first so nothing is missed between the replay and live delivery
+	if since >= 0 && h.events != nil {
+		if err := client.replay(r.Context(), since); err != nil {
+			log.Printf("Error replaying events for user %s: %v", userID, err)
+		}
+	}
 
 	go client.writePump()
 	go client.readPump()
 }
 
+// replay writes missed events straight to the connection before the write pump starts
+func (c *Client) replay(ctx context.Context, since int64) error {
+	events, latest, complete, err := c.hub.events.Since(ctx, c.userID, since)
+	if err != nil {
+		return err
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if !complete {
+		resync, _ := json.Marshal(map[string]interface{}{
+			"type":    EventResync,
+			"payload": ResyncPayload{Since: since, LatestSeq: latest},
+		})
+		if err := c.conn.WriteMessage(websocket.TextMessage, resync); err != nil {
+			return err
+		}
+	}
+	for _, data := range events {
+		if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // readPump reads messages from the WebSocket connection
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
+		if c.hub.presence != nil {
+			c.hub.presence.Disconnected(c.userID, c.id)
+		}
 	}()
 
 	c.conn.SetReadLimit(512 * 1024) // 512KB max message size
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		if c.hub.presence != nil {
+			c.hub.presence.Heartbeat(c.userID, c.id)
+		}
 		return nil
 	})
 
@@ -158,15 +366,24 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Handle incoming messages (typing indicators, etc.)
-		var msg message.WSMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
+		// Handle incoming frames (typing indicators, read marks, acks, pings)
+		if c.hub.frames == nil {
 			continue
 		}
+		if reply := c.hub.frames.HandleFrame(context.Background(), c.userID, data); reply != nil {
+			c.reply(reply)
+		}
+	}
+}
 
-		// Process client messages if needed
-		// For now, we mainly use server -> client communication
+// reply sends a message to this connection only
+func (c *Client) reply(msg interface{}) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling reply: %v", err)
+		return
 	}
+	c.hub.direct <- clientMessage{client: c, data: data}
 }
 
 // writePump writes messages to the WebSocket connection
@@ -212,7 +429,8 @@ func (c *Client) writePump() {
 	}
 }
 
-// IsUserOnline checks if a user has any active connections
+// IsUserOnline checks if a user has any active connections on this process
+// Use the presence service for a view across replicas
 func (h *Hub) IsUserOnline(userID uuid.UUID) bool {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -220,7 +438,7 @@ func (h *Hub) IsUserOnline(userID uuid.UUID) bool {
 	return ok && len(clients) > 0
 }
 
-// GetOnlineUsers returns the count of online users
+// GetOnlineUsers returns the count of users connected to this process
 func (h *Hub) GetOnlineUsers() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -0,0 +1,203 @@
+package websocket_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feels/feels/internal/domain/message"
+	"github.com/feels/feels/internal/testutil"
+	"github.com/feels/feels/internal/websocket"
+	"github.com/google/uuid"
+	gws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connect opens a socket for userID on the given hub
+func connect(t *testing.T, hub *websocket.Hub, userID uuid.UUID) *gws.Conn {
+	return connectWithQuery(t, hub, userID, "")
+}
+
+// connectWithQuery opens a socket with a query string such as "?since=3"
+func connectWithQuery(t *testing.T, hub *websocket.Hub, userID uuid.UUID, query string) *gws.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.HandleWebSocket(w, r, userID)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+query, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// sendUntilReceived publishes until the subscription is live and the event arrives
+func sendUntilReceived(t *testing.T, from *websocket.Hub, conn *gws.Conn, userID uuid.UUID, msg message.WSMessage) message.WSMessage {
+	t.Helper()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			close(received)
+			return
+		}
+		received <- data
+	}()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		from.SendToUser(userID, msg)
+		select {
+		case data, ok := <-received:
+			require.True(t, ok, "event was not delivered across hubs")
+			var got message.WSMessage
+			require.NoError(t, json.Unmarshal([]byte(strings.SplitN(string(data), "\n", 2)[0]), &got))
+			return got
+		case <-ticker.C:
+		}
+	}
+}
+
+func TestRedisHub_DeliversAcrossInstances(t *testing.T) {
+	clientA := testutil.NewTestRedis(t)
+	defer clientA.Close()
+	clientB := testutil.NewTestRedis(t)
+	defer clientB.Close()
+
+	hubA := websocket.NewRedisHub(clientA)
+	defer hubA.Close()
+	go hubA.Run()
+	hubB := websocket.NewRedisHub(clientB)
+	defer hubB.Close()
+	go hubB.Run()
+
+	userID := uuid.New()
+	conn := connect(t, hubB, userID)
+
+	// Sent from the replica the user is not connected to
+	got := sendUntilReceived(t, hubA, conn, userID, message.WSMessage{Type: message.EventNewMessage})
+	assert.Equal(t, message.EventNewMessage, got.Type)
+	assert.False(t, hubA.IsUserOnline(userID))
+	assert.True(t, hubB.IsUserOnline(userID))
+}
+
+func TestRedisHub_OnlyTargetUserReceives(t *testing.T) {
+	clientA := testutil.NewTestRedis(t)
+	defer clientA.Close()
+	clientB := testutil.NewTestRedis(t)
+	defer clientB.Close()
+
+	hubA := websocket.NewRedisHub(clientA)
+	defer hubA.Close()
+	go hubA.Run()
+	hubB := websocket.NewRedisHub(clientB)
+	defer hubB.Close()
+	go hubB.Run()
+
+	target := uuid.New()
+	bystander := uuid.New()
+	targetConn := connect(t, hubB, target)
+	bystanderConn := connect(t, hubA, bystander)
+
+	sendUntilReceived(t, hubA, targetConn, target, message.WSMessage{Type: message.EventMessageRead})
+
+	bystanderConn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	_, _, err := bystanderConn.ReadMessage()
+	assert.Error(t, err, "bystander should not receive another user's events")
+}
+
+// echoFrames replies to every frame with a pong carrying the sender
+type echoFrames struct{}
+
+func (echoFrames) HandleFrame(ctx context.Context, userID uuid.UUID, data []byte) interface{} {
+	return message.WSMessage{Type: message.EventPong, Payload: userID}
+}
+
+func TestHub_FrameReplyGoesToSendingConnection(t *testing.T) {
+	hub := websocket.NewHub()
+	hub.SetFrameHandler(echoFrames{})
+	go hub.Run()
+
+	userID := uuid.New()
+	sender := connect(t, hub, userID)
+	otherDevice := connect(t, hub, userID)
+
+	require.NoError(t, sender.WriteJSON(map[string]string{"type": message.FramePing}))
+
+	sender.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var got message.WSMessage
+	require.NoError(t, sender.ReadJSON(&got))
+	assert.Equal(t, message.EventPong, got.Type)
+
+	otherDevice.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	_, _, err := otherDevice.ReadMessage()
+	assert.Error(t, err, "replies should not fan out to the user's other connections")
+}
+
+// sequencedEvent is an event as received by the client
+type sequencedEvent struct {
+	Type string `json:"type"`
+	Seq  int64  `json:"seq"`
+}
+
+func readEvent(t *testing.T, conn *gws.Conn) sequencedEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ev sequencedEvent
+	require.NoError(t, conn.ReadJSON(&ev))
+	return ev
+}
+
+func TestEventLog_ReplaysMissedEventsSince(t *testing.T) {
+	client := testutil.NewTestRedis(t)
+	defer client.Close()
+
+	hub := websocket.NewRedisHub(client)
+	defer hub.Close()
+	hub.SetEventLog(websocket.NewEventLog(client), message.EventNewMessage)
+	go hub.Run()
+
+	// Sent while the user has no connection
+	userID := uuid.New()
+	for i := 0; i < 3; i++ {
+		hub.SendToUser(userID, message.WSMessage{Type: message.EventNewMessage})
+	}
+	// Ephemeral events are not sequenced or stored
+	hub.SendToUser(userID, message.WSMessage{Type: message.EventTypingStart})
+
+	conn := connectWithQuery(t, hub, userID, "?since=1")
+
+	first := readEvent(t, conn)
+	second := readEvent(t, conn)
+	assert.Equal(t, message.EventNewMessage, first.Type)
+	assert.Equal(t, int64(2), first.Seq)
+	assert.Equal(t, int64(3), second.Seq)
+}
+
+func TestEventLog_ResyncWhenSinceIsUnknown(t *testing.T) {
+	client := testutil.NewTestRedis(t)
+	defer client.Close()
+
+	hub := websocket.NewRedisHub(client)
+	defer hub.Close()
+	hub.SetEventLog(websocket.NewEventLog(client), message.EventNewMessage)
+	go hub.Run()
+
+	userID := uuid.New()
+	hub.SendToUser(userID, message.WSMessage{Type: message.EventNewMessage})
+
+	// Ahead of the server's sequence, e.g. after the event log was flushed
+	conn := connectWithQuery(t, hub, userID, "?since=99")
+
+	assert.Equal(t, websocket.EventResync, readEvent(t, conn).Type)
+}
@@ -0,0 +1,14 @@
+-- Ledger for the queue-clear bonus: a row is opened when a user's qualified
+-- like queue fills up and closed (bonus granted) when they clear it
+CREATE TABLE IF NOT EXISTS queue_clear_rewards (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  armed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  cleared_at TIMESTAMPTZ,
+  bonus_likes INT NOT NULL DEFAULT 0
+);
+
+-- At most one open (unclaimed) reward per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_queue_clear_rewards_open
+  ON queue_clear_rewards(user_id) WHERE cleared_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_queue_clear_rewards_user_id ON queue_clear_rewards(user_id);
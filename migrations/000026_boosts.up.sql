@@ -0,0 +1,12 @@
+-- Profile boosts: a boosted profile ranks above regular browse candidates
+-- for nearby viewers until expires_at
+CREATE TABLE IF NOT EXISTS boosts (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  source TEXT NOT NULL CHECK (source IN ('weekly', 'credits')),
+  credits_spent INT NOT NULL DEFAULT 0,
+  started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_boosts_user_expires ON boosts(user_id, expires_at);
+CREATE INDEX IF NOT EXISTS idx_boosts_expires_at ON boosts(expires_at);
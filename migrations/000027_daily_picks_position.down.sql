@@ -0,0 +1,2 @@
+ALTER TABLE daily_picks DROP COLUMN IF EXISTS priority;
+ALTER TABLE daily_picks DROP COLUMN IF EXISTS position;
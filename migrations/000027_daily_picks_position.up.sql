@@ -0,0 +1,3 @@
+-- Persisted daily picks keep their original order and feed bucket
+ALTER TABLE daily_picks ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+ALTER TABLE daily_picks ADD COLUMN IF NOT EXISTS priority TEXT;
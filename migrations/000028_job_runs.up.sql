@@ -0,0 +1,13 @@
+-- History of background job runs (scheduled and manually triggered)
+CREATE TABLE IF NOT EXISTS job_runs (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  job_name TEXT NOT NULL,
+  trigger TEXT NOT NULL CHECK (trigger IN ('schedule', 'manual')),
+  instance TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+  result TEXT,
+  error TEXT,
+  started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  finished_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_name_started ON job_runs(job_name, started_at DESC);
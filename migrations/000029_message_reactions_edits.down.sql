@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS message_reactions;
+DROP TABLE IF EXISTS message_edits;
+DELETE FROM messages WHERE content IS NULL AND image_url IS NULL;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check CHECK (content IS NOT NULL OR image_url IS NOT NULL);
+ALTER TABLE messages DROP COLUMN IF EXISTS unsent_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
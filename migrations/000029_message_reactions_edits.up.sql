@@ -0,0 +1,28 @@
+-- Edits and unsend: unsent messages are kept as tombstones with their content cleared
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS unsent_at TIMESTAMPTZ;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check
+  CHECK (content IS NOT NULL OR image_url IS NOT NULL OR encrypted_content IS NOT NULL OR unsent_at IS NOT NULL);
+
+-- Previous versions of edited and unsent messages, kept for moderation
+CREATE TABLE IF NOT EXISTS message_edits (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+  editor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  action TEXT NOT NULL CHECK (action IN ('edit', 'unsend')),
+  content TEXT,
+  encrypted_content TEXT,
+  image_url TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_message_edits_message_id ON message_edits(message_id, created_at);
+
+-- One reaction per user per message
+CREATE TABLE IF NOT EXISTS message_reactions (
+  message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  emoji TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (message_id, user_id)
+);
@@ -0,0 +1,2 @@
+-- Replies quote an earlier message in the same match
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_id UUID REFERENCES messages(id) ON DELETE SET NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_content_search;
+DROP INDEX IF EXISTS idx_messages_match_created_id;
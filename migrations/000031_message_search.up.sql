@@ -0,0 +1,6 @@
+-- Keyset pagination orders by (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_messages_match_created_id ON messages(match_id, created_at, id);
+
+-- Full-text search over plaintext content ('simple' config: no stemming, works across languages)
+CREATE INDEX IF NOT EXISTS idx_messages_content_search ON messages
+  USING GIN (to_tsvector('simple', COALESCE(content, '')));
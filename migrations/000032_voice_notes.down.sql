@@ -0,0 +1,9 @@
+DELETE FROM messages WHERE kind = 'voice';
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check
+  CHECK (content IS NOT NULL OR image_url IS NOT NULL OR encrypted_content IS NOT NULL OR unsent_at IS NOT NULL);
+ALTER TABLE message_edits DROP COLUMN IF EXISTS audio_url;
+ALTER TABLE messages DROP COLUMN IF EXISTS audio_waveform;
+ALTER TABLE messages DROP COLUMN IF EXISTS audio_duration_ms;
+ALTER TABLE messages DROP COLUMN IF EXISTS audio_url;
+ALTER TABLE messages DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,14 @@
+-- Message kinds: text, image and voice notes
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'text'
+  CHECK (kind IN ('text', 'image', 'voice'));
+UPDATE messages SET kind = 'image' WHERE image_url IS NOT NULL;
+
+-- Voice note audio and the metadata clients need to draw it without downloading
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS audio_url TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS audio_duration_ms INT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS audio_waveform SMALLINT[];
+ALTER TABLE message_edits ADD COLUMN IF NOT EXISTS audio_url TEXT;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check
+  CHECK (content IS NOT NULL OR image_url IS NOT NULL OR audio_url IS NOT NULL OR encrypted_content IS NOT NULL OR unsent_at IS NOT NULL);
@@ -0,0 +1 @@
+ALTER TABLE matches DROP COLUMN IF EXISTS image_prompt_sent_at;
@@ -0,0 +1,2 @@
+-- When both users first qualified for image sharing and were prompted to enable it
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS image_prompt_sent_at TIMESTAMPTZ;
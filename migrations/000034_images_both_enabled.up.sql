@@ -0,0 +1,5 @@
+-- When both users first had images enabled; after that, turning images off also disables sending
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS images_both_enabled_at TIMESTAMPTZ;
+
+UPDATE matches m SET images_both_enabled_at = NOW()
+WHERE (SELECT COUNT(*) FROM image_permissions ip WHERE ip.match_id = m.id AND ip.enabled) = 2;
@@ -0,0 +1,9 @@
+DELETE FROM messages WHERE view_once;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check
+  CHECK (content IS NOT NULL OR image_url IS NOT NULL OR audio_url IS NOT NULL OR encrypted_content IS NOT NULL OR unsent_at IS NOT NULL);
+DROP INDEX IF EXISTS idx_messages_view_once_pending;
+ALTER TABLE messages DROP COLUMN IF EXISTS object_deleted_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS viewed_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS private_object;
+ALTER TABLE messages DROP COLUMN IF EXISTS view_once;
@@ -0,0 +1,14 @@
+-- View-once images live in the private bucket and are only reachable through presigned links
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS view_once BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS private_object TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS viewed_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS object_deleted_at TIMESTAMPTZ;
+
+-- Objects still waiting for the sweep
+CREATE INDEX IF NOT EXISTS idx_messages_view_once_pending ON messages(created_at)
+  WHERE view_once AND object_deleted_at IS NULL;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check
+  CHECK (content IS NOT NULL OR image_url IS NOT NULL OR audio_url IS NOT NULL OR private_object IS NOT NULL
+    OR encrypted_content IS NOT NULL OR unsent_at IS NOT NULL);
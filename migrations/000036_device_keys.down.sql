@@ -0,0 +1,12 @@
+DELETE FROM messages WHERE device_encrypted AND content IS NULL AND encrypted_content IS NULL
+  AND image_url IS NULL AND audio_url IS NULL AND private_object IS NULL AND unsent_at IS NULL;
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check
+  CHECK (content IS NOT NULL OR image_url IS NOT NULL OR audio_url IS NOT NULL OR private_object IS NOT NULL
+    OR encrypted_content IS NOT NULL OR unsent_at IS NOT NULL);
+DROP TABLE IF EXISTS message_device_ciphertexts;
+ALTER TABLE messages DROP COLUMN IF EXISTS device_encrypted;
+ALTER TABLE messages DROP COLUMN IF EXISTS sender_device_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS device_id;
+DROP TABLE IF EXISTS one_time_prekeys;
+DROP TABLE IF EXISTS device_keys;
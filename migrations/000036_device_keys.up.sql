@@ -0,0 +1,42 @@
+-- Per-device E2E key directory: each logged-in device publishes an identity key,
+-- a signed prekey and a pool of one-time prekeys
+CREATE TABLE IF NOT EXISTS device_keys (
+  device_session_id UUID PRIMARY KEY REFERENCES device_sessions(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  identity_key TEXT NOT NULL,
+  signed_prekey_id INT NOT NULL,
+  signed_prekey TEXT NOT NULL,
+  signed_prekey_signature TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_device_keys_user_id ON device_keys(user_id);
+
+CREATE TABLE IF NOT EXISTS one_time_prekeys (
+  device_session_id UUID NOT NULL REFERENCES device_keys(device_session_id) ON DELETE CASCADE,
+  key_id INT NOT NULL,
+  public_key TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (device_session_id, key_id)
+);
+
+-- Refresh tokens remember their device so logout can revoke that device's keys
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS device_id TEXT;
+
+-- Multi-device messages carry one ciphertext per recipient device
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sender_device_id TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS device_encrypted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS message_device_ciphertexts (
+  message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+  recipient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  device_id TEXT NOT NULL,
+  ciphertext TEXT NOT NULL,
+  PRIMARY KEY (message_id, recipient_id, device_id)
+);
+CREATE INDEX IF NOT EXISTS idx_message_device_ciphertexts_recipient ON message_device_ciphertexts(recipient_id, message_id);
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_check;
+ALTER TABLE messages ADD CONSTRAINT messages_check
+  CHECK (content IS NOT NULL OR image_url IS NOT NULL OR audio_url IS NOT NULL OR private_object IS NOT NULL
+    OR encrypted_content IS NOT NULL OR device_encrypted OR unsent_at IS NOT NULL);
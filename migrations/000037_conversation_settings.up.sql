@@ -0,0 +1,12 @@
+-- Per-user conversation state: mute (optionally until a time), archive and pin
+CREATE TABLE IF NOT EXISTS conversation_settings (
+  match_id UUID NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  muted BOOLEAN NOT NULL DEFAULT FALSE,
+  muted_until TIMESTAMPTZ, -- NULL while muted means muted indefinitely
+  archived_at TIMESTAMPTZ,
+  pinned_at TIMESTAMPTZ,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (match_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_conversation_settings_user_id ON conversation_settings(user_id);
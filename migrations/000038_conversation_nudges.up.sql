@@ -0,0 +1,9 @@
+-- Nudges sent for stalled conversations, used to rate limit them per match
+CREATE TABLE IF NOT EXISTS conversation_nudges (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  match_id UUID NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_conversation_nudges_match ON conversation_nudges(match_id, created_at DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS date_plan_audit;
+DROP TABLE IF EXISTS date_plan_check_ins;
+DROP TABLE IF EXISTS date_plans;
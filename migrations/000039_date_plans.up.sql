@@ -0,0 +1,51 @@
+-- Date plans: a proposed in-person date between two matched users
+-- match_id is cleared on unmatch so safety records outlive the match
+CREATE TABLE IF NOT EXISTS date_plans (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  match_id UUID REFERENCES matches(id) ON DELETE SET NULL,
+  proposer_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  invitee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  scheduled_at TIMESTAMPTZ NOT NULL,
+  place_name TEXT NOT NULL,
+  place_address TEXT,
+  lat DOUBLE PRECISION,
+  lng DOUBLE PRECISION,
+  notes TEXT,
+  status TEXT NOT NULL DEFAULT 'proposed',
+  responded_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_date_plans_match ON date_plans(match_id, scheduled_at DESC);
+
+-- Each participant's own safety check-in and trusted contact
+CREATE TABLE IF NOT EXISTS date_plan_check_ins (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  plan_id UUID NOT NULL REFERENCES date_plans(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  contact_name TEXT,
+  contact_email TEXT,
+  contact_phone TEXT,
+  check_in_at TIMESTAMPTZ NOT NULL,
+  reminded_at TIMESTAMPTZ,
+  checked_in_at TIMESTAMPTZ,
+  escalated_at TIMESTAMPTZ,
+  cancelled_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (plan_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_date_plan_check_ins_due ON date_plan_check_ins(check_in_at)
+  WHERE checked_in_at IS NULL AND escalated_at IS NULL AND cancelled_at IS NULL;
+
+-- Audit trail of every plan and check-in action (actor_id NULL for the server)
+CREATE TABLE IF NOT EXISTS date_plan_audit (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  plan_id UUID NOT NULL REFERENCES date_plans(id) ON DELETE CASCADE,
+  actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  action TEXT NOT NULL,
+  details JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_date_plan_audit_plan ON date_plan_audit(plan_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_date_plan_audit_action ON date_plan_audit(action, created_at DESC);
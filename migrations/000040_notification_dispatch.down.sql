@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_pending;
+DROP TABLE IF EXISTS notification_deliveries;
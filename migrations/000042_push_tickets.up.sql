@@ -0,0 +1,8 @@
+-- Expo push tickets awaiting a receipt check, so dead device tokens can be pruned
+CREATE TABLE IF NOT EXISTS push_tickets (
+  id TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_push_tickets_created ON push_tickets(created_at);
@@ -0,0 +1,10 @@
+-- Templated emails already sent, so digests, receipts and reminders go out once per key
+CREATE TABLE IF NOT EXISTS email_sends (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  dedupe_key TEXT NOT NULL,
+  sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, type, dedupe_key)
+);
+CREATE INDEX IF NOT EXISTS idx_email_sends_sent ON email_sends(sent_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_message_edits_match_id;
+DELETE FROM message_edits e WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.id = e.message_id);
+ALTER TABLE message_edits ADD CONSTRAINT message_edits_message_id_fkey
+  FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE;
+ALTER TABLE message_edits DROP COLUMN IF EXISTS match_id;
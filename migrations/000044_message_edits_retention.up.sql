@@ -0,0 +1,9 @@
+-- Edit history outlives the conversation so moderators can still review it after an
+-- unmatch deletes the messages; message_id is no longer a foreign key and each edit
+-- records the match it came from
+ALTER TABLE message_edits ADD COLUMN IF NOT EXISTS match_id UUID;
+UPDATE message_edits e SET match_id = m.match_id
+  FROM messages m
+  WHERE m.id = e.message_id AND e.match_id IS NULL;
+ALTER TABLE message_edits DROP CONSTRAINT IF EXISTS message_edits_message_id_fkey;
+CREATE INDEX IF NOT EXISTS idx_message_edits_match_id ON message_edits(match_id, created_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS prekey_claims;
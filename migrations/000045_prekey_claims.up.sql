@@ -0,0 +1,9 @@
+-- Which devices a device has claimed a one-time prekey from; each device gets one prekey
+-- from each other device until either of them re-registers its keys
+CREATE TABLE IF NOT EXISTS prekey_claims (
+  requester_session_id UUID NOT NULL REFERENCES device_sessions(id) ON DELETE CASCADE,
+  target_session_id UUID NOT NULL REFERENCES device_sessions(id) ON DELETE CASCADE,
+  claimed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (requester_session_id, target_session_id)
+);
+CREATE INDEX IF NOT EXISTS idx_prekey_claims_target ON prekey_claims(target_session_id);
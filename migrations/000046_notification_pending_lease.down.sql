@@ -0,0 +1 @@
+ALTER TABLE notification_pending DROP COLUMN IF EXISTS claimed_until;
@@ -0,0 +1,4 @@
+-- A flush leases pending rows instead of deleting them, and deletes each one only once
+-- it has been delivered; a flush that dies mid-way leaves its rows to be picked up
+-- again when the lease runs out
+ALTER TABLE notification_pending ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
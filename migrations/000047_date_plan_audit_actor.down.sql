@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_date_plan_audit_actor;
@@ -0,0 +1,2 @@
+-- Counting a user's trusted contact shares for the daily cap
+CREATE INDEX IF NOT EXISTS idx_date_plan_audit_actor ON date_plan_audit(actor_id, action, created_at DESC);